@@ -0,0 +1,480 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+// dataSources holds the --from-literal, --from-file and --from-env-file
+// flags shared by cm create and secret create.
+type dataSources struct {
+	literals []string
+	files    []string
+	envFiles []string
+}
+
+func (d *dataSources) register(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&d.literals, "from-literal", nil, "key and literal value to add (KEY=VALUE, repeatable)")
+	cmd.Flags().StringArrayVar(&d.files, "from-file", nil, "file to add, keyed by its name or [KEY=]PATH; a directory adds each file in it (repeatable)")
+	cmd.Flags().StringArrayVar(&d.envFiles, "from-env-file", nil, "file of KEY=VALUE lines to add (repeatable)")
+}
+
+// collect reads every source into a single map. A key given twice is an
+// error rather than a silent overwrite.
+func (d *dataSources) collect() (map[string][]byte, error) {
+	data := map[string][]byte{}
+	add := func(key string, value []byte, source string) error {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return fmt.Errorf("invalid key %q from %s: %s", key, source, strings.Join(errs, "; "))
+		}
+		if _, ok := data[key]; ok {
+			return fmt.Errorf("key %q is given more than once (again in %s)", key, source)
+		}
+		data[key] = value
+		return nil
+	}
+
+	for _, literal := range d.literals {
+		key, value, ok := strings.Cut(literal, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --from-literal %q: expected KEY=VALUE", literal)
+		}
+		if err := add(key, []byte(value), "--from-literal"); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, source := range d.files {
+		key, path, ok := strings.Cut(source, "=")
+		if !ok {
+			key, path = "", source
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --from-file %q: %v", source, err)
+		}
+
+		if !info.IsDir() {
+			if key == "" {
+				key = filepath.Base(path)
+			}
+			value, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s: %v", path, err)
+			}
+			if err := add(key, value, path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if key != "" {
+			return nil, fmt.Errorf("invalid --from-file %q: a key cannot be given for a directory", source)
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading directory %s: %v", path, err)
+		}
+		for _, entry := range entries {
+			// Subdirectories and special files are skipped, as kubectl does.
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			file := filepath.Join(path, entry.Name())
+			value, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s: %v", file, err)
+			}
+			if err := add(entry.Name(), value, file); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, path := range d.envFiles {
+		values, err := parseEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range values {
+			if err := add(kv[0], []byte(kv[1]), path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no data given; use --from-literal, --from-file or --from-env-file")
+	}
+	return data, nil
+}
+
+// parseEnvFile reads KEY=VALUE lines in file order. Blank lines and lines
+// starting with # are ignored; values are taken literally, without quote
+// removal, like kubectl --from-env-file.
+func parseEnvFile(path string) ([][2]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading env file: %v", err)
+	}
+	defer f.Close()
+
+	var values [][2]string
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		line = strings.TrimLeft(line, " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE, got %q", path, lineNumber, line)
+		}
+		if errs := validation.IsEnvVarName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("%s:%d: invalid variable name %q: %s", path, lineNumber, key, strings.Join(errs, "; "))
+		}
+		values = append(values, [2]string{key, value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading env file %s: %v", path, err)
+	}
+	return values, nil
+}
+
+// editInEditor opens content in the user's editor ($KUBE_EDITOR, $EDITOR,
+// then vi or notepad) and returns the edited content. The temporary file is
+// kept, and its path returned, so that edits are not lost if applying them
+// fails; the caller removes it on success.
+func editInEditor(content []byte, name string) ([]byte, string, error) {
+	f, err := os.CreateTemp("", "k8s-admin-edit-*-"+name+".yaml")
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating temporary file: %v", err)
+	}
+	path := f.Name()
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return nil, path, fmt.Errorf("error writing temporary file: %v", err)
+	}
+	f.Close()
+
+	editor := os.Getenv("KUBE_EDITOR")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	// Editors such as "code --wait" come with arguments.
+	args := append(strings.Fields(editor), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, path, fmt.Errorf("error running editor %q: %v", editor, err)
+	}
+
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return nil, path, fmt.Errorf("error reading edited file: %v", err)
+	}
+	return edited, path, nil
+}
+
+// diffData compares the data of an object in the cluster with a local copy
+// and describes each added, removed and changed key. Secret values are
+// masked: only their sizes are shown.
+func diffData(cluster, local map[string][]byte, mask bool) []string {
+	keys := map[string]bool{}
+	for k := range cluster {
+		keys[k] = true
+	}
+	for k := range local {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var out []string
+	for _, key := range sorted {
+		remote, inCluster := cluster[key]
+		mine, inLocal := local[key]
+		switch {
+		case !inLocal:
+			out = append(out, fmt.Sprintf("- %s (only in the cluster, %d bytes)", key, len(remote)))
+		case !inCluster:
+			out = append(out, fmt.Sprintf("+ %s (only in the local file, %d bytes)", key, len(mine)))
+		case bytes.Equal(remote, mine):
+		case mask || !utf8.Valid(remote) || !utf8.Valid(mine):
+			out = append(out, fmt.Sprintf("~ %s (value differs: %d bytes in the cluster, %d bytes locally)", key, len(remote), len(mine)))
+		default:
+			out = append(out, fmt.Sprintf("~ %s", key))
+			for _, line := range diffLines(strings.Split(string(remote), "\n"), strings.Split(string(mine), "\n")) {
+				out = append(out, "    "+line)
+			}
+		}
+	}
+	return out
+}
+
+// diffLines returns a minimal line diff of a and b, with "-" for lines only
+// in a, "+" for lines only in b and " " for common lines.
+func diffLines(a, b []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+	return out
+}
+
+// configConsumer is a workload whose pod template uses a ConfigMap or
+// Secret.
+type configConsumer struct {
+	Kind   string
+	Name   string
+	UsedBy []string
+	Paused bool
+}
+
+// findConfigConsumers returns the Deployments, StatefulSets and DaemonSets in
+// namespace that mount or read environment variables from the given
+// ConfigMap or Secret.
+func findConfigConsumers(clientset *kubernetes.Clientset, namespace, kind, name string) ([]configConsumer, error) {
+	uses := func(spec *corev1.PodSpec) []string {
+		var usedBy []string
+		for _, r := range inspector.PodSpecReferences(spec) {
+			if r.Kind == kind && r.Name == name {
+				usedBy = append(usedBy, r.UsedBy)
+			}
+		}
+		return usedBy
+	}
+
+	var consumers []configConsumer
+	ctx := context.TODO()
+
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing deployments: %v", err)
+	}
+	for _, d := range deployments.Items {
+		if usedBy := uses(&d.Spec.Template.Spec); len(usedBy) > 0 {
+			consumers = append(consumers, configConsumer{Kind: "Deployment", Name: d.Name, UsedBy: usedBy, Paused: d.Spec.Paused})
+		}
+	}
+
+	sets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing stateful sets: %v", err)
+	}
+	for _, s := range sets.Items {
+		if usedBy := uses(&s.Spec.Template.Spec); len(usedBy) > 0 {
+			consumers = append(consumers, configConsumer{Kind: "StatefulSet", Name: s.Name, UsedBy: usedBy})
+		}
+	}
+
+	daemons, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing daemon sets: %v", err)
+	}
+	for _, d := range daemons.Items {
+		if usedBy := uses(&d.Spec.Template.Spec); len(usedBy) > 0 {
+			consumers = append(consumers, configConsumer{Kind: "DaemonSet", Name: d.Name, UsedBy: usedBy})
+		}
+	}
+
+	return consumers, nil
+}
+
+// newRestartConsumersCmd builds the rollout-restart-consumers command for
+// ConfigMaps or Secrets.
+func newRestartConsumersCmd(parent, kind string) *cobra.Command {
+	var (
+		dryRun bool
+		yes    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rollout-restart-consumers <name>",
+		Short: fmt.Sprintf("Restart every workload that uses a %s", kind),
+		Long: fmt.Sprintf(`Find the Deployments, StatefulSets and DaemonSets that mount the %[1]s or read
+environment variables from it, and restart them with a rolling update so
+that their pods pick up its current content.
+
+Pods that are not managed by one of these controllers cannot be restarted
+this way and are not listed.`, kind),
+		Example: fmt.Sprintf(`  k8s-admin %[1]s rollout-restart-consumers app-config --dry-run
+  k8s-admin %[1]s rollout-restart-consumers app-config -y`, parent),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			consumers, err := findConfigConsumers(clientset, namespace, kind, args[0])
+			if err != nil {
+				return err
+			}
+			if len(consumers) == 0 {
+				fmt.Printf("No workloads in namespace %s use %s %s\n", namespace, kind, args[0])
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KIND\tNAME\tUSED BY")
+			for _, c := range consumers {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", c.Kind, c.Name, strings.Join(c.UsedBy, ", "))
+			}
+			w.Flush()
+
+			if dryRun {
+				fmt.Printf("%d workload(s) would be restarted (dry run)\n", len(consumers))
+				return nil
+			}
+			ok, err := confirm(fmt.Sprintf("Restart %d workload(s) in namespace %s?", len(consumers), namespace), yes)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Restart cancelled")
+				return nil
+			}
+
+			var failed []string
+			for _, c := range consumers {
+				if err := restartConsumer(clientset, c); err != nil {
+					fmt.Fprintf(os.Stderr, "Error restarting %s %s: %v\n", c.Kind, c.Name, err)
+					failed = append(failed, c.Kind+"/"+c.Name)
+					continue
+				}
+				if c.Paused {
+					fmt.Fprintf(os.Stderr, "Warning: deployment %s is paused; its pods are replaced once it is resumed\n", c.Name)
+				}
+				fmt.Printf("%s %s restarted in namespace %s\n", c.Kind, c.Name, namespace)
+			}
+			if len(failed) > 0 {
+				return fmt.Errorf("failed to restart %d workload(s): %s", len(failed), strings.Join(failed, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only list the workloads that would be restarted")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	return cmd
+}
+
+func restartConsumer(clientset *kubernetes.Clientset, c configConsumer) error {
+	ctx := context.TODO()
+	patch := inspector.RestartPatch()
+	var err error
+	switch c.Kind {
+	case "Deployment":
+		_, err = clientset.AppsV1().Deployments(namespace).Patch(ctx, c.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case "StatefulSet":
+		_, err = clientset.AppsV1().StatefulSets(namespace).Patch(ctx, c.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case "DaemonSet":
+		_, err = clientset.AppsV1().DaemonSets(namespace).Patch(ctx, c.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	default:
+		err = fmt.Errorf("restarting a %s is not supported", c.Kind)
+	}
+	return err
+}
+
+// editObject lets the user edit original, the YAML of an object, and hands
+// the result to apply. Saving the file unchanged cancels the edit.
+func editObject(original []byte, name string, apply func(edited []byte) error) error {
+	edited, path, err := editInEditor(original, name)
+	if err != nil {
+		if path != "" {
+			os.Remove(path)
+		}
+		return err
+	}
+	if bytes.Equal(bytes.TrimSpace(edited), bytes.TrimSpace(original)) {
+		os.Remove(path)
+		fmt.Println("Edit cancelled, no changes made")
+		return nil
+	}
+	if err := apply(edited); err != nil {
+		return fmt.Errorf("%v\nyour changes have been saved to %s", err, path)
+	}
+	os.Remove(path)
+	return nil
+}
+
+// printDataDiff prints the differences between the data of an object in the
+// cluster and a local manifest and reports whether there are any.
+func printDataDiff(kind, name, path string, cluster, local map[string][]byte, mask bool) bool {
+	lines := diffData(cluster, local, mask)
+	if len(lines) == 0 {
+		return false
+	}
+	fmt.Printf("--- %s %s (cluster)\n+++ %s\n", kind, name, path)
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return true
+}
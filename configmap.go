@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/yaml"
+)
+
+func newConfigMapCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "cm",
+		Aliases: []string{"configmap", "configmaps"},
+		Short:   "Manage config maps",
+		Long: `List, create, edit and diff config maps, and restart the workloads that use
+them.`,
+	}
+
+	cmd.AddCommand(newConfigMapListCmd())
+	cmd.AddCommand(newConfigMapCreateCmd())
+	cmd.AddCommand(newConfigMapEditCmd())
+	cmd.AddCommand(newConfigMapDiffCmd())
+	cmd.AddCommand(newRestartConsumersCmd("cm", "ConfigMap"))
+
+	return cmd
+}
+
+func newConfigMapListCmd() *cobra.Command {
+	var (
+		selector string
+		watching bool
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List config maps",
+		Example: `  k8s-admin cm list
+  k8s-admin cm list -l app=web`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			if watching {
+				configMaps := clientset.CoreV1().ConfigMaps(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						opts.LabelSelector = selector
+						return configMaps.List(ctx, opts)
+					},
+					watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+						opts.LabelSelector = selector
+						return configMaps.Watch(ctx, opts)
+					},
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]corev1.ConfigMap, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*corev1.ConfigMap))
+						}
+						_, err := io.WriteString(w, inspector.FormatConfigMapTable(items, false))
+						return err
+					},
+				}, output)
+			}
+
+			configMaps, err := clientset.CoreV1().ConfigMaps(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				return printObject(configMaps, output)
+			}
+			if len(configMaps.Items) == 0 {
+				fmt.Printf("No config maps found in namespace %s\n", namespace)
+				return nil
+			}
+			fmt.Print(inspector.FormatConfigMapTable(configMaps.Items, false))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter config maps")
+	addWatchFlags(cmd, &watching, &output)
+	return cmd
+}
+
+func newConfigMapCreateCmd() *cobra.Command {
+	var (
+		sources dataSources
+		dryRun  string
+		output  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a config map from literals and files",
+		Long: `Create a config map from any combination of literal values, files, whole
+directories and env files. Values that are not valid UTF-8 are stored as
+binary data.
+
+--dry-run=client prints the config map instead of creating it; combine it
+with -o yaml to author manifests.`,
+		Example: `  k8s-admin cm create app-config --from-literal LOG_LEVEL=debug --from-file nginx.conf
+  k8s-admin cm create app-env --from-env-file app.env
+  k8s-admin cm create static --from-file ./static --dry-run=client -o yaml > static.yaml`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateCreateFlags(dryRun, output); err != nil {
+				return err
+			}
+			data, err := sources.collect()
+			if err != nil {
+				return err
+			}
+
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: args[0], Namespace: namespace},
+			}
+			for key, value := range data {
+				if utf8.Valid(value) {
+					if cm.Data == nil {
+						cm.Data = map[string]string{}
+					}
+					cm.Data[key] = string(value)
+				} else {
+					if cm.BinaryData == nil {
+						cm.BinaryData = map[string][]byte{}
+					}
+					cm.BinaryData[key] = value
+				}
+			}
+
+			if dryRun == "client" {
+				return printObject(cm, output)
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			createOptions := metav1.CreateOptions{}
+			if dryRun == "server" {
+				createOptions.DryRun = []string{metav1.DryRunAll}
+			}
+			cm, err = clientset.CoreV1().ConfigMaps(namespace).Create(context.TODO(), cm, createOptions)
+			if err != nil {
+				return fmt.Errorf("error creating config map: %v", err)
+			}
+
+			if output != "" {
+				return printObject(cm, output)
+			}
+			if dryRun == "server" {
+				fmt.Printf("ConfigMap %s validated in namespace %s (server dry run)\n", cm.Name, cm.Namespace)
+				return nil
+			}
+			fmt.Printf("ConfigMap %s created in namespace %s with %d key(s)\n", cm.Name, cm.Namespace, len(data))
+			return nil
+		},
+	}
+
+	sources.register(cmd)
+	cmd.Flags().StringVar(&dryRun, "dry-run", "none", "Only print (client) or validate on the server (server) instead of creating")
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = "client"
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Print the config map as yaml or json")
+	return cmd
+}
+
+func newConfigMapEditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit <name>",
+		Short: "Edit a config map in $EDITOR",
+		Long: `Open a config map as YAML in $KUBE_EDITOR or $EDITOR and update it with the
+saved result. The update fails if the config map changed in the meantime;
+your edits are then kept in a temporary file.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			original, err := encodeObject(cm, "yaml")
+			if err != nil {
+				return err
+			}
+
+			return editObject(original, cm.Name, func(edited []byte) error {
+				updated := &corev1.ConfigMap{}
+				if err := yaml.UnmarshalStrict(edited, updated); err != nil {
+					return fmt.Errorf("error parsing edited config map: %v", err)
+				}
+				if updated.Name != cm.Name || (updated.Namespace != "" && updated.Namespace != cm.Namespace) {
+					return fmt.Errorf("the name and namespace of a config map cannot be changed")
+				}
+				if _, err := clientset.CoreV1().ConfigMaps(namespace).Update(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+					return fmt.Errorf("error updating config map: %v", err)
+				}
+				fmt.Printf("ConfigMap %s updated in namespace %s\n", cm.Name, namespace)
+				return nil
+			})
+		},
+	}
+
+	return cmd
+}
+
+func newConfigMapDiffCmd() *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "diff <name> -f FILE",
+		Short: "Compare a config map with a local manifest",
+		Long: `Compare the keys and values of a config map in the cluster with a local
+ConfigMap manifest. The command exits with status 1 when they differ.`,
+		Example: `  k8s-admin cm diff app-config -f app-config.yaml`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			local := &corev1.ConfigMap{}
+			if err := readManifest(file, local); err != nil {
+				return err
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+			cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			if !printDataDiff("ConfigMap", cm.Name, file, configMapData(cm), configMapData(local), false) {
+				fmt.Printf("ConfigMap %s matches %s\n", cm.Name, file)
+				return nil
+			}
+			return differencesFound(cmd)
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "filename", "f", "", "ConfigMap manifest to compare with ('-' reads stdin)")
+	cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+// configMapData merges the text and binary data of a config map.
+func configMapData(cm *corev1.ConfigMap) map[string][]byte {
+	data := make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+	for k, v := range cm.Data {
+		data[k] = []byte(v)
+	}
+	for k, v := range cm.BinaryData {
+		data[k] = v
+	}
+	return data
+}
+
+// validateCreateFlags checks the --dry-run and --output values of the create
+// commands.
+func validateCreateFlags(dryRun, output string) error {
+	switch dryRun {
+	case "none", "client", "server":
+	default:
+		return fmt.Errorf("invalid --dry-run %q: must be none, client or server", dryRun)
+	}
+	switch output {
+	case "", "yaml", "json":
+	default:
+		return fmt.Errorf("invalid --output %q: must be yaml or json", output)
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// maxJobNameLength keeps generated job names within the 63 characters
+// allowed for the job-name label of their pods.
+const maxJobNameLength = 63
+
+func newCronJobCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "cronjob",
+		Aliases: []string{"cronjobs", "cj"},
+		Short:   "Manage cron jobs",
+		Long:    `List cron jobs, trigger them by hand, suspend or resume them and review their runs.`,
+	}
+
+	cmd.AddCommand(newCronJobListCmd())
+	cmd.AddCommand(newCronJobTriggerCmd())
+	cmd.AddCommand(newCronJobSuspendCmd(true))
+	cmd.AddCommand(newCronJobSuspendCmd(false))
+	cmd.AddCommand(newCronJobHistoryCmd())
+
+	return cmd
+}
+
+func newCronJobListCmd() *cobra.Command {
+	var (
+		selector string
+		watching bool
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List cron jobs",
+		Long: `List cron jobs with their schedule, active jobs and when they were last
+scheduled, last succeeded and last failed.`,
+		Example: `  k8s-admin cronjob list
+  k8s-admin cronjob list -w`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			// The last failure is derived from the jobs each cron job keeps.
+			listJobs := func(ctx context.Context) ([]batchv1.Job, error) {
+				jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return nil, fmt.Errorf("error listing jobs: %v", err)
+				}
+				return jobs.Items, nil
+			}
+
+			if watching {
+				cronJobs := clientset.BatchV1().CronJobs(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						opts.LabelSelector = selector
+						return cronJobs.List(ctx, opts)
+					},
+					watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+						opts.LabelSelector = selector
+						return cronJobs.Watch(ctx, opts)
+					},
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]batchv1.CronJob, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*batchv1.CronJob))
+						}
+						jobs, err := listJobs(context.TODO())
+						if err != nil {
+							return err
+						}
+						_, err = io.WriteString(w, inspector.FormatCronJobTable(items, jobs, false))
+						return err
+					},
+				}, output)
+			}
+
+			cronJobs, err := clientset.BatchV1().CronJobs(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				return printObject(cronJobs, output)
+			}
+			if len(cronJobs.Items) == 0 {
+				fmt.Printf("No cron jobs found in namespace %s\n", namespace)
+				return nil
+			}
+
+			jobs, err := listJobs(context.TODO())
+			if err != nil {
+				return err
+			}
+			fmt.Print(inspector.FormatCronJobTable(cronJobs.Items, jobs, false))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector to filter cron jobs (e.g. app=backup)")
+	addWatchFlags(cmd, &watching, &output)
+	return cmd
+}
+
+func newCronJobTriggerCmd() *cobra.Command {
+	var jobName string
+
+	cmd := &cobra.Command{
+		Use:   "trigger <name>",
+		Short: "Run a cron job now",
+		Long: `Create a job from a cron job's job template, like kubectl create job --from.
+The job is owned by the cron job, so it shows up in its history and is
+cleaned up with it, and it is marked as a manual run. Suspended cron jobs
+can be triggered too.`,
+		Example: `  k8s-admin cronjob trigger backup
+  k8s-admin cronjob trigger backup --job-name backup-before-upgrade`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			cj, err := clientset.BatchV1().CronJobs(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			if jobName == "" {
+				jobName = manualJobName(cj.Name)
+			}
+			job := jobFromCronJob(cj, jobName)
+			created, err := clientset.BatchV1().Jobs(namespace).Create(context.TODO(), job, metav1.CreateOptions{})
+			if err != nil {
+				return fmt.Errorf("error creating job: %v", err)
+			}
+
+			fmt.Printf("Job %s created from cron job %s in namespace %s\n", created.Name, cj.Name, namespace)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&jobName, "job-name", "", "name of the job to create (default: <cronjob>-manual-<id>)")
+	return cmd
+}
+
+// jobFromCronJob builds a Job from the job template of cj, owned by cj and
+// annotated as a manual run.
+func jobFromCronJob(cj *batchv1.CronJob, name string) *batchv1.Job {
+	template := cj.Spec.JobTemplate.DeepCopy()
+
+	annotations := map[string]string{inspector.ManualJobAnnotation: "manual"}
+	for k, v := range template.Annotations {
+		annotations[k] = v
+	}
+	controller := true
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   cj.Namespace,
+			Labels:      template.Labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: batchv1.SchemeGroupVersion.String(),
+				Kind:       "CronJob",
+				Name:       cj.Name,
+				UID:        cj.UID,
+				Controller: &controller,
+			}},
+		},
+		Spec: template.Spec,
+	}
+}
+
+// manualJobName derives a unique job name from the cron job name, shortened
+// if needed to stay within the job name limit.
+func manualJobName(cronJob string) string {
+	suffix := "-manual-" + strconv.FormatInt(time.Now().Unix(), 36)
+	if len(cronJob)+len(suffix) > maxJobNameLength {
+		cronJob = cronJob[:maxJobNameLength-len(suffix)]
+	}
+	return cronJob + suffix
+}
+
+func newCronJobSuspendCmd(suspend bool) *cobra.Command {
+	use, short, long := "suspend <name>", "Stop a cron job from scheduling new runs",
+		`Suspend a cron job so that it schedules no new jobs. Jobs that are already
+running are not affected.`
+	if !suspend {
+		use, short, long = "resume <name>", "Resume scheduling of a suspended cron job",
+			`Resume a suspended cron job. Runs missed while it was suspended are subject to
+the cron job's startingDeadlineSeconds and concurrency policy.`
+	}
+
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long:  long,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			cj, err := clientset.BatchV1().CronJobs(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			state := "suspended"
+			if !suspend {
+				state = "resumed"
+			}
+			if (cj.Spec.Suspend != nil && *cj.Spec.Suspend) == suspend {
+				fmt.Printf("Cron job %s is already %s\n", cj.Name, state)
+				return nil
+			}
+
+			patch := []byte(fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend))
+			_, err = clientset.BatchV1().CronJobs(namespace).Patch(context.TODO(), cj.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			if err != nil {
+				return fmt.Errorf("error updating cron job: %v", err)
+			}
+
+			fmt.Printf("Cron job %s %s in namespace %s\n", cj.Name, state, namespace)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newCronJobHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history <name>",
+		Short: "Show the runs of a cron job",
+		Long: `Show the jobs a cron job has created, oldest first, with how they were
+triggered, when they started, how long they ran and whether they succeeded.
+Only the jobs kept by the cron job's history limits are available.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			cj, err := clientset.BatchV1().CronJobs(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			jobs, err := clientset.BatchV1().Jobs(namespace).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("error listing jobs: %v", err)
+			}
+
+			fmt.Print(inspector.FormatCronJobHistory(cj, inspector.CronJobHistory(cj, jobs.Items)))
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+func newDaemonSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "ds",
+		Aliases: []string{"daemonset", "daemonsets"},
+		Short:   "Manage daemon sets",
+		Long:    `List daemon sets, follow their rollouts and check which nodes they cover.`,
+	}
+
+	cmd.AddCommand(newDaemonSetListCmd())
+	cmd.AddCommand(newDaemonSetCoverageCmd())
+	cmd.AddCommand(newDaemonSetRolloutCmd())
+
+	return cmd
+}
+
+func newDaemonSetListCmd() *cobra.Command {
+	var (
+		selector string
+		watching bool
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List daemon sets",
+		Example: `  k8s-admin ds list -n kube-system
+  k8s-admin ds list -l app=agent -w`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			if watching {
+				sets := clientset.AppsV1().DaemonSets(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						opts.LabelSelector = selector
+						return sets.List(ctx, opts)
+					},
+					watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+						opts.LabelSelector = selector
+						return sets.Watch(ctx, opts)
+					},
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]appsv1.DaemonSet, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*appsv1.DaemonSet))
+						}
+						_, err := io.WriteString(w, inspector.FormatDaemonSetTable(items, false))
+						return err
+					},
+				}, output)
+			}
+
+			sets, err := clientset.AppsV1().DaemonSets(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				return printObject(sets, output)
+			}
+			if len(sets.Items) == 0 {
+				fmt.Printf("No daemon sets found in namespace %s\n", namespace)
+				return nil
+			}
+
+			fmt.Print(inspector.FormatDaemonSetTable(sets.Items, false))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector to filter daemon sets (e.g. app=agent)")
+	addWatchFlags(cmd, &watching, &output)
+	return cmd
+}
+
+func newDaemonSetCoverageCmd() *cobra.Command {
+	var missingOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "coverage <name>",
+		Short: "Show which nodes run a daemon set pod",
+		Long: `Check every node of the cluster against a daemon set and report whether its pod
+runs there. Nodes without a pod are explained: a nodeSelector or required
+node affinity that does not match the node's labels, or a taint the pod does
+not tolerate. Nodes that are eligible but still have no running pod are
+reported separately, with the pending pod's status when there is one.
+
+The tolerations the daemon set controller adds to every pod (not-ready,
+unreachable, node pressure and unschedulable) are taken into account.`,
+		Example: `  k8s-admin ds coverage node-exporter -n monitoring
+  k8s-admin ds coverage fluent-bit -n logging --missing`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			ds, err := clientset.AppsV1().DaemonSets(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			coverage, err := inspector.DaemonSetCoverage(clientset, ds)
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(inspector.FormatCoverage(ds, coverage, missingOnly))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&missingOnly, "missing", false, "only list nodes without a running pod")
+	return cmd
+}
+
+func newDaemonSetRolloutCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollout",
+		Short: "Manage the rollout of a daemon set",
+	}
+
+	cmd.AddCommand(newRolloutStatusCmd("ds", "daemon set", func(ctx context.Context, clientset *kubernetes.Clientset, name string) (string, bool, error) {
+		ds, err := clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", false, err
+		}
+		return inspector.DaemonSetRolloutStatus(ds)
+	}))
+
+	return cmd
+}
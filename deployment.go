@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func newDeploymentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "deploy",
+		Aliases: []string{"deployment", "deployments"},
+		Short:   "Manage deployments",
+		Long:    `List, describe, scale and roll out deployments.`,
+	}
+
+	cmd.AddCommand(newDeploymentListCmd())
+	cmd.AddCommand(newDeploymentDescribeCmd())
+	cmd.AddCommand(newDeploymentScaleCmd())
+	cmd.AddCommand(newDeploymentSetCmd())
+	cmd.AddCommand(newDeploymentRolloutCmd())
+
+	return cmd
+}
+
+func newDeploymentListCmd() *cobra.Command {
+	var (
+		selector string
+		watching bool
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List deployments",
+		Long: `List deployments with their ready, up-to-date and available replicas and the
+images they run.`,
+		Example: `  k8s-admin deploy list
+  k8s-admin deploy list -l app=web -w`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			if watching {
+				deployments := clientset.AppsV1().Deployments(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						opts.LabelSelector = selector
+						return deployments.List(ctx, opts)
+					},
+					watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+						opts.LabelSelector = selector
+						return deployments.Watch(ctx, opts)
+					},
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]appsv1.Deployment, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*appsv1.Deployment))
+						}
+						_, err := io.WriteString(w, inspector.FormatDeploymentTable(items, false))
+						return err
+					},
+				}, output)
+			}
+
+			deployments, err := clientset.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				return printObject(deployments, output)
+			}
+			if len(deployments.Items) == 0 {
+				fmt.Printf("No deployments found in namespace %s\n", namespace)
+				return nil
+			}
+
+			fmt.Print(inspector.FormatDeploymentTable(deployments.Items, false))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector to filter deployments (e.g. app=web)")
+	addWatchFlags(cmd, &watching, &output)
+	return cmd
+}
+
+func newDeploymentDescribeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "describe <name>",
+		Short: "Show details of a deployment",
+		Long: `Show a deployment's replicas, rollout strategy, pod template, conditions,
+ReplicaSets and events.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			description, err := inspector.DescribeDeployment(clientset, namespace, args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Print(description)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newDeploymentScaleCmd() *cobra.Command {
+	var replicas int32
+
+	cmd := &cobra.Command{
+		Use:     "scale <name>",
+		Short:   "Set the number of replicas of a deployment",
+		Example: `  k8s-admin deploy scale web --replicas 5`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if replicas < 0 {
+				return fmt.Errorf("--replicas must not be negative")
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			previous, err := inspector.ScaleDeployment(clientset, namespace, args[0], replicas)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Deployment %s scaled from %d to %d replicas in namespace %s\n", args[0], previous, replicas, namespace)
+			return nil
+		},
+	}
+
+	cmd.Flags().Int32Var(&replicas, "replicas", 0, "desired number of replicas")
+	cmd.MarkFlagRequired("replicas")
+	return cmd
+}
+
+func newDeploymentSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Change fields of a deployment",
+	}
+
+	cmd.AddCommand(newDeploymentSetImageCmd())
+	return cmd
+}
+
+func newDeploymentSetImageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "image <name> CONTAINER=IMAGE...",
+		Short: "Update the container images of a deployment",
+		Long: `Update the images of one or more containers in a deployment's pod template,
+which starts a new rollout. Init containers can be named as well; "*" sets the
+image of every container.`,
+		Example: `  k8s-admin deploy set image web app=example/web:1.5
+  k8s-admin deploy set image web app=example/web:1.5 sidecar=envoyproxy/envoy:v1.30
+  k8s-admin deploy set image web '*=example/web:1.5'`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			images, err := parseKeyValues("image", args[1:])
+			if err != nil {
+				return err
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			d, err := clientset.AppsV1().Deployments(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			var containers, initContainers []map[string]interface{}
+			matched := map[string]bool{}
+			for _, c := range d.Spec.Template.Spec.Containers {
+				if image, ok := containerImage(images, c.Name, matched); ok {
+					containers = append(containers, map[string]interface{}{"name": c.Name, "image": image})
+				}
+			}
+			for _, c := range d.Spec.Template.Spec.InitContainers {
+				if image, ok := containerImage(images, c.Name, matched); ok {
+					initContainers = append(initContainers, map[string]interface{}{"name": c.Name, "image": image})
+				}
+			}
+			for name := range images {
+				if !matched[name] {
+					return fmt.Errorf("deployment %s has no container named %q", d.Name, name)
+				}
+			}
+
+			podSpec := map[string]interface{}{}
+			if len(containers) > 0 {
+				podSpec["containers"] = containers
+			}
+			if len(initContainers) > 0 {
+				podSpec["initContainers"] = initContainers
+			}
+			patch := map[string]interface{}{
+				// Fail instead of overwriting a concurrent change to the deployment.
+				"metadata": map[string]interface{}{"resourceVersion": d.ResourceVersion},
+				"spec":     map[string]interface{}{"template": map[string]interface{}{"spec": podSpec}},
+			}
+			data, err := json.Marshal(patch)
+			if err != nil {
+				return fmt.Errorf("error encoding patch: %v", err)
+			}
+
+			_, err = clientset.AppsV1().Deployments(namespace).Patch(context.TODO(), d.Name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+			if err != nil {
+				return fmt.Errorf("error updating deployment: %v", err)
+			}
+
+			fmt.Printf("Deployment %s image updated in namespace %s\n", d.Name, namespace)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// containerImage returns the new image for the named container, preferring
+// an exact entry over "*", and records which entries were used.
+func containerImage(images map[string]string, container string, matched map[string]bool) (string, bool) {
+	if image, ok := images[container]; ok {
+		matched[container] = true
+		return image, true
+	}
+	if image, ok := images["*"]; ok {
+		matched["*"] = true
+		return image, true
+	}
+	return "", false
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// rolloutPollInterval is how often rollout status checks the workload.
+const rolloutPollInterval = 2 * time.Second
+
+func newDeploymentRolloutCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollout",
+		Short: "Manage the rollout of a deployment",
+	}
+
+	cmd.AddCommand(newRolloutStatusCmd("deploy", "deployment", deploymentRolloutStatus))
+	cmd.AddCommand(newRolloutRestartCmd())
+	cmd.AddCommand(newRolloutHistoryCmd())
+	cmd.AddCommand(newRolloutUndoCmd())
+
+	return cmd
+}
+
+// rolloutStatusFunc fetches a workload and reports the progress of its
+// rollout; done is true once the rollout has finished.
+type rolloutStatusFunc func(ctx context.Context, clientset *kubernetes.Clientset, name string) (message string, done bool, err error)
+
+// newRolloutStatusCmd builds the rollout status command of the command tree
+// parent (e.g. "deploy") for workloads of the given kind.
+func newRolloutStatusCmd(parent, kind string, status rolloutStatusFunc) *cobra.Command {
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "status <name>",
+		Short: fmt.Sprintf("Wait for a %s rollout to finish", kind),
+		Long: fmt.Sprintf(`Wait until every pod of a %s runs the latest pod template and is
+available, printing progress as it changes. The command fails when the
+rollout cannot progress (for example a deployment exceeding its progress
+deadline) or --timeout passes first; a timeout of 0 waits indefinitely.`, kind),
+		Example: fmt.Sprintf(`  k8s-admin %[1]s rollout status web
+  k8s-admin %[1]s rollout status web --timeout 10m`, parent),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			last := ""
+			err = wait.PollUntilContextCancel(ctx, rolloutPollInterval, true, func(ctx context.Context) (bool, error) {
+				message, done, err := status(ctx, clientset, args[0])
+				if err != nil {
+					return false, err
+				}
+				if message != last {
+					fmt.Println(message)
+					last = message
+				}
+				return done, nil
+			})
+			if err != nil && ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("timed out after %s waiting for %s %s to roll out", timeout, kind, args[0])
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "how long to wait before giving up (0 waits indefinitely)")
+	return cmd
+}
+
+func deploymentRolloutStatus(ctx context.Context, clientset *kubernetes.Clientset, name string) (string, bool, error) {
+	d, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", false, err
+	}
+	return inspector.RolloutStatus(d)
+}
+
+func newRolloutRestartCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restart <name>",
+		Short: "Restart every pod of a deployment",
+		Long: `Replace the pods of a deployment through a regular rolling update, without
+changing its spec, by stamping the pod template with the restart time.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			if err := inspector.RestartDeployment(clientset, namespace, args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Deployment %s restarted in namespace %s\n", args[0], namespace)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newRolloutHistoryCmd() *cobra.Command {
+	var revision int64
+
+	cmd := &cobra.Command{
+		Use:   "history <name>",
+		Short: "Show the rollout history of a deployment",
+		Long: `List the revisions of a deployment from the ReplicaSets it owns, with their
+images and change cause. --revision shows the pod template of one revision.`,
+		Example: `  k8s-admin deploy rollout history web
+  k8s-admin deploy rollout history web --revision 3`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			d, err := clientset.AppsV1().Deployments(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			revisions, err := inspector.DeploymentRevisions(clientset, d)
+			if err != nil {
+				return err
+			}
+
+			if revision == 0 {
+				fmt.Print(inspector.FormatRolloutHistory(d, revisions))
+				return nil
+			}
+			r, err := findRevision(d, revisions, revision)
+			if err != nil {
+				return err
+			}
+			fmt.Print(inspector.FormatRevision(r))
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64Var(&revision, "revision", 0, "show the details of this revision")
+	return cmd
+}
+
+func newRolloutUndoCmd() *cobra.Command {
+	var toRevision int64
+
+	cmd := &cobra.Command{
+		Use:   "undo <name>",
+		Short: "Roll a deployment back to an earlier revision",
+		Long: `Roll a deployment back to the pod template of an earlier revision, by default
+the one before the current revision. The rollback itself becomes a new
+revision.`,
+		Example: `  k8s-admin deploy rollout undo web
+  k8s-admin deploy rollout undo web --to-revision 2`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if toRevision < 0 {
+				return fmt.Errorf("--to-revision must not be negative")
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			d, err := clientset.AppsV1().Deployments(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if d.Spec.Paused {
+				return fmt.Errorf("deployment %s is paused; resume it before rolling back", d.Name)
+			}
+			revisions, err := inspector.DeploymentRevisions(clientset, d)
+			if err != nil {
+				return err
+			}
+
+			current, _ := strconv.ParseInt(d.Annotations[inspector.RevisionAnnotation], 10, 64)
+			if toRevision == 0 {
+				toRevision = previousRevision(revisions, current)
+				if toRevision == 0 {
+					return fmt.Errorf("deployment %s has no earlier revision to roll back to", d.Name)
+				}
+			}
+			if toRevision == current {
+				fmt.Printf("Deployment %s is already at revision %d\n", d.Name, current)
+				return nil
+			}
+			r, err := findRevision(d, revisions, toRevision)
+			if err != nil {
+				return err
+			}
+
+			template := r.ReplicaSet.Spec.Template.DeepCopy()
+			// The hash label is added by the controller for each ReplicaSet.
+			delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+			d.Spec.Template = *template
+			if cause, ok := r.ReplicaSet.Annotations[inspector.ChangeCauseAnnotation]; ok {
+				if d.Annotations == nil {
+					d.Annotations = map[string]string{}
+				}
+				d.Annotations[inspector.ChangeCauseAnnotation] = cause
+			} else {
+				delete(d.Annotations, inspector.ChangeCauseAnnotation)
+			}
+
+			if _, err := clientset.AppsV1().Deployments(namespace).Update(context.TODO(), d, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("error rolling back deployment: %v", err)
+			}
+
+			fmt.Printf("Deployment %s rolled back to revision %d in namespace %s\n", d.Name, toRevision, namespace)
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64Var(&toRevision, "to-revision", 0, "revision to roll back to (default: the previous revision)")
+	return cmd
+}
+
+func findRevision(d *appsv1.Deployment, revisions []inspector.DeploymentRevision, revision int64) (inspector.DeploymentRevision, error) {
+	for _, r := range revisions {
+		if r.Revision == revision {
+			return r, nil
+		}
+	}
+	return inspector.DeploymentRevision{}, fmt.Errorf("revision %d of deployment %s not found", revision, d.Name)
+}
+
+// previousRevision returns the highest revision below current, or 0.
+func previousRevision(revisions []inspector.DeploymentRevision, current int64) int64 {
+	var previous int64
+	for _, r := range revisions {
+		if r.Revision < current && r.Revision > previous {
+			previous = r.Revision
+		}
+	}
+	return previous
+}
@@ -3,9 +3,13 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func newHealthCmd() *cobra.Command {
@@ -23,38 +27,75 @@ func newHealthCmd() *cobra.Command {
 }
 
 func newNodeStatusCmd() *cobra.Command {
-	return &cobra.Command{
+	var (
+		watchNodes bool
+		output     string
+	)
+
+	cmd := &cobra.Command{
 		Use:   "nodes",
 		Short: "Check node status",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
 			clientset, err := getClientset()
 			if err != nil {
 				return err
 			}
 
+			if watchNodes {
+				nodes := clientset.CoreV1().Nodes()
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						return nodes.List(ctx, opts)
+					},
+					watch: nodes.Watch,
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]corev1.Node, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*corev1.Node))
+						}
+						writeNodeStatus(w, items)
+						return nil
+					},
+				}, output)
+			}
+
 			nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
 			if err != nil {
 				return err
 			}
 
-			fmt.Println("Node Status:")
-			for _, node := range nodes.Items {
-				ready := "NotReady"
-				for _, condition := range node.Status.Conditions {
-					if condition.Type == "Ready" {
-						if condition.Status == "True" {
-							ready = "Ready"
-						}
-						break
-					}
-				}
-				fmt.Printf("- %s: %s\n", node.Name, ready)
-				fmt.Printf("  Version: %s\n", node.Status.NodeInfo.KubeletVersion)
-				fmt.Printf("  OS: %s\n", node.Status.NodeInfo.OperatingSystem)
+			if output == "json" {
+				return printObject(nodes, output)
 			}
+			writeNodeStatus(os.Stdout, nodes.Items)
 			return nil
 		},
 	}
+
+	addWatchFlags(cmd, &watchNodes, &output)
+	return cmd
+}
+
+func writeNodeStatus(w io.Writer, nodes []corev1.Node) {
+	fmt.Fprintln(w, "Node Status:")
+	for _, node := range nodes {
+		ready := "NotReady"
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == "Ready" {
+				if condition.Status == "True" {
+					ready = "Ready"
+				}
+				break
+			}
+		}
+		fmt.Fprintf(w, "- %s: %s\n", node.Name, ready)
+		fmt.Fprintf(w, "  Version: %s\n", node.Status.NodeInfo.KubeletVersion)
+		fmt.Fprintf(w, "  OS: %s\n", node.Status.NodeInfo.OperatingSystem)
+	}
 }
 
 func newPodDistributionCmd() *cobra.Command {
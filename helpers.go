@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// confirm asks the user a yes/no question on stdin. It returns true without
+// prompting when assumeYes is set.
+func confirm(prompt string, assumeYes bool) (bool, error) {
+	if assumeYes {
+		return true, nil
+	}
+
+	fmt.Printf("%s [y/N]: ", prompt)
+	reader := bufio.NewReader(os.Stdin)
+	answer, err := reader.ReadString('\n')
+	if err != nil && answer == "" {
+		return false, fmt.Errorf("error reading confirmation: %v", err)
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// writeBackup serializes the given objects into a single multi-document YAML
+// file inside dir and returns the path of the file written.
+func writeBackup(dir, prefix string, objects []runtime.Object) (string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating backup directory: %v", err)
+	}
+
+	timestamp := time.Now().Format("2006-01-02-150405")
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.yaml", prefix, timestamp))
+
+	var docs []string
+	for _, obj := range objects {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return "", fmt.Errorf("error encoding backup: %v", err)
+		}
+		docs = append(docs, string(data))
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Join(docs, "---\n")), 0o600); err != nil {
+		return "", fmt.Errorf("error writing backup: %v", err)
+	}
+	return path, nil
+}
+
+// printObject writes obj to stdout as YAML or JSON.
+func printObject(obj runtime.Object, format string) error {
+	data, err := encodeObject(obj, format)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// encodeObject serializes obj as YAML or JSON, as returned by
+// exportObject.
+func encodeObject(obj runtime.Object, format string) ([]byte, error) {
+	obj = exportObject(obj)
+
+	var data []byte
+	var err error
+	if format == "json" {
+		data, err = json.MarshalIndent(obj, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(obj)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error encoding output: %v", err)
+	}
+	return data, nil
+}
+
+// exportObject returns a copy of obj for output: the apiVersion and kind,
+// which typed clients leave empty, are filled in from the client-go scheme
+// and managed fields are dropped to keep it readable.
+func exportObject(obj runtime.Object) runtime.Object {
+	obj = obj.DeepCopyObject()
+	if gvks, _, err := scheme.Scheme.ObjectKinds(obj); err == nil && len(gvks) > 0 {
+		obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+	}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj
+}
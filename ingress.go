@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func newIngressCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "ingress",
+		Aliases: []string{"ingresses", "ing"},
+		Short:   "Inspect ingresses and their routes",
+		Long:    `List ingresses and trace their routes to services and endpoints.`,
+	}
+
+	cmd.AddCommand(newIngressListCmd())
+	cmd.AddCommand(newIngressRoutesCmd())
+
+	return cmd
+}
+
+func newIngressListCmd() *cobra.Command {
+	var (
+		selector      string
+		allNamespaces bool
+		watching      bool
+		output        string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List ingresses",
+		Example: `  k8s-admin ingress list
+  k8s-admin ingress list -A`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			ns := namespace
+			if allNamespaces {
+				ns = metav1.NamespaceAll
+			}
+
+			if watching {
+				ingresses := clientset.NetworkingV1().Ingresses(ns)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						opts.LabelSelector = selector
+						return ingresses.List(ctx, opts)
+					},
+					watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+						opts.LabelSelector = selector
+						return ingresses.Watch(ctx, opts)
+					},
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]networkingv1.Ingress, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*networkingv1.Ingress))
+						}
+						_, err := io.WriteString(w, inspector.FormatIngressTable(items, allNamespaces))
+						return err
+					},
+				}, output)
+			}
+
+			ingresses, err := clientset.NetworkingV1().Ingresses(ns).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				return printObject(ingresses, output)
+			}
+			if len(ingresses.Items) == 0 {
+				if allNamespaces {
+					fmt.Println("No ingresses found")
+				} else {
+					fmt.Printf("No ingresses found in namespace %s\n", namespace)
+				}
+				return nil
+			}
+			fmt.Print(inspector.FormatIngressTable(ingresses.Items, allNamespaces))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter ingresses")
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "list ingresses in every namespace")
+	addWatchFlags(cmd, &watching, &output)
+	return cmd
+}
+
+func newIngressRoutesCmd() *cobra.Command {
+	var (
+		selector      string
+		allNamespaces bool
+		host          string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "routes",
+		Short: "Show the routing table of ingresses",
+		Long: `Show every host and path of the ingresses with the service and port they
+route to, the ready endpoints behind it, the TLS secret that serves the host
+and the ingress class.
+
+Problems are listed below the table: routes to a service or service port
+that does not exist, TLS secrets that do not exist, and host and path pairs
+that more than one ingress of the same class routes. Duplicates are only
+found among the ingresses shown; use -A to find them across namespaces.`,
+		Example: `  k8s-admin ingress routes
+  k8s-admin ingress routes -A --host shop.example.com`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			ns := namespace
+			if allNamespaces {
+				ns = metav1.NamespaceAll
+			}
+			ingresses, err := clientset.NetworkingV1().Ingresses(ns).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+
+			routes, err := inspector.IngressRoutes(clientset, ingresses.Items)
+			if err != nil {
+				return err
+			}
+			if host != "" {
+				var matching []inspector.IngressRoute
+				for _, route := range routes {
+					if route.Host == host {
+						matching = append(matching, route)
+					}
+				}
+				routes = matching
+			}
+
+			if len(routes) == 0 {
+				switch {
+				case host != "":
+					fmt.Printf("No routes found for host %s\n", host)
+				case allNamespaces:
+					fmt.Println("No ingress routes found")
+				default:
+					fmt.Printf("No ingress routes found in namespace %s\n", namespace)
+				}
+				return nil
+			}
+			fmt.Print(inspector.FormatRoutes(routes, allNamespaces))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter ingresses")
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "show the routes of ingresses in every namespace")
+	cmd.Flags().StringVar(&host, "host", "", "only show the routes of this host (* for rules without a host)")
+	return cmd
+}
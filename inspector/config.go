@@ -0,0 +1,66 @@
+package inspector
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// FormatConfigMapTable renders config maps with their number of keys and
+// age.
+func FormatConfigMapTable(configMaps []corev1.ConfigMap, showNamespace bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	header := []string{"NAME", "DATA", "AGE"}
+	if showNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, cm := range configMaps {
+		cols := []string{
+			cm.Name,
+			fmt.Sprintf("%d", len(cm.Data)+len(cm.BinaryData)),
+			FormatAge(cm.CreationTimestamp.Time),
+		}
+		if showNamespace {
+			cols = append([]string{cm.Namespace}, cols...)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// FormatSecretTable renders secrets with their type, number of keys and age.
+// Values are never shown.
+func FormatSecretTable(secrets []corev1.Secret, showNamespace bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	header := []string{"NAME", "TYPE", "DATA", "AGE"}
+	if showNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, s := range secrets {
+		cols := []string{
+			s.Name,
+			string(s.Type),
+			fmt.Sprintf("%d", len(s.Data)),
+			FormatAge(s.CreationTimestamp.Time),
+		}
+		if showNamespace {
+			cols = append([]string{s.Namespace}, cols...)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+
+	w.Flush()
+	return result.String()
+}
@@ -0,0 +1,306 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NodeCoverage reports whether a DaemonSet runs on one node and, when it
+// does not, why.
+type NodeCoverage struct {
+	Node string
+	// Pod is the DaemonSet pod on the node, or nil.
+	Pod *corev1.Pod
+	// Eligible is false when the node is excluded by the pod's nodeSelector,
+	// node affinity or an untolerated taint.
+	Eligible bool
+	// Reasons explains why the node is excluded, or why an eligible node
+	// has no running pod.
+	Reasons []string
+}
+
+// daemonSetTolerations are added to every DaemonSet pod by the controller,
+// so these taints never keep a DaemonSet off a node.
+var daemonSetTolerations = []corev1.Toleration{
+	{Key: corev1.TaintNodeNotReady, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	{Key: corev1.TaintNodeDiskPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodeMemoryPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodePIDPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodeUnschedulable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+}
+
+// FormatDaemonSetTable renders daemon sets with the same columns as kubectl
+// get daemonsets.
+func FormatDaemonSetTable(sets []appsv1.DaemonSet, showNamespace bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	header := []string{"NAME", "DESIRED", "CURRENT", "READY", "UP-TO-DATE", "AVAILABLE", "NODE SELECTOR", "AGE"}
+	if showNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, d := range sets {
+		cols := []string{
+			d.Name,
+			fmt.Sprintf("%d", d.Status.DesiredNumberScheduled),
+			fmt.Sprintf("%d", d.Status.CurrentNumberScheduled),
+			fmt.Sprintf("%d", d.Status.NumberReady),
+			fmt.Sprintf("%d", d.Status.UpdatedNumberScheduled),
+			fmt.Sprintf("%d", d.Status.NumberAvailable),
+			formatMap(d.Spec.Template.Spec.NodeSelector),
+			FormatAge(d.CreationTimestamp.Time),
+		}
+		if showNamespace {
+			cols = append([]string{d.Namespace}, cols...)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// DaemonSetCoverage checks every node of the cluster against the DaemonSet's
+// scheduling constraints and pairs it with the DaemonSet pod running there.
+func DaemonSetCoverage(clientset *kubernetes.Clientset, ds *appsv1.DaemonSet) ([]NodeCoverage, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing nodes: %v", err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(ds.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on daemon set %s: %v", ds.Name, err)
+	}
+	pods, err := clientset.CoreV1().Pods(ds.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods: %v", err)
+	}
+	podByNode := make(map[string]*corev1.Pod)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if owner := metav1.GetControllerOf(pod); owner == nil || owner.UID != ds.UID {
+			continue
+		}
+		if node := daemonPodNode(pod); node != "" {
+			podByNode[node] = pod
+		}
+	}
+
+	tolerations := append(append([]corev1.Toleration{}, ds.Spec.Template.Spec.Tolerations...), daemonSetTolerations...)
+	if ds.Spec.Template.Spec.HostNetwork {
+		tolerations = append(tolerations, corev1.Toleration{Key: corev1.TaintNodeNetworkUnavailable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule})
+	}
+
+	coverage := make([]NodeCoverage, 0, len(nodes.Items))
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		c := NodeCoverage{Node: node.Name, Pod: podByNode[node.Name]}
+		c.Reasons = append(c.Reasons, nodeSelectorMismatches(ds.Spec.Template.Spec.NodeSelector, node)...)
+		if !nodeAffinityMatches(ds.Spec.Template.Spec.Affinity, node) {
+			c.Reasons = append(c.Reasons, "required node affinity does not match the node")
+		}
+		for _, taint := range node.Spec.Taints {
+			if taint.Effect == corev1.TaintEffectPreferNoSchedule || tolerated(tolerations, &taint) {
+				continue
+			}
+			c.Reasons = append(c.Reasons, fmt.Sprintf("taint %s is not tolerated", taint.ToString()))
+		}
+		c.Eligible = len(c.Reasons) == 0
+
+		switch {
+		case !c.Eligible:
+		case c.Pod == nil:
+			c.Reasons = append(c.Reasons, "no pod has been created; check the DaemonSet events for errors")
+		case c.Pod.Status.Phase == corev1.PodPending:
+			c.Reasons = append(c.Reasons, fmt.Sprintf("pod %s is %s", c.Pod.Name, NewPodRow(c.Pod).Status))
+		}
+		coverage = append(coverage, c)
+	}
+	return coverage, nil
+}
+
+// FormatCoverage renders the coverage report. With missingOnly, nodes that
+// run a healthy pod are left out of the table.
+func FormatCoverage(ds *appsv1.DaemonSet, coverage []NodeCoverage, missingOnly bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tPOD\tREADY\tSTATUS\tREASON")
+
+	running, excluded, missing := 0, 0, 0
+	for _, c := range coverage {
+		pod, ready, status := "<none>", "-", "-"
+		if c.Pod != nil {
+			row := NewPodRow(c.Pod)
+			pod, ready, status = c.Pod.Name, row.Ready, row.Status
+		}
+
+		switch {
+		case !c.Eligible:
+			excluded++
+			if c.Pod == nil {
+				status = "Excluded"
+			}
+		case c.Pod == nil || c.Pod.Status.Phase == corev1.PodPending:
+			missing++
+			if c.Pod == nil {
+				status = "Missing"
+			}
+		default:
+			running++
+			if missingOnly {
+				continue
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Node, pod, ready, status, orNone(strings.Join(c.Reasons, "; ")))
+	}
+	w.Flush()
+
+	fmt.Fprintf(&result, "\nDaemonSet %s runs on %d of %d nodes: %d excluded by nodeSelector, affinity or taints, %d eligible without a running pod\n",
+		ds.Name, running, len(coverage), excluded, missing)
+	return result.String()
+}
+
+// DaemonSetRolloutStatus reports the progress of a daemon set's rolling
+// update the way kubectl rollout status does.
+func DaemonSetRolloutStatus(ds *appsv1.DaemonSet) (message string, done bool, err error) {
+	if ds.Spec.UpdateStrategy.Type != appsv1.RollingUpdateDaemonSetStrategyType {
+		return "", false, fmt.Errorf("rollout status is only available for the %s update strategy", appsv1.RollingUpdateDaemonSetStrategyType)
+	}
+	if ds.Generation > ds.Status.ObservedGeneration {
+		return "Waiting for daemon set spec update to be observed...", false, nil
+	}
+
+	desired := ds.Status.DesiredNumberScheduled
+	switch {
+	case ds.Status.UpdatedNumberScheduled < desired:
+		return fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d out of %d new pods have been updated...",
+			ds.Name, ds.Status.UpdatedNumberScheduled, desired), false, nil
+	case ds.Status.NumberAvailable < desired:
+		return fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d of %d updated pods are available...",
+			ds.Name, ds.Status.NumberAvailable, desired), false, nil
+	}
+	return fmt.Sprintf("daemon set %q successfully rolled out", ds.Name), true, nil
+}
+
+// daemonPodNode returns the node a DaemonSet pod is bound to, or targets
+// through the node affinity the controller sets before it is scheduled.
+func daemonPodNode(pod *corev1.Pod) string {
+	if pod.Spec.NodeName != "" {
+		return pod.Spec.NodeName
+	}
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, field := range term.MatchFields {
+			if field.Key == metav1.ObjectNameField && field.Operator == corev1.NodeSelectorOpIn && len(field.Values) == 1 {
+				return field.Values[0]
+			}
+		}
+	}
+	return ""
+}
+
+func nodeSelectorMismatches(selector map[string]string, node *corev1.Node) []string {
+	var reasons []string
+	for _, key := range sortedKeys(selector) {
+		value, ok := node.Labels[key]
+		switch {
+		case !ok:
+			reasons = append(reasons, fmt.Sprintf("nodeSelector %s=%s: node has no %s label", key, selector[key], key))
+		case value != selector[key]:
+			reasons = append(reasons, fmt.Sprintf("nodeSelector %s=%s: node has %s=%s", key, selector[key], key, value))
+		}
+	}
+	return reasons
+}
+
+// nodeAffinityMatches evaluates the required node affinity of a pod
+// template: the node must match at least one of the terms.
+func nodeAffinityMatches(affinity *corev1.Affinity, node *corev1.Node) bool {
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if nodeSelectorTermMatches(term, node) {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeSelectorTermMatches(term corev1.NodeSelectorTerm, node *corev1.Node) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, req := range term.MatchExpressions {
+		value, ok := node.Labels[req.Key]
+		if !requirementMatches(req, value, ok) {
+			return false
+		}
+	}
+	for _, req := range term.MatchFields {
+		if req.Key != metav1.ObjectNameField || !requirementMatches(req, node.Name, true) {
+			return false
+		}
+	}
+	return true
+}
+
+func requirementMatches(req corev1.NodeSelectorRequirement, value string, present bool) bool {
+	switch req.Operator {
+	case corev1.NodeSelectorOpIn:
+		return present && contains(req.Values, value)
+	case corev1.NodeSelectorOpNotIn:
+		return !present || !contains(req.Values, value)
+	case corev1.NodeSelectorOpExists:
+		return present
+	case corev1.NodeSelectorOpDoesNotExist:
+		return !present
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		if !present || len(req.Values) != 1 {
+			return false
+		}
+		actual, err1 := strconv.ParseInt(value, 10, 64)
+		bound, err2 := strconv.ParseInt(req.Values[0], 10, 64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		if req.Operator == corev1.NodeSelectorOpGt {
+			return actual > bound
+		}
+		return actual < bound
+	}
+	return false
+}
+
+func tolerated(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
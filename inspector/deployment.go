@@ -0,0 +1,327 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// RevisionAnnotation is set by the deployment controller on a Deployment
+	// and its ReplicaSets to the rollout revision.
+	RevisionAnnotation = "deployment.kubernetes.io/revision"
+	// ChangeCauseAnnotation records why a revision was rolled out.
+	ChangeCauseAnnotation = "kubernetes.io/change-cause"
+	// RestartedAtAnnotation is the pod template annotation kubectl rollout
+	// restart sets; changing it rolls every pod without changing the spec.
+	RestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+)
+
+// DeploymentRevision is one entry of a Deployment's rollout history, backed
+// by the ReplicaSet that holds the pod template of that revision.
+type DeploymentRevision struct {
+	Revision    int64
+	ReplicaSet  *appsv1.ReplicaSet
+	ChangeCause string
+}
+
+// FormatDeploymentTable renders deployments with their READY, UP-TO-DATE,
+// AVAILABLE, AGE and IMAGES columns.
+func FormatDeploymentTable(deployments []appsv1.Deployment, showNamespace bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	header := []string{"NAME", "READY", "UP-TO-DATE", "AVAILABLE", "AGE", "IMAGES"}
+	if showNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, d := range deployments {
+		cols := []string{
+			d.Name,
+			fmt.Sprintf("%d/%d", d.Status.ReadyReplicas, desiredReplicas(&d)),
+			fmt.Sprintf("%d", d.Status.UpdatedReplicas),
+			fmt.Sprintf("%d", d.Status.AvailableReplicas),
+			FormatAge(d.CreationTimestamp.Time),
+			strings.Join(templateImages(d.Spec.Template), ","),
+		}
+		if showNamespace {
+			cols = append([]string{d.Namespace}, cols...)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// DescribeDeployment renders a kubectl-style description of a deployment:
+// its replicas, strategy, pod template, conditions, ReplicaSets and events.
+func DescribeDeployment(clientset *kubernetes.Clientset, namespace, name string) (string, error) {
+	d, err := clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error getting deployment: %v", err)
+	}
+
+	revisions, err := DeploymentRevisions(clientset, d)
+	if err != nil {
+		return "", err
+	}
+	events, err := ObjectEvents(clientset, "Deployment", d.ObjectMeta)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", d.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", d.Namespace)
+	fmt.Fprintf(w, "CreationTimestamp:\t%s\n", d.CreationTimestamp.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(w, "Labels:\t%s\n", formatMap(d.Labels))
+	fmt.Fprintf(w, "Annotations:\t%s\n", formatMap(d.Annotations))
+	fmt.Fprintf(w, "Selector:\t%s\n", metav1.FormatLabelSelector(d.Spec.Selector))
+	fmt.Fprintf(w, "Replicas:\t%d desired | %d updated | %d total | %d available | %d unavailable\n",
+		desiredReplicas(d), d.Status.UpdatedReplicas, d.Status.Replicas, d.Status.AvailableReplicas, d.Status.UnavailableReplicas)
+	fmt.Fprintf(w, "StrategyType:\t%s\n", d.Spec.Strategy.Type)
+	fmt.Fprintf(w, "MinReadySeconds:\t%d\n", d.Spec.MinReadySeconds)
+	if ru := d.Spec.Strategy.RollingUpdate; ru != nil {
+		fmt.Fprintf(w, "RollingUpdateStrategy:\t%s max unavailable, %s max surge\n", ru.MaxUnavailable, ru.MaxSurge)
+	}
+	if d.Spec.Paused {
+		fmt.Fprintf(w, "Paused:\ttrue\n")
+	}
+
+	writePodTemplate(w, d.Spec.Template)
+
+	fmt.Fprintf(w, "Conditions:\n")
+	fmt.Fprintf(w, "  Type\tStatus\tReason\tMessage\n")
+	for _, c := range d.Status.Conditions {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", c.Type, c.Status, c.Reason, c.Message)
+	}
+
+	current := d.Annotations[RevisionAnnotation]
+	var oldSets []string
+	newSet := "<none>"
+	for _, r := range revisions {
+		rs := r.ReplicaSet
+		desc := fmt.Sprintf("%s (%d/%d replicas created)", rs.Name, rs.Status.Replicas, derefInt32(rs.Spec.Replicas))
+		if strconv.FormatInt(r.Revision, 10) == current {
+			newSet = desc
+		} else if rs.Status.Replicas > 0 {
+			oldSets = append(oldSets, desc)
+		}
+	}
+	if len(oldSets) == 0 {
+		oldSets = []string{"<none>"}
+	}
+	fmt.Fprintf(w, "OldReplicaSets:\t%s\n", strings.Join(oldSets, ", "))
+	fmt.Fprintf(w, "NewReplicaSet:\t%s\n", newSet)
+
+	writeEvents(w, events)
+	w.Flush()
+
+	return result.String(), nil
+}
+
+// ScaleDeployment sets the number of replicas of a deployment through its
+// scale subresource and returns the previous number.
+func ScaleDeployment(clientset *kubernetes.Clientset, namespace, name string, replicas int32) (int32, error) {
+	deployments := clientset.AppsV1().Deployments(namespace)
+	scale, err := deployments.GetScale(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+	previous := scale.Spec.Replicas
+	scale.Spec.Replicas = replicas
+	if _, err := deployments.UpdateScale(context.TODO(), name, scale, metav1.UpdateOptions{}); err != nil {
+		return 0, fmt.Errorf("error scaling deployment: %v", err)
+	}
+	return previous, nil
+}
+
+// RestartDeployment sets the restartedAt annotation on the pod template so
+// that the deployment controller rolls out new pods. A paused deployment
+// would not roll them out, so it is refused.
+func RestartDeployment(clientset *kubernetes.Clientset, namespace, name string) error {
+	d, err := clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if d.Spec.Paused {
+		return fmt.Errorf("deployment %s is paused; resume it before restarting", name)
+	}
+
+	_, err = clientset.AppsV1().Deployments(namespace).Patch(context.TODO(), name, types.StrategicMergePatchType, RestartPatch(), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("error restarting deployment: %v", err)
+	}
+	return nil
+}
+
+// RestartPatch is a strategic merge patch that stamps a workload's pod
+// template with the current time, which makes its controller replace every
+// pod.
+func RestartPatch() []byte {
+	return []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`,
+		RestartedAtAnnotation, time.Now().Format(time.RFC3339)))
+}
+
+// DeploymentRevisions returns the ReplicaSets controlled by d as rollout
+// revisions, oldest first. ReplicaSets without a revision are ignored.
+func DeploymentRevisions(clientset *kubernetes.Clientset, d *appsv1.Deployment) ([]DeploymentRevision, error) {
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on deployment %s: %v", d.Name, err)
+	}
+	list, err := clientset.AppsV1().ReplicaSets(d.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing replica sets: %v", err)
+	}
+
+	var revisions []DeploymentRevision
+	for i := range list.Items {
+		rs := &list.Items[i]
+		if owner := metav1.GetControllerOf(rs); owner == nil || owner.UID != d.UID {
+			continue
+		}
+		revision, err := strconv.ParseInt(rs.Annotations[RevisionAnnotation], 10, 64)
+		if err != nil {
+			continue
+		}
+		revisions = append(revisions, DeploymentRevision{
+			Revision:    revision,
+			ReplicaSet:  rs,
+			ChangeCause: rs.Annotations[ChangeCauseAnnotation],
+		})
+	}
+
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+	return revisions, nil
+}
+
+// FormatRolloutHistory renders revisions as a table, marking the revision
+// the deployment is currently on.
+func FormatRolloutHistory(d *appsv1.Deployment, revisions []DeploymentRevision) string {
+	if len(revisions) == 0 {
+		return fmt.Sprintf("No rollout history found for deployment %s\n", d.Name)
+	}
+
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tREPLICASET\tREADY\tAGE\tIMAGES\tCHANGE-CAUSE")
+
+	current := d.Annotations[RevisionAnnotation]
+	for _, r := range revisions {
+		revision := strconv.FormatInt(r.Revision, 10)
+		if revision == current {
+			revision += " (current)"
+		}
+		rs := r.ReplicaSet
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\t%s\t%s\n",
+			revision, rs.Name, rs.Status.ReadyReplicas, derefInt32(rs.Spec.Replicas),
+			FormatAge(rs.CreationTimestamp.Time), strings.Join(templateImages(rs.Spec.Template), ","),
+			orNone(r.ChangeCause))
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// FormatRevision renders the pod template of a single revision.
+func FormatRevision(r DeploymentRevision) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Revision:\t%d\n", r.Revision)
+	fmt.Fprintf(w, "ReplicaSet:\t%s\n", r.ReplicaSet.Name)
+	fmt.Fprintf(w, "Change Cause:\t%s\n", orNone(r.ChangeCause))
+	writePodTemplate(w, r.ReplicaSet.Spec.Template)
+	w.Flush()
+	return result.String()
+}
+
+// RolloutStatus reports the progress of a deployment's rollout the way
+// kubectl rollout status does. done is true once every replica runs the
+// latest template and is available; an error is returned when the rollout
+// exceeded its progress deadline.
+func RolloutStatus(d *appsv1.Deployment) (message string, done bool, err error) {
+	if d.Generation > d.Status.ObservedGeneration {
+		return "Waiting for deployment spec update to be observed...", false, nil
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return "", false, fmt.Errorf("deployment %q exceeded its progress deadline", d.Name)
+		}
+	}
+
+	desired := desiredReplicas(d)
+	switch {
+	case d.Status.UpdatedReplicas < desired:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated...",
+			d.Name, d.Status.UpdatedReplicas, desired), false, nil
+	case d.Status.Replicas > d.Status.UpdatedReplicas:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d old replicas are pending termination...",
+			d.Name, d.Status.Replicas-d.Status.UpdatedReplicas), false, nil
+	case d.Status.AvailableReplicas < d.Status.UpdatedReplicas:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d of %d updated replicas are available...",
+			d.Name, d.Status.AvailableReplicas, d.Status.UpdatedReplicas), false, nil
+	}
+	return fmt.Sprintf("deployment %q successfully rolled out", d.Name), true, nil
+}
+
+func writePodTemplate(w io.Writer, template corev1.PodTemplateSpec) {
+	fmt.Fprintf(w, "Pod Template:\n")
+	fmt.Fprintf(w, "  Labels:\t%s\n", formatMap(template.Labels))
+	if len(template.Annotations) > 0 {
+		fmt.Fprintf(w, "  Annotations:\t%s\n", formatMap(template.Annotations))
+	}
+	if template.Spec.ServiceAccountName != "" {
+		fmt.Fprintf(w, "  Service Account:\t%s\n", template.Spec.ServiceAccountName)
+	}
+	if len(template.Spec.InitContainers) > 0 {
+		fmt.Fprintf(w, "Init Containers:\n")
+		for _, c := range template.Spec.InitContainers {
+			writeTemplateContainer(w, c)
+		}
+	}
+	fmt.Fprintf(w, "Containers:\n")
+	for _, c := range template.Spec.Containers {
+		writeTemplateContainer(w, c)
+	}
+	writeVolumes(w, template.Spec.Volumes)
+}
+
+func templateImages(template corev1.PodTemplateSpec) []string {
+	images := make([]string, 0, len(template.Spec.Containers))
+	for _, c := range template.Spec.Containers {
+		images = append(images, c.Image)
+	}
+	return images
+}
+
+// desiredReplicas returns spec.replicas, which defaults to 1.
+func desiredReplicas(d *appsv1.Deployment) int32 {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+func derefInt32(p *int32) int32 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
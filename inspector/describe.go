@@ -0,0 +1,518 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DescribePod renders a kubectl-style description of a pod, including its
+// containers, volumes, scheduling constraints, owner chain and events.
+func DescribePod(clientset *kubernetes.Clientset, namespace, name string) (string, error) {
+	pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error getting pod: %v", err)
+	}
+
+	events, err := PodEvents(clientset, pod)
+	if err != nil {
+		return "", err
+	}
+
+	owners := OwnerChain(clientset, pod.Namespace, pod.OwnerReferences)
+
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+	writePod(w, pod, owners)
+	writeEvents(w, events)
+	w.Flush()
+
+	return result.String(), nil
+}
+
+// PodEvents returns the events recorded for pod, oldest first.
+func PodEvents(clientset *kubernetes.Clientset, pod *corev1.Pod) ([]corev1.Event, error) {
+	return ObjectEvents(clientset, "Pod", pod.ObjectMeta)
+}
+
+// ObjectEvents returns the events recorded for the object of the given kind,
+// oldest first.
+func ObjectEvents(clientset *kubernetes.Clientset, kind string, obj metav1.ObjectMeta) ([]corev1.Event, error) {
+	selector := fields.Set{
+		"involvedObject.kind":      kind,
+		"involvedObject.name":      obj.Name,
+		"involvedObject.namespace": obj.Namespace,
+		"involvedObject.uid":       string(obj.UID),
+	}.AsSelector().String()
+
+	events, err := clientset.CoreV1().Events(obj.Namespace).List(context.TODO(), metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("error listing events: %v", err)
+	}
+
+	items := events.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		return EventTime(items[i]).Before(EventTime(items[j]))
+	})
+	return items, nil
+}
+
+// EventTime returns the most recent timestamp recorded on an event.
+func EventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// OwnerChain follows controller references upwards, e.g. a pod's ReplicaSet
+// and then its Deployment. Owners that cannot be fetched end the chain.
+func OwnerChain(clientset *kubernetes.Clientset, namespace string, refs []metav1.OwnerReference) []string {
+	var chain []string
+	for depth := 0; depth < 5; depth++ {
+		ref := metav1.GetControllerOfNoCopy(&metav1.ObjectMeta{OwnerReferences: refs})
+		if ref == nil {
+			break
+		}
+		chain = append(chain, fmt.Sprintf("%s/%s", ref.Kind, ref.Name))
+
+		var next []metav1.OwnerReference
+		switch ref.Kind {
+		case "ReplicaSet":
+			rs, err := clientset.AppsV1().ReplicaSets(namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+			if err == nil {
+				next = rs.OwnerReferences
+			}
+		case "Job":
+			job, err := clientset.BatchV1().Jobs(namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+			if err == nil {
+				next = job.OwnerReferences
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		refs = next
+	}
+	return chain
+}
+
+func writePod(w io.Writer, pod *corev1.Pod, owners []string) {
+	fmt.Fprintf(w, "Name:\t%s\n", pod.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", pod.Namespace)
+	if pod.Spec.Priority != nil {
+		fmt.Fprintf(w, "Priority:\t%d\n", *pod.Spec.Priority)
+	}
+	if pod.Spec.PriorityClassName != "" {
+		fmt.Fprintf(w, "Priority Class Name:\t%s\n", pod.Spec.PriorityClassName)
+	}
+	fmt.Fprintf(w, "Service Account:\t%s\n", pod.Spec.ServiceAccountName)
+	if pod.Spec.NodeName != "" {
+		fmt.Fprintf(w, "Node:\t%s/%s\n", pod.Spec.NodeName, pod.Status.HostIP)
+	} else {
+		fmt.Fprintf(w, "Node:\t<none>\n")
+	}
+	if pod.Status.StartTime != nil {
+		fmt.Fprintf(w, "Start Time:\t%s\n", pod.Status.StartTime.Time.Format(time.RFC1123Z))
+	}
+	fmt.Fprintf(w, "Labels:\t%s\n", formatMap(pod.Labels))
+	fmt.Fprintf(w, "Annotations:\t%s\n", formatMap(pod.Annotations))
+	if pod.DeletionTimestamp != nil {
+		fmt.Fprintf(w, "Status:\tTerminating\n")
+	} else {
+		fmt.Fprintf(w, "Status:\t%s\n", pod.Status.Phase)
+	}
+	if pod.Status.Reason != "" {
+		fmt.Fprintf(w, "Reason:\t%s\n", pod.Status.Reason)
+	}
+	if pod.Status.Message != "" {
+		fmt.Fprintf(w, "Message:\t%s\n", pod.Status.Message)
+	}
+	fmt.Fprintf(w, "IP:\t%s\n", pod.Status.PodIP)
+	if len(pod.Status.PodIPs) > 1 {
+		var ips []string
+		for _, ip := range pod.Status.PodIPs {
+			ips = append(ips, ip.IP)
+		}
+		fmt.Fprintf(w, "IPs:\t%s\n", strings.Join(ips, ", "))
+	}
+	if len(owners) > 0 {
+		fmt.Fprintf(w, "Controlled By:\t%s\n", strings.Join(owners, " <- "))
+	} else {
+		fmt.Fprintf(w, "Controlled By:\t<none>\n")
+	}
+
+	if len(pod.Spec.InitContainers) > 0 {
+		fmt.Fprintf(w, "Init Containers:\n")
+		for _, c := range pod.Spec.InitContainers {
+			writeContainer(w, c, containerStatus(pod.Status.InitContainerStatuses, c.Name))
+		}
+	}
+	fmt.Fprintf(w, "Containers:\n")
+	for _, c := range pod.Spec.Containers {
+		writeContainer(w, c, containerStatus(pod.Status.ContainerStatuses, c.Name))
+	}
+	if len(pod.Spec.EphemeralContainers) > 0 {
+		fmt.Fprintf(w, "Ephemeral Containers:\n")
+		for _, ec := range pod.Spec.EphemeralContainers {
+			writeContainer(w, corev1.Container(ec.EphemeralContainerCommon), containerStatus(pod.Status.EphemeralContainerStatuses, ec.Name))
+		}
+	}
+
+	fmt.Fprintf(w, "Conditions:\n")
+	fmt.Fprintf(w, "  Type\tStatus\tLast Transition\n")
+	for _, cond := range pod.Status.Conditions {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", cond.Type, cond.Status, FormatAge(cond.LastTransitionTime.Time))
+	}
+
+	writeVolumes(w, pod.Spec.Volumes)
+
+	fmt.Fprintf(w, "QoS Class:\t%s\n", QOSClass(pod))
+	fmt.Fprintf(w, "Node-Selectors:\t%s\n", formatMap(pod.Spec.NodeSelector))
+	fmt.Fprintf(w, "Tolerations:\t%s\n", formatTolerations(pod.Spec.Tolerations))
+}
+
+func writeContainer(w io.Writer, c corev1.Container, status *corev1.ContainerStatus) {
+	fmt.Fprintf(w, "  %s:\n", c.Name)
+	fmt.Fprintf(w, "    Image:\t%s\n", c.Image)
+	if status != nil && status.ImageID != "" {
+		fmt.Fprintf(w, "    Image ID:\t%s\n", status.ImageID)
+	}
+	writeContainerCommand(w, c)
+
+	if status != nil {
+		fmt.Fprintf(w, "    State:\t%s\n", formatContainerState(status.State))
+		if status.LastTerminationState != (corev1.ContainerState{}) {
+			fmt.Fprintf(w, "    Last State:\t%s\n", formatContainerState(status.LastTerminationState))
+		}
+		fmt.Fprintf(w, "    Ready:\t%v\n", status.Ready)
+		fmt.Fprintf(w, "    Restart Count:\t%d\n", status.RestartCount)
+	} else {
+		fmt.Fprintf(w, "    State:\t<unknown>\n")
+	}
+
+	writeContainerConfig(w, c)
+}
+
+// writeTemplateContainer describes a container of a pod template, which has
+// no runtime state.
+func writeTemplateContainer(w io.Writer, c corev1.Container) {
+	fmt.Fprintf(w, "  %s:\n", c.Name)
+	fmt.Fprintf(w, "    Image:\t%s\n", c.Image)
+	writeContainerCommand(w, c)
+	writeContainerConfig(w, c)
+}
+
+func writeContainerCommand(w io.Writer, c corev1.Container) {
+	if len(c.Ports) > 0 {
+		var ports []string
+		for _, p := range c.Ports {
+			port := fmt.Sprintf("%d/%s", p.ContainerPort, p.Protocol)
+			if p.Name != "" {
+				port = fmt.Sprintf("%s (%s)", port, p.Name)
+			}
+			ports = append(ports, port)
+		}
+		fmt.Fprintf(w, "    Ports:\t%s\n", strings.Join(ports, ", "))
+	}
+	if len(c.Command) > 0 {
+		fmt.Fprintf(w, "    Command:\t%s\n", strings.Join(c.Command, " "))
+	}
+	if len(c.Args) > 0 {
+		fmt.Fprintf(w, "    Args:\t%s\n", strings.Join(c.Args, " "))
+	}
+}
+
+func writeContainerConfig(w io.Writer, c corev1.Container) {
+	if len(c.Resources.Limits) > 0 {
+		fmt.Fprintf(w, "    Limits:\t%s\n", formatResourceList(c.Resources.Limits))
+	}
+	if len(c.Resources.Requests) > 0 {
+		fmt.Fprintf(w, "    Requests:\t%s\n", formatResourceList(c.Resources.Requests))
+	}
+	if c.LivenessProbe != nil {
+		fmt.Fprintf(w, "    Liveness:\t%s\n", FormatProbe(c.LivenessProbe))
+	}
+	if c.ReadinessProbe != nil {
+		fmt.Fprintf(w, "    Readiness:\t%s\n", FormatProbe(c.ReadinessProbe))
+	}
+	if c.StartupProbe != nil {
+		fmt.Fprintf(w, "    Startup:\t%s\n", FormatProbe(c.StartupProbe))
+	}
+
+	if len(c.Env) > 0 || len(c.EnvFrom) > 0 {
+		fmt.Fprintf(w, "    Environment:\n")
+		for _, from := range c.EnvFrom {
+			switch {
+			case from.ConfigMapRef != nil:
+				fmt.Fprintf(w, "      <all keys>\tfrom ConfigMap %s (prefix %q)\n", from.ConfigMapRef.Name, from.Prefix)
+			case from.SecretRef != nil:
+				fmt.Fprintf(w, "      <all keys>\tfrom Secret %s (prefix %q)\n", from.SecretRef.Name, from.Prefix)
+			}
+		}
+		for _, env := range c.Env {
+			fmt.Fprintf(w, "      %s:\t%s\n", env.Name, formatEnvValue(env))
+		}
+	}
+
+	if len(c.VolumeMounts) > 0 {
+		fmt.Fprintf(w, "    Mounts:\n")
+		for _, m := range c.VolumeMounts {
+			mode := "rw"
+			if m.ReadOnly {
+				mode = "ro"
+			}
+			fmt.Fprintf(w, "      %s from %s (%s)\n", m.MountPath, m.Name, mode)
+		}
+	}
+}
+
+func formatContainerState(state corev1.ContainerState) string {
+	switch {
+	case state.Running != nil:
+		return fmt.Sprintf("Running (started %s ago)", FormatAge(state.Running.StartedAt.Time))
+	case state.Waiting != nil:
+		if state.Waiting.Message != "" {
+			return fmt.Sprintf("Waiting (%s: %s)", state.Waiting.Reason, state.Waiting.Message)
+		}
+		return fmt.Sprintf("Waiting (%s)", state.Waiting.Reason)
+	case state.Terminated != nil:
+		t := state.Terminated
+		s := fmt.Sprintf("Terminated (%s, exit code %d", t.Reason, t.ExitCode)
+		if t.Signal != 0 {
+			s += fmt.Sprintf(", signal %d", t.Signal)
+		}
+		if !t.FinishedAt.IsZero() {
+			s += fmt.Sprintf(", finished %s ago", FormatAge(t.FinishedAt.Time))
+		}
+		return s + ")"
+	}
+	return "<unknown>"
+}
+
+func formatEnvValue(env corev1.EnvVar) string {
+	if env.ValueFrom == nil {
+		return env.Value
+	}
+	switch {
+	case env.ValueFrom.SecretKeyRef != nil:
+		return fmt.Sprintf("<set to the key '%s' in secret '%s'>", env.ValueFrom.SecretKeyRef.Key, env.ValueFrom.SecretKeyRef.Name)
+	case env.ValueFrom.ConfigMapKeyRef != nil:
+		return fmt.Sprintf("<set to the key '%s' of config map '%s'>", env.ValueFrom.ConfigMapKeyRef.Key, env.ValueFrom.ConfigMapKeyRef.Name)
+	case env.ValueFrom.FieldRef != nil:
+		return fmt.Sprintf("(%s:%s)", env.ValueFrom.FieldRef.APIVersion, env.ValueFrom.FieldRef.FieldPath)
+	case env.ValueFrom.ResourceFieldRef != nil:
+		return fmt.Sprintf("%s (%s)", env.ValueFrom.ResourceFieldRef.Resource, env.ValueFrom.ResourceFieldRef.ContainerName)
+	}
+	return ""
+}
+
+// FormatProbe renders a probe the way kubectl describe does, e.g.
+// "http-get http://:8080/healthz delay=0s timeout=1s period=10s #success=1 #failure=3".
+func FormatProbe(p *corev1.Probe) string {
+	var handler string
+	switch {
+	case p.HTTPGet != nil:
+		scheme := strings.ToLower(string(p.HTTPGet.Scheme))
+		if scheme == "" {
+			scheme = "http"
+		}
+		handler = fmt.Sprintf("http-get %s://%s:%s%s", scheme, p.HTTPGet.Host, p.HTTPGet.Port.String(), p.HTTPGet.Path)
+	case p.TCPSocket != nil:
+		handler = fmt.Sprintf("tcp-socket %s:%s", p.TCPSocket.Host, p.TCPSocket.Port.String())
+	case p.GRPC != nil:
+		handler = fmt.Sprintf("grpc <pod>:%d", p.GRPC.Port)
+	case p.Exec != nil:
+		handler = fmt.Sprintf("exec [%s]", strings.Join(p.Exec.Command, " "))
+	default:
+		handler = "unknown"
+	}
+
+	return fmt.Sprintf("%s delay=%ds timeout=%ds period=%ds #success=%d #failure=%d",
+		handler, p.InitialDelaySeconds, p.TimeoutSeconds, p.PeriodSeconds, p.SuccessThreshold, p.FailureThreshold)
+}
+
+func formatResourceList(list corev1.ResourceList) string {
+	names := make([]string, 0, len(list))
+	for name := range list {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		q := list[corev1.ResourceName(name)]
+		parts = append(parts, fmt.Sprintf("%s=%s", name, q.String()))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func writeVolumes(w io.Writer, volumes []corev1.Volume) {
+	if len(volumes) == 0 {
+		fmt.Fprintf(w, "Volumes:\t<none>\n")
+		return
+	}
+
+	fmt.Fprintf(w, "Volumes:\n")
+	for _, v := range volumes {
+		fmt.Fprintf(w, "  %s:\t%s\n", v.Name, formatVolumeSource(v.VolumeSource))
+	}
+}
+
+func formatVolumeSource(src corev1.VolumeSource) string {
+	switch {
+	case src.ConfigMap != nil:
+		return fmt.Sprintf("ConfigMap %s", src.ConfigMap.Name)
+	case src.Secret != nil:
+		return fmt.Sprintf("Secret %s", src.Secret.SecretName)
+	case src.PersistentVolumeClaim != nil:
+		return fmt.Sprintf("PersistentVolumeClaim %s (read-only: %v)", src.PersistentVolumeClaim.ClaimName, src.PersistentVolumeClaim.ReadOnly)
+	case src.EmptyDir != nil:
+		medium := string(src.EmptyDir.Medium)
+		if medium == "" {
+			medium = "node disk"
+		}
+		return fmt.Sprintf("EmptyDir (medium: %s)", medium)
+	case src.HostPath != nil:
+		return fmt.Sprintf("HostPath %s", src.HostPath.Path)
+	case src.Projected != nil:
+		var sources []string
+		for _, s := range src.Projected.Sources {
+			switch {
+			case s.ServiceAccountToken != nil:
+				sources = append(sources, "ServiceAccountToken")
+			case s.ConfigMap != nil:
+				sources = append(sources, "ConfigMap "+s.ConfigMap.Name)
+			case s.Secret != nil:
+				sources = append(sources, "Secret "+s.Secret.Name)
+			case s.DownwardAPI != nil:
+				sources = append(sources, "DownwardAPI")
+			}
+		}
+		return fmt.Sprintf("Projected (%s)", strings.Join(sources, ", "))
+	case src.DownwardAPI != nil:
+		return "DownwardAPI"
+	case src.CSI != nil:
+		return fmt.Sprintf("CSI %s", src.CSI.Driver)
+	case src.NFS != nil:
+		return fmt.Sprintf("NFS %s:%s", src.NFS.Server, src.NFS.Path)
+	case src.Ephemeral != nil:
+		return "Ephemeral volume claim"
+	}
+	return "<unknown>"
+}
+
+func formatTolerations(tolerations []corev1.Toleration) string {
+	if len(tolerations) == 0 {
+		return "<none>"
+	}
+
+	var parts []string
+	for _, t := range tolerations {
+		s := t.Key
+		if t.Operator == corev1.TolerationOpExists {
+			s += ":Exists"
+		} else if t.Value != "" {
+			s += "=" + t.Value
+		}
+		if t.Effect != "" {
+			s += ":" + string(t.Effect)
+		}
+		if t.TolerationSeconds != nil {
+			s += fmt.Sprintf(" for %ds", *t.TolerationSeconds)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func writeEvents(w io.Writer, events []corev1.Event) {
+	if len(events) == 0 {
+		fmt.Fprintf(w, "Events:\t<none>\n")
+		return
+	}
+
+	fmt.Fprintf(w, "Events:\n")
+	fmt.Fprintf(w, "  Type\tReason\tAge\tFrom\tMessage\n")
+	for _, e := range events {
+		age := FormatAge(EventTime(e))
+		if e.Count > 1 {
+			age = fmt.Sprintf("%s (x%d over %s)", age, e.Count, FormatAge(e.FirstTimestamp.Time))
+		}
+		from := e.Source.Component
+		if from == "" {
+			from = e.ReportingController
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", e.Type, e.Reason, age, from, strings.TrimSpace(e.Message))
+	}
+}
+
+func containerStatus(statuses []corev1.ContainerStatus, name string) *corev1.ContainerStatus {
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// QOSClass returns the pod's QoS class, computing it from the container
+// resources when the status does not report one yet.
+func QOSClass(pod *corev1.Pod) corev1.PodQOSClass {
+	if pod.Status.QOSClass != "" {
+		return pod.Status.QOSClass
+	}
+
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	anySet, guaranteed := false, true
+	for _, c := range containers {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			req, hasReq := c.Resources.Requests[name]
+			lim, hasLim := c.Resources.Limits[name]
+			if hasReq || hasLim {
+				anySet = true
+			}
+			if !hasLim || (hasReq && req.Cmp(lim) != 0) {
+				guaranteed = false
+			}
+		}
+	}
+
+	switch {
+	case !anySet:
+		return corev1.PodQOSBestEffort
+	case guaranteed:
+		return corev1.PodQOSGuaranteed
+	}
+	return corev1.PodQOSBurstable
+}
+
+// DefaultContainer honors the kubectl.kubernetes.io/default-container
+// annotation and otherwise picks the first container.
+func DefaultContainer(pod *corev1.Pod) string {
+	if name := pod.Annotations["kubectl.kubernetes.io/default-container"]; name != "" {
+		for _, c := range pod.Spec.Containers {
+			if c.Name == name {
+				return name
+			}
+		}
+	}
+	if len(pod.Spec.Containers) == 0 {
+		return ""
+	}
+	return pod.Spec.Containers[0].Name
+}
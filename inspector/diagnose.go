@@ -0,0 +1,592 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Severity ranks a finding.
+type Severity string
+
+const (
+	SeverityError   Severity = "ERROR"
+	SeverityWarning Severity = "WARNING"
+)
+
+// Finding is one problem detected on a pod, explained in plain language.
+type Finding struct {
+	Severity  Severity
+	Container string
+	Reason    string
+	Summary   string
+	Details   []string
+}
+
+// DiagnoseOptions tunes DiagnosePod.
+type DiagnoseOptions struct {
+	// Metrics is used to compare memory usage with limits after an
+	// OOMKill. It may be nil when metrics-server is not available.
+	Metrics versioned.Interface
+	// LogLines is how many lines of the previous container log to include
+	// for crashing containers.
+	LogLines int64
+}
+
+// DiagnosePod inspects a pod's status, events and references and returns
+// the likely causes of any failure, most severe first.
+func DiagnosePod(clientset *kubernetes.Clientset, pod *corev1.Pod, opts DiagnoseOptions) ([]Finding, error) {
+	events, err := PodEvents(clientset, pod)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &diagnoser{clientset: clientset, pod: pod, events: events, opts: opts}
+	d.checkPhase()
+	d.checkScheduling()
+	d.checkReferences()
+	for _, c := range pod.Spec.InitContainers {
+		d.checkContainer(c, containerStatus(pod.Status.InitContainerStatuses, c.Name), true)
+	}
+	for _, c := range pod.Spec.Containers {
+		d.checkContainer(c, containerStatus(pod.Status.ContainerStatuses, c.Name), false)
+	}
+
+	var errs, warnings []Finding
+	for _, f := range d.findings {
+		if f.Severity == SeverityError {
+			errs = append(errs, f)
+		} else {
+			warnings = append(warnings, f)
+		}
+	}
+	return append(errs, warnings...), nil
+}
+
+// FormatDiagnosis renders findings for display.
+func FormatDiagnosis(pod *corev1.Pod, findings []Finding) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Diagnosis for pod %s/%s (%s)\n", pod.Namespace, pod.Name, pod.Status.Phase)
+	if len(findings) == 0 {
+		b.WriteString("  No problems found.\n")
+		return b.String()
+	}
+
+	for _, f := range findings {
+		subject := f.Reason
+		if f.Container != "" {
+			subject = fmt.Sprintf("container %s: %s", f.Container, f.Reason)
+		}
+		fmt.Fprintf(&b, "\n[%s] %s\n", f.Severity, subject)
+		fmt.Fprintf(&b, "  %s\n", f.Summary)
+		for _, line := range f.Details {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+type diagnoser struct {
+	clientset *kubernetes.Clientset
+	pod       *corev1.Pod
+	events    []corev1.Event
+	opts      DiagnoseOptions
+	findings  []Finding
+}
+
+func (d *diagnoser) add(f Finding) {
+	d.findings = append(d.findings, f)
+}
+
+func (d *diagnoser) checkPhase() {
+	status := d.pod.Status
+	if status.Phase != corev1.PodFailed {
+		return
+	}
+
+	f := Finding{
+		Severity: SeverityError,
+		Reason:   "Failed",
+		Summary:  "The pod has stopped and will not be restarted.",
+	}
+	if status.Reason != "" {
+		f.Reason = status.Reason
+	}
+	if status.Reason == "Evicted" {
+		f.Summary = "The kubelet evicted the pod, usually because the node ran short of memory, disk or PIDs."
+	}
+	if status.Message != "" {
+		f.Details = append(f.Details, status.Message)
+	}
+	d.add(f)
+}
+
+func (d *diagnoser) checkScheduling() {
+	if d.pod.Status.Phase != corev1.PodPending || d.pod.Spec.NodeName != "" {
+		return
+	}
+
+	var unschedulable *corev1.PodCondition
+	for i, c := range d.pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse {
+			unschedulable = &d.pod.Status.Conditions[i]
+		}
+	}
+
+	message := ""
+	for _, e := range d.events {
+		if e.Reason == "FailedScheduling" {
+			message = e.Message
+		}
+	}
+	if message == "" && unschedulable != nil {
+		message = unschedulable.Message
+	}
+
+	if message == "" {
+		d.add(Finding{
+			Severity: SeverityWarning,
+			Reason:   "Pending",
+			Summary:  fmt.Sprintf("The pod has not been scheduled yet after %s and no scheduling failure was reported. Check that a scheduler is running for %q.", FormatAge(d.pod.CreationTimestamp.Time), schedulerName(d.pod)),
+		})
+		return
+	}
+
+	d.add(Finding{
+		Severity: SeverityError,
+		Reason:   "FailedScheduling",
+		Summary:  "The scheduler cannot find a node for the pod.",
+		Details:  explainSchedulingFailure(message),
+	})
+}
+
+func schedulerName(pod *corev1.Pod) string {
+	if pod.Spec.SchedulerName != "" {
+		return pod.Spec.SchedulerName
+	}
+	return corev1.DefaultSchedulerName
+}
+
+// explainSchedulingFailure decodes a FailedScheduling message such as
+//
+//	0/5 nodes are available: 2 Insufficient cpu, 3 node(s) had untolerated taint {dedicated: gpu}. preemption: ...
+//
+// into one line per reason with a hint on how to address it.
+func explainSchedulingFailure(message string) []string {
+	message = strings.TrimSpace(message)
+	if i := strings.Index(message, " preemption:"); i >= 0 {
+		message = message[:i]
+	}
+	message = strings.TrimSuffix(message, ".")
+
+	summary, reasons, ok := strings.Cut(message, ": ")
+	if !ok {
+		return []string{message}
+	}
+
+	lines := []string{summary + ":"}
+	for _, reason := range strings.Split(reasons, ", ") {
+		reason = strings.TrimSpace(reason)
+		line := "  - " + reason
+		if hint := schedulingHint(reason); hint != "" {
+			line += " -> " + hint
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func schedulingHint(reason string) string {
+	r := strings.ToLower(reason)
+	switch {
+	case strings.Contains(r, "insufficient"):
+		return "the requests do not fit in the free capacity of these nodes; lower the requests or add capacity"
+	case strings.Contains(r, "untolerated taint"), strings.Contains(r, "had taint"):
+		return "add a matching toleration or schedule onto other nodes"
+	case strings.Contains(r, "didn't match pod's node affinity/selector"), strings.Contains(r, "node affinity"):
+		return "no node has the labels required by nodeSelector or node affinity"
+	case strings.Contains(r, "unbound immediate persistentvolumeclaims"), strings.Contains(r, "persistentvolumeclaim"):
+		return "a PVC is not bound; check its storage class and provisioner"
+	case strings.Contains(r, "volume node affinity conflict"):
+		return "the pod's volume lives in a zone or on a node the pod cannot run on"
+	case strings.Contains(r, "free ports"):
+		return "a requested hostPort is already used on these nodes"
+	case strings.Contains(r, "too many pods"):
+		return "these nodes reached their pod limit"
+	case strings.Contains(r, "unschedulable"):
+		return "these nodes are cordoned"
+	case strings.Contains(r, "pod affinity"), strings.Contains(r, "anti-affinity"):
+		return "the pod (anti-)affinity rules cannot be satisfied"
+	case strings.Contains(r, "topology spread"):
+		return "the topology spread constraints cannot be satisfied"
+	}
+	return ""
+}
+
+func (d *diagnoser) checkContainer(c corev1.Container, status *corev1.ContainerStatus, init bool) {
+	if status == nil {
+		return
+	}
+
+	kind := "container"
+	if init {
+		kind = "init container"
+	}
+
+	if w := status.State.Waiting; w != nil {
+		switch w.Reason {
+		case "CrashLoopBackOff":
+			d.crashLoop(c, status, init)
+		case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull":
+			d.imagePull(c, w)
+		case "CreateContainerConfigError", "CreateContainerError", "RunContainerError":
+			d.add(Finding{
+				Severity:  SeverityError,
+				Container: c.Name,
+				Reason:    w.Reason,
+				Summary:   fmt.Sprintf("The %s could not be created from its spec; this is usually a missing ConfigMap, Secret or key, or an invalid command.", kind),
+				Details:   nonEmpty(w.Message),
+			})
+		}
+	}
+
+	if t := lastTermination(status); t != nil && t.Reason == "OOMKilled" {
+		d.oomKilled(c, status, t)
+	}
+
+	if !init && status.State.Running != nil && !status.Ready {
+		d.notReady(c, status)
+	}
+
+	if status.State.Running != nil && status.RestartCount >= 3 {
+		details := []string{}
+		if t := status.LastTerminationState.Terminated; t != nil {
+			details = append(details, fmt.Sprintf("Last exit: %s", describeExit(t)))
+		}
+		d.add(Finding{
+			Severity:  SeverityWarning,
+			Container: c.Name,
+			Reason:    "Restarting",
+			Summary:   fmt.Sprintf("The %s is running but has restarted %d times.", kind, status.RestartCount),
+			Details:   details,
+		})
+	}
+}
+
+func (d *diagnoser) crashLoop(c corev1.Container, status *corev1.ContainerStatus, init bool) {
+	f := Finding{
+		Severity:  SeverityError,
+		Container: c.Name,
+		Reason:    "CrashLoopBackOff",
+		Summary:   fmt.Sprintf("The container keeps exiting and has been restarted %d times; Kubernetes now waits longer between restarts.", status.RestartCount),
+	}
+	if init {
+		f.Summary = fmt.Sprintf("The init container keeps failing (%d restarts), so the pod's main containers never start.", status.RestartCount)
+	}
+
+	if t := status.LastTerminationState.Terminated; t != nil {
+		f.Details = append(f.Details, fmt.Sprintf("Last exit: %s", describeExit(t)))
+		if t.Message != "" {
+			f.Details = append(f.Details, fmt.Sprintf("Termination message: %s", strings.TrimSpace(t.Message)))
+		}
+	}
+
+	if lines := d.previousLogs(c.Name); len(lines) > 0 {
+		f.Details = append(f.Details, "Last log lines:")
+		for _, line := range lines {
+			f.Details = append(f.Details, "  | "+line)
+		}
+	}
+	d.add(f)
+}
+
+func (d *diagnoser) previousLogs(container string) []string {
+	tail := d.opts.LogLines
+	if tail <= 0 {
+		tail = 10
+	}
+	data, err := d.clientset.CoreV1().Pods(d.pod.Namespace).GetLogs(d.pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		Previous:  true,
+		TailLines: &tail,
+	}).DoRaw(context.TODO())
+	if err != nil {
+		return nil
+	}
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// describeExit explains common exit codes.
+func describeExit(t *corev1.ContainerStateTerminated) string {
+	s := fmt.Sprintf("exit code %d", t.ExitCode)
+	if t.Reason != "" {
+		s += fmt.Sprintf(" (%s)", t.Reason)
+	}
+	switch t.ExitCode {
+	case 1:
+		s += " - the application reported an error"
+	case 126:
+		s += " - the command is not executable"
+	case 127:
+		s += " - the command was not found in the image"
+	case 137:
+		s += " - killed with SIGKILL, by the OOM killer or after failing its liveness probe"
+	case 139:
+		s += " - segmentation fault"
+	case 143:
+		s += " - terminated with SIGTERM"
+	}
+	return s
+}
+
+func (d *diagnoser) imagePull(c corev1.Container, w *corev1.ContainerStateWaiting) {
+	f := Finding{
+		Severity:  SeverityError,
+		Container: c.Name,
+		Reason:    w.Reason,
+		Summary:   fmt.Sprintf("The image %s cannot be pulled.", c.Image),
+		Details:   nonEmpty(w.Message),
+	}
+
+	msg := strings.ToLower(w.Message)
+	switch {
+	case w.Reason == "InvalidImageName":
+		f.Details = append(f.Details, "Hint: the image reference is malformed; check for typos, upper-case letters or a stray tag separator.")
+	case w.Reason == "ErrImageNeverPull":
+		f.Details = append(f.Details, "Hint: imagePullPolicy is Never and the image is not present on the node.")
+	case strings.Contains(msg, "not found"), strings.Contains(msg, "manifest unknown"), strings.Contains(msg, "does not exist"):
+		f.Details = append(f.Details, "Hint: the repository or tag does not exist; check the image name and tag.")
+	case strings.Contains(msg, "unauthorized"), strings.Contains(msg, "authentication required"),
+		strings.Contains(msg, "denied"), strings.Contains(msg, "forbidden"), strings.Contains(msg, "401"), strings.Contains(msg, "403"):
+		f.Details = append(f.Details, "Hint: the registry rejected the credentials; the pod needs an imagePullSecret with access to this repository.")
+		f.Details = append(f.Details, d.pullSecretHints()...)
+	case strings.Contains(msg, "no such host"), strings.Contains(msg, "i/o timeout"),
+		strings.Contains(msg, "connection refused"), strings.Contains(msg, "tls"), strings.Contains(msg, "x509"):
+		f.Details = append(f.Details, "Hint: the node cannot reach the registry; check DNS, proxies, firewalls and the registry's TLS certificate.")
+	case strings.Contains(msg, "toomanyrequests"), strings.Contains(msg, "rate limit"):
+		f.Details = append(f.Details, "Hint: the registry is rate limiting pulls; authenticate or use a mirror.")
+	default:
+		f.Details = append(f.Details, d.pullSecretHints()...)
+	}
+	d.add(f)
+}
+
+// pullSecretHints reports image pull secrets, from the pod or its service
+// account, that do not exist.
+func (d *diagnoser) pullSecretHints() []string {
+	names := map[string]bool{}
+	for _, ref := range d.pod.Spec.ImagePullSecrets {
+		names[ref.Name] = true
+	}
+
+	saName := d.pod.Spec.ServiceAccountName
+	if saName == "" {
+		saName = "default"
+	}
+	if sa, err := d.clientset.CoreV1().ServiceAccounts(d.pod.Namespace).Get(context.TODO(), saName, metav1.GetOptions{}); err == nil {
+		for _, ref := range sa.ImagePullSecrets {
+			names[ref.Name] = true
+		}
+	}
+
+	if len(names) == 0 {
+		return []string{fmt.Sprintf("Neither the pod nor service account %s has an imagePullSecret.", saName)}
+	}
+
+	var hints []string
+	for name := range names {
+		secret, err := d.clientset.CoreV1().Secrets(d.pod.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			hints = append(hints, fmt.Sprintf("Image pull secret %s does not exist in namespace %s.", name, d.pod.Namespace))
+		case err == nil && secret.Type != corev1.SecretTypeDockerConfigJson && secret.Type != corev1.SecretTypeDockercfg:
+			hints = append(hints, fmt.Sprintf("Image pull secret %s has type %s instead of %s.", name, secret.Type, corev1.SecretTypeDockerConfigJson))
+		}
+	}
+	return hints
+}
+
+func (d *diagnoser) oomKilled(c corev1.Container, status *corev1.ContainerStatus, t *corev1.ContainerStateTerminated) {
+	f := Finding{
+		Severity:  SeverityError,
+		Container: c.Name,
+		Reason:    "OOMKilled",
+		Summary:   "The container was killed for using more memory than it is allowed.",
+	}
+	if !t.FinishedAt.IsZero() {
+		f.Details = append(f.Details, fmt.Sprintf("Killed %s ago; %d restarts in total.", FormatAge(t.FinishedAt.Time), status.RestartCount))
+	}
+
+	limit, hasLimit := c.Resources.Limits[corev1.ResourceMemory]
+	if hasLimit {
+		f.Details = append(f.Details, fmt.Sprintf("Memory limit: %s", limit.String()))
+	} else {
+		f.Details = append(f.Details, "The container has no memory limit, so the node itself ran out of memory.")
+	}
+	if request, ok := c.Resources.Requests[corev1.ResourceMemory]; ok {
+		f.Details = append(f.Details, fmt.Sprintf("Memory request: %s", request.String()))
+	}
+
+	if usage, ok := d.memoryUsage(c.Name); ok {
+		line := fmt.Sprintf("Current usage: %s", usage.String())
+		if hasLimit && limit.Value() > 0 {
+			line += fmt.Sprintf(" (%.0f%% of the limit)", float64(usage.Value())*100/float64(limit.Value()))
+		}
+		f.Details = append(f.Details, line)
+	}
+	if hasLimit {
+		f.Details = append(f.Details, "Hint: raise the memory limit or reduce the application's heap/cache size.")
+	}
+	d.add(f)
+}
+
+func (d *diagnoser) memoryUsage(container string) (resource.Quantity, bool) {
+	if d.opts.Metrics == nil {
+		return resource.Quantity{}, false
+	}
+	metrics, err := d.opts.Metrics.MetricsV1beta1().PodMetricses(d.pod.Namespace).Get(context.TODO(), d.pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return resource.Quantity{}, false
+	}
+	for _, c := range metrics.Containers {
+		if c.Name == container {
+			return *c.Usage.Memory(), true
+		}
+	}
+	return resource.Quantity{}, false
+}
+
+func (d *diagnoser) notReady(c corev1.Container, status *corev1.ContainerStatus) {
+	if c.ReadinessProbe == nil {
+		return
+	}
+
+	f := Finding{
+		Severity:  SeverityWarning,
+		Container: c.Name,
+		Reason:    "NotReady",
+		Summary:   "The container is running but failing its readiness probe, so it receives no Service traffic.",
+		Details:   []string{fmt.Sprintf("Probe: %s", FormatProbe(c.ReadinessProbe))},
+	}
+	for i := len(d.events) - 1; i >= 0; i-- {
+		e := d.events[i]
+		if e.Reason == "Unhealthy" && strings.HasPrefix(e.Message, "Readiness probe failed") {
+			f.Details = append(f.Details, fmt.Sprintf("Last failure (%s ago, x%d): %s", FormatAge(EventTime(e)), max(e.Count, 1), strings.TrimSpace(e.Message)))
+			break
+		}
+	}
+	if status.Started != nil && *status.Started && status.State.Running != nil {
+		f.Details = append(f.Details, fmt.Sprintf("Running for %s.", FormatAge(status.State.Running.StartedAt.Time)))
+	}
+	d.add(f)
+}
+
+// checkReferences reports ConfigMaps and Secrets that the pod mounts or
+// reads environment variables from but that do not exist. Optional
+// references are ignored.
+func (d *diagnoser) checkReferences() {
+	var refs []ConfigReference
+	for _, r := range PodSpecReferences(&d.pod.Spec) {
+		if !r.Optional {
+			refs = append(refs, r)
+		}
+	}
+
+	// Each object is fetched once; keys holds nil for objects that do not
+	// exist.
+	keys := map[string]map[string]bool{}
+	lookup := func(kind, name string) map[string]bool {
+		id := kind + "/" + name
+		if k, ok := keys[id]; ok {
+			return k
+		}
+		var found map[string]bool
+		if kind == "ConfigMap" {
+			cm, err := d.clientset.CoreV1().ConfigMaps(d.pod.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+			if err == nil {
+				found = map[string]bool{}
+				for k := range cm.Data {
+					found[k] = true
+				}
+				for k := range cm.BinaryData {
+					found[k] = true
+				}
+			} else if !apierrors.IsNotFound(err) {
+				found = map[string]bool{"*": true}
+			}
+		} else {
+			secret, err := d.clientset.CoreV1().Secrets(d.pod.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+			if err == nil {
+				found = map[string]bool{}
+				for k := range secret.Data {
+					found[k] = true
+				}
+			} else if !apierrors.IsNotFound(err) {
+				// Without read access we cannot tell; assume it exists.
+				found = map[string]bool{"*": true}
+			}
+		}
+		keys[id] = found
+		return found
+	}
+
+	missing := map[string]*Finding{}
+	var order []string
+	for _, r := range refs {
+		found := lookup(r.Kind, r.Name)
+		id := r.Kind + "/" + r.Name
+		switch {
+		case found == nil:
+		case r.Key != "" && !found[r.Key] && !found["*"]:
+			id += "#" + r.Key
+		default:
+			continue
+		}
+
+		f, ok := missing[id]
+		if !ok {
+			summary := fmt.Sprintf("%s %s does not exist in namespace %s.", r.Kind, r.Name, d.pod.Namespace)
+			if found != nil {
+				summary = fmt.Sprintf("%s %s has no key %s.", r.Kind, r.Name, r.Key)
+			}
+			f = &Finding{
+				Severity: SeverityError,
+				Reason:   "Missing" + r.Kind,
+				Summary:  summary,
+			}
+			missing[id] = f
+			order = append(order, id)
+		}
+		f.Details = append(f.Details, "Used by "+r.UsedBy)
+	}
+	for _, id := range order {
+		d.add(*missing[id])
+	}
+}
+
+// lastTermination returns the current termination state, or the previous one
+// for a container that has already been restarted.
+func lastTermination(status *corev1.ContainerStatus) *corev1.ContainerStateTerminated {
+	if status.State.Terminated != nil {
+		return status.State.Terminated
+	}
+	return status.LastTerminationState.Terminated
+}
+
+func nonEmpty(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return []string{strings.TrimSpace(s)}
+}
@@ -0,0 +1,431 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ingressClassAnnotation is the pre-IngressClass way of choosing a
+// controller, still honoured by most of them.
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+// IngressRoute is one host and path of an ingress with the backend it
+// routes to and the ready endpoints behind that backend.
+type IngressRoute struct {
+	Namespace string
+	Ingress   string
+	Class     string
+	Host      string
+	Path      string
+	PathType  string
+	Backend   string
+	Endpoints []string
+	TLSSecret string
+	Problems  []string
+}
+
+// FormatIngressTable renders ingresses with their CLASS, HOSTS, ADDRESS,
+// PORTS and AGE, like kubectl get ingress.
+func FormatIngressTable(ingresses []networkingv1.Ingress, showNamespace bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	header := []string{"NAME", "CLASS", "HOSTS", "ADDRESS", "PORTS", "AGE"}
+	if showNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for i := range ingresses {
+		ing := &ingresses[i]
+		var hosts []string
+		for _, rule := range ing.Spec.Rules {
+			host := rule.Host
+			if host == "" {
+				host = "*"
+			}
+			if !contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
+		}
+		var addresses []string
+		for _, lb := range ing.Status.LoadBalancer.Ingress {
+			if lb.IP != "" {
+				addresses = append(addresses, lb.IP)
+			} else if lb.Hostname != "" {
+				addresses = append(addresses, lb.Hostname)
+			}
+		}
+		ports := "80"
+		if len(ing.Spec.TLS) > 0 {
+			ports = "80, 443"
+		}
+
+		cols := []string{
+			ing.Name,
+			IngressClass(ing),
+			orNone(strings.Join(hosts, ",")),
+			orNone(strings.Join(addresses, ",")),
+			ports,
+			FormatAge(ing.CreationTimestamp.Time),
+		}
+		if showNamespace {
+			cols = append([]string{ing.Namespace}, cols...)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// IngressClass returns the class an ingress asks for, from
+// spec.ingressClassName or the legacy annotation.
+func IngressClass(ing *networkingv1.Ingress) string {
+	if ing.Spec.IngressClassName != nil && *ing.Spec.IngressClassName != "" {
+		return *ing.Spec.IngressClassName
+	}
+	if class := ing.Annotations[ingressClassAnnotation]; class != "" {
+		return class
+	}
+	return "<default>"
+}
+
+// IngressRoutes resolves every rule, path and default backend of ingresses
+// to its service port and ready endpoints. Routes to a missing service or
+// port and hosts whose TLS secret does not exist are recorded in Problems;
+// host and path pairs claimed more than once are too.
+func IngressRoutes(clientset *kubernetes.Clientset, ingresses []networkingv1.Ingress) ([]IngressRoute, error) {
+	r := &routeResolver{
+		clientset: clientset,
+		services:  map[string]map[string]*corev1.Service{},
+		slices:    map[string][]discoveryv1.EndpointSlice{},
+		secrets:   map[string]string{},
+	}
+
+	var routes []IngressRoute
+	for i := range ingresses {
+		ing := &ingresses[i]
+		class := IngressClass(ing)
+
+		add := func(host, path, pathType string, backend networkingv1.IngressBackend) error {
+			route := IngressRoute{
+				Namespace: ing.Namespace,
+				Ingress:   ing.Name,
+				Class:     class,
+				Host:      host,
+				Path:      path,
+				PathType:  pathType,
+			}
+			if err := r.resolveBackend(&route, backend); err != nil {
+				return err
+			}
+			if err := r.resolveTLS(&route, ing); err != nil {
+				return err
+			}
+			routes = append(routes, route)
+			return nil
+		}
+
+		if ing.Spec.DefaultBackend != nil {
+			if err := add("*", "<default>", "", *ing.Spec.DefaultBackend); err != nil {
+				return nil, err
+			}
+		}
+		for _, rule := range ing.Spec.Rules {
+			host := rule.Host
+			if host == "" {
+				host = "*"
+			}
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, p := range rule.HTTP.Paths {
+				path := p.Path
+				if path == "" {
+					path = "/"
+				}
+				pathType := ""
+				if p.PathType != nil {
+					pathType = string(*p.PathType)
+				}
+				if err := add(host, path, pathType, p.Backend); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	markDuplicateRoutes(routes)
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		return routes[i].Path < routes[j].Path
+	})
+	return routes, nil
+}
+
+// FormatRoutes renders routes as a HOST, PATH, BACKEND, ENDPOINTS table with
+// their TLS secret, class and ingress, followed by the problems found.
+func FormatRoutes(routes []IngressRoute, showNamespace bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	header := []string{"HOST", "PATH", "BACKEND", "ENDPOINTS", "TLS", "CLASS", "INGRESS"}
+	if showNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, route := range routes {
+		path := route.Path
+		if route.PathType != "" {
+			path += " (" + route.PathType + ")"
+		}
+		cols := []string{
+			route.Host,
+			path,
+			route.Backend,
+			orNone(abbreviate(route.Endpoints, 3)),
+			orNone(route.TLSSecret),
+			route.Class,
+			route.Ingress,
+		}
+		if showNamespace {
+			cols = append([]string{route.Namespace}, cols...)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+	w.Flush()
+
+	var problems []string
+	for _, route := range routes {
+		for _, problem := range route.Problems {
+			line := fmt.Sprintf("%s %s (ingress %s/%s): %s", route.Host, route.Path, route.Namespace, route.Ingress, problem)
+			if !contains(problems, line) {
+				problems = append(problems, line)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Fprintln(&result, "\nProblems:")
+		for _, problem := range problems {
+			fmt.Fprintf(&result, "  %s\n", problem)
+		}
+	}
+	return result.String()
+}
+
+// routeResolver looks up the services, endpoint slices and secrets that
+// routes refer to, listing each namespace once.
+type routeResolver struct {
+	clientset *kubernetes.Clientset
+	services  map[string]map[string]*corev1.Service
+	slices    map[string][]discoveryv1.EndpointSlice
+	// secrets holds the problem found with each TLS secret, empty when
+	// there is none.
+	secrets map[string]string
+}
+
+func (r *routeResolver) resolveBackend(route *IngressRoute, backend networkingv1.IngressBackend) error {
+	if backend.Resource != nil {
+		route.Backend = fmt.Sprintf("%s/%s", backend.Resource.Kind, backend.Resource.Name)
+		return nil
+	}
+	if backend.Service == nil {
+		route.Backend = "<none>"
+		route.Problems = append(route.Problems, "no backend")
+		return nil
+	}
+
+	port := backend.Service.Port.Name
+	if port == "" {
+		port = fmt.Sprintf("%d", backend.Service.Port.Number)
+	}
+	route.Backend = backend.Service.Name + ":" + port
+
+	services, err := r.namespaceServices(route.Namespace)
+	if err != nil {
+		return err
+	}
+	svc, ok := services[backend.Service.Name]
+	if !ok {
+		route.Problems = append(route.Problems, fmt.Sprintf("service %s does not exist", backend.Service.Name))
+		return nil
+	}
+	var svcPort *corev1.ServicePort
+	for i, p := range svc.Spec.Ports {
+		if (backend.Service.Port.Name != "" && p.Name == backend.Service.Port.Name) ||
+			(backend.Service.Port.Name == "" && p.Port == backend.Service.Port.Number) {
+			svcPort = &svc.Spec.Ports[i]
+			break
+		}
+	}
+	if svcPort == nil {
+		route.Problems = append(route.Problems, fmt.Sprintf("service %s has no port %s", svc.Name, port))
+		return nil
+	}
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		route.Endpoints = []string{svc.Spec.ExternalName}
+		return nil
+	}
+
+	slices, err := r.namespaceSlices(route.Namespace)
+	if err != nil {
+		return err
+	}
+	for _, slice := range slices {
+		if slice.Labels[discoveryv1.LabelServiceName] != svc.Name {
+			continue
+		}
+		// Slice ports are named after the service port they serve.
+		var target *int32
+		for _, p := range slice.Ports {
+			if p.Name != nil && *p.Name == svcPort.Name {
+				target = p.Port
+			}
+		}
+		if target == nil {
+			continue
+		}
+		for _, e := range slice.Endpoints {
+			if e.Conditions.Ready != nil && !*e.Conditions.Ready {
+				continue
+			}
+			for _, address := range e.Addresses {
+				route.Endpoints = append(route.Endpoints, fmt.Sprintf("%s:%d", address, *target))
+			}
+		}
+	}
+	sort.Strings(route.Endpoints)
+	return nil
+}
+
+func (r *routeResolver) resolveTLS(route *IngressRoute, ing *networkingv1.Ingress) error {
+	for _, tls := range ing.Spec.TLS {
+		if !tlsCovers(tls.Hosts, route.Host) {
+			continue
+		}
+		if tls.SecretName == "" {
+			// The controller's default certificate is used.
+			route.TLSSecret = "<controller default>"
+			return nil
+		}
+		route.TLSSecret = tls.SecretName
+
+		key := route.Namespace + "/" + tls.SecretName
+		problem, ok := r.secrets[key]
+		if !ok {
+			_, err := r.clientset.CoreV1().Secrets(route.Namespace).Get(context.TODO(), tls.SecretName, metav1.GetOptions{})
+			switch {
+			case err == nil:
+			case apierrors.IsNotFound(err):
+				problem = fmt.Sprintf("TLS secret %s does not exist", tls.SecretName)
+			case apierrors.IsForbidden(err):
+				// Reading ingresses is often allowed where reading
+				// secrets is not.
+				problem = fmt.Sprintf("TLS secret %s not checked (forbidden)", tls.SecretName)
+			default:
+				return fmt.Errorf("error getting TLS secret %s: %v", key, err)
+			}
+			r.secrets[key] = problem
+		}
+		if problem != "" {
+			route.Problems = append(route.Problems, problem)
+		}
+		return nil
+	}
+	return nil
+}
+
+func (r *routeResolver) namespaceServices(namespace string) (map[string]*corev1.Service, error) {
+	if services, ok := r.services[namespace]; ok {
+		return services, nil
+	}
+	list, err := r.clientset.CoreV1().Services(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing services: %v", err)
+	}
+	services := make(map[string]*corev1.Service, len(list.Items))
+	for i := range list.Items {
+		services[list.Items[i].Name] = &list.Items[i]
+	}
+	r.services[namespace] = services
+	return services, nil
+}
+
+func (r *routeResolver) namespaceSlices(namespace string) ([]discoveryv1.EndpointSlice, error) {
+	if slices, ok := r.slices[namespace]; ok {
+		return slices, nil
+	}
+	list, err := r.clientset.DiscoveryV1().EndpointSlices(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing endpoint slices: %v", err)
+	}
+	r.slices[namespace] = list.Items
+	return list.Items, nil
+}
+
+// tlsCovers reports whether a TLS entry's hosts include host, allowing for a
+// single-label wildcard such as *.example.com. An entry without hosts
+// applies to every host.
+func tlsCovers(hosts []string, host string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(h, "*."); ok {
+			if first, rest, found := strings.Cut(host, "."); found && first != "" && rest == suffix {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// markDuplicateRoutes flags host and path pairs that more than one ingress
+// of the same class claims; which one wins depends on the controller.
+func markDuplicateRoutes(routes []IngressRoute) {
+	owners := map[string][]int{}
+	for i, route := range routes {
+		key := route.Class + " " + route.Host + route.Path
+		owners[key] = append(owners[key], i)
+	}
+	for _, indexes := range owners {
+		if len(indexes) < 2 {
+			continue
+		}
+		for _, i := range indexes {
+			var others []string
+			for _, j := range indexes {
+				if j == i {
+					continue
+				}
+				other := routes[j].Namespace + "/" + routes[j].Ingress
+				if routes[j].Namespace == routes[i].Namespace && routes[j].Ingress == routes[i].Ingress {
+					other = "the same ingress"
+				}
+				if !contains(others, other) {
+					others = append(others, other)
+				}
+			}
+			sort.Strings(others)
+			routes[i].Problems = append(routes[i].Problems, fmt.Sprintf("host and path are also routed by %s", strings.Join(others, ", ")))
+		}
+	}
+}
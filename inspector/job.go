@@ -0,0 +1,270 @@
+package inspector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ManualJobAnnotation marks Jobs created from a CronJob by hand rather than
+// by its schedule, as kubectl create job --from does.
+const ManualJobAnnotation = "cronjob.kubernetes.io/instantiate"
+
+// JobStatus summarizes a Job as Complete, Failed, Suspended or Running.
+func JobStatus(job *batchv1.Job) string {
+	if jobCondition(job, batchv1.JobComplete) != nil {
+		return "Complete"
+	}
+	if c := jobCondition(job, batchv1.JobFailed); c != nil {
+		if c.Reason != "" {
+			return "Failed (" + c.Reason + ")"
+		}
+		return "Failed"
+	}
+	if job.Spec.Suspend != nil && *job.Spec.Suspend {
+		return "Suspended"
+	}
+	return "Running"
+}
+
+// JobFinished returns when a Job completed or failed, or false while it is
+// still running.
+func JobFinished(job *batchv1.Job) (time.Time, bool) {
+	if job.Status.CompletionTime != nil {
+		return job.Status.CompletionTime.Time, true
+	}
+	if c := jobCondition(job, batchv1.JobFailed); c != nil {
+		return c.LastTransitionTime.Time, true
+	}
+	return time.Time{}, false
+}
+
+// JobFailed reports whether a Job has failed for good.
+func JobFailed(job *batchv1.Job) bool {
+	return jobCondition(job, batchv1.JobFailed) != nil
+}
+
+// JobDuration is how long a Job ran, or has been running so far.
+func JobDuration(job *batchv1.Job) (time.Duration, bool) {
+	if job.Status.StartTime == nil {
+		return 0, false
+	}
+	end, ok := JobFinished(job)
+	if !ok {
+		end = time.Now()
+	}
+	return end.Sub(job.Status.StartTime.Time), true
+}
+
+// FormatJobTable renders jobs with their STATUS, COMPLETIONS, DURATION and
+// AGE, and the CronJob that created them.
+func FormatJobTable(jobs []batchv1.Job, showNamespace bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	header := []string{"NAME", "STATUS", "COMPLETIONS", "DURATION", "AGE", "CRONJOB"}
+	if showNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for i := range jobs {
+		job := &jobs[i]
+		cronJob := "<none>"
+		if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == "CronJob" {
+			cronJob = owner.Name
+		}
+		cols := []string{
+			job.Name,
+			JobStatus(job),
+			jobCompletions(job),
+			formatJobDuration(job),
+			FormatAge(job.CreationTimestamp.Time),
+			cronJob,
+		}
+		if showNamespace {
+			cols = append([]string{job.Namespace}, cols...)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// FormatCronJobTable renders cron jobs with their schedule and the times of
+// the last scheduled run, the last success and the last failure. The
+// failure time comes from the Jobs the CronJob still keeps in its history.
+func FormatCronJobTable(cronJobs []batchv1.CronJob, jobs []batchv1.Job, showNamespace bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	header := []string{"NAME", "SCHEDULE", "SUSPEND", "ACTIVE", "LAST SCHEDULE", "LAST SUCCESS", "LAST FAILURE", "AGE"}
+	if showNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for i := range cronJobs {
+		cj := &cronJobs[i]
+		suspended := cj.Spec.Suspend != nil && *cj.Spec.Suspend
+
+		lastFailure := "<none>"
+		var failedAt time.Time
+		for _, job := range CronJobHistory(cj, jobs) {
+			if finished, ok := JobFinished(&job); ok && JobFailed(&job) && finished.After(failedAt) {
+				failedAt = finished
+			}
+		}
+		if !failedAt.IsZero() {
+			lastFailure = FormatAge(failedAt) + " ago"
+		}
+
+		cols := []string{
+			cj.Name,
+			cj.Spec.Schedule,
+			fmt.Sprintf("%v", suspended),
+			fmt.Sprintf("%d", len(cj.Status.Active)),
+			formatAgo(cj.Status.LastScheduleTime),
+			formatAgo(cj.Status.LastSuccessfulTime),
+			lastFailure,
+			FormatAge(cj.CreationTimestamp.Time),
+		}
+		if showNamespace {
+			cols = append([]string{cj.Namespace}, cols...)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// CronJobHistory returns the Jobs controlled by cj, oldest first.
+func CronJobHistory(cj *batchv1.CronJob, jobs []batchv1.Job) []batchv1.Job {
+	var history []batchv1.Job
+	for _, job := range jobs {
+		if owner := metav1.GetControllerOf(&job); owner != nil && owner.UID == cj.UID {
+			history = append(history, job)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].CreationTimestamp.Before(&history[j].CreationTimestamp)
+	})
+	return history
+}
+
+// FormatCronJobHistory renders the runs of a CronJob with their start time,
+// duration and outcome, followed by success and duration statistics.
+func FormatCronJobHistory(cj *batchv1.CronJob, history []batchv1.Job) string {
+	if len(history) == 0 {
+		return fmt.Sprintf("No jobs found for cron job %s (it keeps %d successful and %d failed jobs)\n",
+			cj.Name, derefInt32Or(cj.Spec.SuccessfulJobsHistoryLimit, 3), derefInt32Or(cj.Spec.FailedJobsHistoryLimit, 1))
+	}
+
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tTRIGGER\tSTARTED\tDURATION\tCOMPLETIONS\tSTATUS")
+
+	succeeded, failed := 0, 0
+	var total, longest time.Duration
+	finished := 0
+	for i := range history {
+		job := &history[i]
+		trigger := "schedule"
+		if job.Annotations[ManualJobAnnotation] == "manual" {
+			trigger = "manual"
+		}
+		started := "<pending>"
+		if job.Status.StartTime != nil {
+			started = job.Status.StartTime.Time.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", job.Name, trigger, started, formatJobDuration(job), jobCompletions(job), JobStatus(job))
+
+		if _, done := JobFinished(job); !done {
+			continue
+		}
+		if JobFailed(job) {
+			failed++
+		} else {
+			succeeded++
+		}
+		if d, ok := JobDuration(job); ok {
+			finished++
+			total += d
+			if d > longest {
+				longest = d
+			}
+		}
+	}
+	w.Flush()
+
+	fmt.Fprintf(&result, "\n%d succeeded, %d failed", succeeded, failed)
+	if finished > 0 {
+		fmt.Fprintf(&result, "; average duration %s, longest %s", formatExactDuration(total/time.Duration(finished)), formatExactDuration(longest))
+	}
+	fmt.Fprintln(&result)
+	return result.String()
+}
+
+func jobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		c := &job.Status.Conditions[i]
+		if c.Type == conditionType && c.Status == corev1.ConditionTrue {
+			return c
+		}
+	}
+	return nil
+}
+
+func jobCompletions(job *batchv1.Job) string {
+	completions := "1"
+	if job.Spec.Completions != nil {
+		completions = fmt.Sprintf("%d", *job.Spec.Completions)
+	}
+	return fmt.Sprintf("%d/%s", job.Status.Succeeded, completions)
+}
+
+func formatJobDuration(job *batchv1.Job) string {
+	d, ok := JobDuration(job)
+	if !ok {
+		return "-"
+	}
+	return formatExactDuration(d)
+}
+
+// formatExactDuration renders d with two units (e.g. 2m30s, 1h5m), which is
+// more useful than FormatDuration for job run times.
+func formatExactDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%ds", int(d.Minutes()), int(d.Seconds())%60)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh", int(d.Hours()/24), int(d.Hours())%24)
+	}
+}
+
+func formatAgo(t *metav1.Time) string {
+	if t == nil || t.IsZero() {
+		return "<none>"
+	}
+	return FormatAge(t.Time) + " ago"
+}
+
+func derefInt32Or(p *int32, fallback int32) int32 {
+	if p == nil {
+		return fallback
+	}
+	return *p
+}
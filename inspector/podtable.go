@@ -0,0 +1,281 @@
+package inspector
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodTableOptions controls FormatPodTable.
+type PodTableOptions struct {
+	// SortBy names a column to sort on (see PodTableColumns). Empty keeps
+	// the order of the input.
+	SortBy string
+	// ShowNamespace adds a NAMESPACE column.
+	ShowNamespace bool
+	// ShowLabels adds a LABELS column.
+	ShowLabels bool
+}
+
+// PodTableColumns lists the columns FormatPodTable can sort on.
+var PodTableColumns = []string{"namespace", "name", "ready", "status", "restarts", "age", "ip", "node", "nominated-node"}
+
+// PodRow holds the values shown for one pod in the pod table.
+type PodRow struct {
+	Namespace     string
+	Name          string
+	Ready         string
+	Status        string
+	Restarts      string
+	Age           string
+	IP            string
+	Node          string
+	NominatedNode string
+	Labels        string
+
+	readyCount   int
+	restartCount int32
+	created      time.Time
+}
+
+// NewPodRow computes the table values for pod the way kubectl get pods does.
+func NewPodRow(pod *corev1.Pod) PodRow {
+	status, ready, total, restarts, lastRestart := podStatus(pod)
+
+	row := PodRow{
+		Namespace:     pod.Namespace,
+		Name:          pod.Name,
+		Ready:         fmt.Sprintf("%d/%d", ready, total),
+		Status:        status,
+		Restarts:      fmt.Sprintf("%d", restarts),
+		Age:           FormatAge(pod.CreationTimestamp.Time),
+		IP:            orNone(pod.Status.PodIP),
+		Node:          orNone(pod.Spec.NodeName),
+		NominatedNode: orNone(pod.Status.NominatedNodeName),
+		Labels:        formatMap(pod.Labels),
+		readyCount:    ready,
+		restartCount:  restarts,
+		created:       pod.CreationTimestamp.Time,
+	}
+	if restarts > 0 && !lastRestart.IsZero() {
+		row.Restarts = fmt.Sprintf("%d (%s ago)", restarts, FormatAge(lastRestart))
+	}
+	return row
+}
+
+// FormatPodTable renders pods as a table with READY, STATUS, RESTARTS, AGE,
+// IP, NODE and NOMINATED NODE columns.
+func FormatPodTable(pods []corev1.Pod, opts PodTableOptions) (string, error) {
+	rows := make([]PodRow, 0, len(pods))
+	for i := range pods {
+		rows = append(rows, NewPodRow(&pods[i]))
+	}
+	if err := SortPodRows(rows, opts.SortBy); err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	var header []string
+	if opts.ShowNamespace {
+		header = append(header, "NAMESPACE")
+	}
+	header = append(header, "NAME", "READY", "STATUS", "RESTARTS", "AGE", "IP", "NODE", "NOMINATED NODE")
+	if opts.ShowLabels {
+		header = append(header, "LABELS")
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, r := range rows {
+		var cols []string
+		if opts.ShowNamespace {
+			cols = append(cols, r.Namespace)
+		}
+		cols = append(cols, r.Name, r.Ready, r.Status, r.Restarts, r.Age, r.IP, r.Node, r.NominatedNode)
+		if opts.ShowLabels {
+			cols = append(cols, r.Labels)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+
+	w.Flush()
+	return result.String(), nil
+}
+
+// SortPodRows sorts rows on the named column. Numeric columns sort
+// numerically, AGE from youngest to oldest and IP by address.
+func SortPodRows(rows []PodRow, column string) error {
+	var less func(a, b PodRow) bool
+	switch strings.ToLower(strings.ReplaceAll(column, "_", "-")) {
+	case "":
+		return nil
+	case "namespace":
+		less = func(a, b PodRow) bool { return a.Namespace < b.Namespace }
+	case "name":
+		less = func(a, b PodRow) bool { return a.Name < b.Name }
+	case "ready":
+		less = func(a, b PodRow) bool { return a.readyCount < b.readyCount }
+	case "status":
+		less = func(a, b PodRow) bool { return a.Status < b.Status }
+	case "restarts":
+		less = func(a, b PodRow) bool { return a.restartCount < b.restartCount }
+	case "age":
+		less = func(a, b PodRow) bool { return a.created.After(b.created) }
+	case "ip":
+		less = func(a, b PodRow) bool { return compareIP(a.IP, b.IP) < 0 }
+	case "node":
+		less = func(a, b PodRow) bool { return a.Node < b.Node }
+	case "nominated-node", "nominated node":
+		less = func(a, b PodRow) bool { return a.NominatedNode < b.NominatedNode }
+	default:
+		return fmt.Errorf("cannot sort by %q; choose one of %s", column, strings.Join(PodTableColumns, ", "))
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+	return nil
+}
+
+// podStatus mirrors the STATUS column of kubectl get pods: waiting and
+// termination reasons of the containers take precedence over the phase,
+// and init containers are reported as Init:N/M or Init:<reason>. It also
+// returns the ready and total container counts, the restart count and the
+// time of the most recent restart.
+func podStatus(pod *corev1.Pod) (string, int, int, int32, time.Time) {
+	reason := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		reason = pod.Status.Reason
+	}
+
+	total := len(pod.Spec.Containers)
+	ready := 0
+	var restarts int32
+	var lastRestart time.Time
+	noteRestart := func(status corev1.ContainerStatus, count *int32) {
+		*count += status.RestartCount
+		if t := status.LastTerminationState.Terminated; t != nil && t.FinishedAt.After(lastRestart) {
+			lastRestart = t.FinishedAt.Time
+		}
+	}
+
+	// Sidecars are init containers that keep running; they count towards
+	// READY like regular containers.
+	sidecars := map[string]bool{}
+	for _, c := range pod.Spec.InitContainers {
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			sidecars[c.Name] = true
+			total++
+		}
+	}
+
+	// Restarts of regular init containers only matter while the pod is
+	// still initializing.
+	var initRestarts int32
+	initializing := false
+	for i, status := range pod.Status.InitContainerStatuses {
+		if !sidecars[status.Name] {
+			noteRestart(status, &initRestarts)
+		} else {
+			noteRestart(status, &restarts)
+			if status.Started != nil && *status.Started {
+				if status.Ready {
+					ready++
+				}
+				continue
+			}
+		}
+		switch {
+		case status.State.Terminated != nil && status.State.Terminated.ExitCode == 0:
+			continue
+		case status.State.Terminated != nil:
+			t := status.State.Terminated
+			switch {
+			case t.Reason != "":
+				reason = "Init:" + t.Reason
+			case t.Signal != 0:
+				reason = fmt.Sprintf("Init:Signal:%d", t.Signal)
+			default:
+				reason = fmt.Sprintf("Init:ExitCode:%d", t.ExitCode)
+			}
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "" && status.State.Waiting.Reason != "PodInitializing":
+			reason = "Init:" + status.State.Waiting.Reason
+		default:
+			reason = fmt.Sprintf("Init:%d/%d", i, len(pod.Spec.InitContainers))
+		}
+		initializing = true
+		break
+	}
+
+	if initializing {
+		restarts += initRestarts
+	} else {
+		hasRunning := false
+		for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
+			status := pod.Status.ContainerStatuses[i]
+			noteRestart(status, &restarts)
+
+			switch {
+			case status.State.Waiting != nil && status.State.Waiting.Reason != "":
+				reason = status.State.Waiting.Reason
+			case status.State.Terminated != nil && status.State.Terminated.Reason != "":
+				reason = status.State.Terminated.Reason
+			case status.State.Terminated != nil:
+				if status.State.Terminated.Signal != 0 {
+					reason = fmt.Sprintf("Signal:%d", status.State.Terminated.Signal)
+				} else {
+					reason = fmt.Sprintf("ExitCode:%d", status.State.Terminated.ExitCode)
+				}
+			case status.Ready && status.State.Running != nil:
+				hasRunning = true
+				ready++
+			}
+		}
+
+		// A completed container next to running ones is not the story for
+		// the whole pod.
+		if reason == "Completed" && hasRunning {
+			reason = "NotReady"
+			for _, c := range pod.Status.Conditions {
+				if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+					reason = "Running"
+				}
+			}
+		}
+	}
+
+	if pod.DeletionTimestamp != nil {
+		if pod.Status.Reason == "NodeLost" {
+			reason = "Unknown"
+		} else {
+			reason = "Terminating"
+		}
+	}
+
+	return reason, ready, total, restarts, lastRestart
+}
+
+func compareIP(a, b string) int {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	switch {
+	case ipA == nil && ipB == nil:
+		return strings.Compare(a, b)
+	case ipA == nil:
+		return 1
+	case ipB == nil:
+		return -1
+	}
+	return bytes.Compare(ipA.To16(), ipB.To16())
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
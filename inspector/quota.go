@@ -0,0 +1,461 @@
+package inspector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// quotaBarWidth is the width of the usage bars in the quota report.
+const quotaBarWidth = 20
+
+// QuotaThresholds are the usage percentages from which the quota report
+// warns.
+type QuotaThresholds struct {
+	Warning  float64
+	Critical float64
+}
+
+// WorkloadUsage is the quota a workload's pods are charged for.
+type WorkloadUsage struct {
+	Namespace string
+	Workload  string
+	Pods      int
+	Usage     corev1.ResourceList
+	// Share is the largest fraction of a namespace quota that the workload
+	// uses, and Resource the quota resource it is measured against.
+	Share    float64
+	Resource corev1.ResourceName
+}
+
+// PodQuotaUsage returns what a pod is charged against a ResourceQuota: one
+// pod and its effective requests and limits once the LimitRange defaults
+// are applied, as admission would. Init containers count with their
+// highest value, sidecars (restartable init containers) on top of the app
+// containers.
+func PodQuotaUsage(pod *corev1.Pod, limitRanges []corev1.LimitRange) corev1.ResourceList {
+	usage := corev1.ResourceList{corev1.ResourcePods: resource.MustParse("1")}
+
+	sum := corev1.ResourceList{}
+	initMax := corev1.ResourceList{}
+	for _, c := range pod.Spec.InitContainers {
+		resources := containerQuotaUsage(c, limitRanges)
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			addResources(sum, resources)
+			continue
+		}
+		for name, q := range resources {
+			// A regular init container runs after the sidecars before it.
+			total := q.DeepCopy()
+			if running, ok := sum[name]; ok {
+				total.Add(running)
+			}
+			if current, ok := initMax[name]; !ok || total.Cmp(current) > 0 {
+				initMax[name] = total
+			}
+		}
+	}
+	for _, c := range pod.Spec.Containers {
+		addResources(sum, containerQuotaUsage(c, limitRanges))
+	}
+	for name, q := range initMax {
+		if current, ok := sum[name]; !ok || q.Cmp(current) > 0 {
+			sum[name] = q
+		}
+	}
+	for name, q := range pod.Spec.Overhead {
+		for _, prefix := range []string{"requests.", "limits."} {
+			quotaName := corev1.ResourceName(prefix + string(name))
+			if current, ok := sum[quotaName]; ok {
+				current.Add(q)
+				sum[quotaName] = current
+			}
+		}
+	}
+
+	addResources(usage, sum)
+	return usage
+}
+
+// containerQuotaUsage returns the requests.* and limits.* a container is
+// charged for after LimitRange defaulting.
+func containerQuotaUsage(c corev1.Container, limitRanges []corev1.LimitRange) corev1.ResourceList {
+	requests, limits := ContainerDefaults(c, limitRanges)
+	usage := corev1.ResourceList{}
+	for name, q := range requests {
+		usage[corev1.ResourceName("requests."+string(name))] = q
+	}
+	for name, q := range limits {
+		usage[corev1.ResourceName("limits."+string(name))] = q
+	}
+	return usage
+}
+
+// ContainerDefaults returns a container's requests and limits with the
+// defaults of the Container items of limitRanges filled in, in the order
+// the API server applies them: a missing request first defaults to the
+// container's own limit when the pod is decoded, and only then does the
+// LimitRange admission fill in its defaults.
+func ContainerDefaults(c corev1.Container, limitRanges []corev1.LimitRange) (requests, limits corev1.ResourceList) {
+	requests = c.Resources.Requests.DeepCopy()
+	limits = c.Resources.Limits.DeepCopy()
+	if requests == nil {
+		requests = corev1.ResourceList{}
+	}
+	if limits == nil {
+		limits = corev1.ResourceList{}
+	}
+	for name, q := range limits {
+		if _, ok := requests[name]; !ok {
+			requests[name] = q.DeepCopy()
+		}
+	}
+
+	for _, lr := range limitRanges {
+		for _, item := range lr.Spec.Limits {
+			if item.Type != corev1.LimitTypeContainer {
+				continue
+			}
+			for name, q := range item.Default {
+				if _, ok := limits[name]; !ok {
+					limits[name] = q.DeepCopy()
+				}
+			}
+			for name, q := range item.DefaultRequest {
+				if _, ok := requests[name]; !ok {
+					requests[name] = q.DeepCopy()
+				}
+			}
+		}
+	}
+	// A LimitRange without a defaultRequest uses its default limit as the
+	// default request.
+	for name, q := range limits {
+		if _, ok := requests[name]; !ok {
+			requests[name] = q.DeepCopy()
+		}
+	}
+	return requests, limits
+}
+
+// CheckPodQuota reports, in readable sentences, why creating pod would be
+// rejected by the quotas of its namespace: a cpu or memory request or limit
+// a quota tracks that a container leaves unset, or usage beyond the hard
+// limit. Quotas with scopes are skipped since they apply only to some pods.
+func CheckPodQuota(pod *corev1.Pod, quotas []corev1.ResourceQuota, limitRanges []corev1.LimitRange) []string {
+	var problems []string
+	usage := PodQuotaUsage(pod, limitRanges)
+
+	for _, quota := range quotas {
+		if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+			continue
+		}
+		hard := quotaHard(&quota)
+
+		for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+			for _, c := range containers {
+				requests, limits := ContainerDefaults(c, limitRanges)
+				for _, name := range sortedResourceNames(hard) {
+					kind, resourceName, ok := strings.Cut(string(name), ".")
+					if !ok {
+						// cpu and memory are aliases of the requests.
+						kind, resourceName = "requests", string(name)
+					}
+					set := requests
+					if kind == "limits" {
+						set = limits
+					} else if kind != "requests" {
+						continue
+					}
+					// Admission only requires cpu and memory to be set;
+					// other resources are charged when present.
+					if resourceName != string(corev1.ResourceCPU) && resourceName != string(corev1.ResourceMemory) {
+						continue
+					}
+					if _, ok := set[corev1.ResourceName(resourceName)]; !ok {
+						problems = append(problems, fmt.Sprintf("quota %s tracks %s, so container %s must set a %s %s (no LimitRange provides a default)",
+							quota.Name, name, c.Name, resourceName, strings.TrimSuffix(kind, "s")))
+					}
+				}
+			}
+		}
+
+		for _, name := range sortedResourceNames(hard) {
+			needed, ok := usage[quotaResourceAlias(name)]
+			if !ok || needed.IsZero() {
+				continue
+			}
+			used := quota.Status.Used[name]
+			total := used.DeepCopy()
+			total.Add(needed)
+			limit := hard[name]
+			if total.Cmp(limit) > 0 {
+				available := limit.DeepCopy()
+				available.Sub(used)
+				if available.Sign() < 0 {
+					available = resource.Quantity{}
+				}
+				problems = append(problems, fmt.Sprintf("quota %s: the pod needs %s %s but only %s of %s is left (%s used)",
+					quota.Name, needed.String(), name, available.String(), limit.String(), used.String()))
+			}
+		}
+	}
+	return problems
+}
+
+// TopQuotaConsumers groups the running pods of a namespace by the workload
+// that owns them, measures each workload against the namespace's quotas and
+// returns the n largest consumers.
+func TopQuotaConsumers(pods []corev1.Pod, quotas []corev1.ResourceQuota, limitRanges []corev1.LimitRange, n int) []WorkloadUsage {
+	byWorkload := map[string]*WorkloadUsage{}
+	for i := range pods {
+		pod := &pods[i]
+		// Finished pods are no longer charged.
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		workload := podWorkload(pod)
+		w, ok := byWorkload[pod.Namespace+"/"+workload]
+		if !ok {
+			w = &WorkloadUsage{Namespace: pod.Namespace, Workload: workload, Usage: corev1.ResourceList{}}
+			byWorkload[pod.Namespace+"/"+workload] = w
+		}
+		w.Pods++
+		addResources(w.Usage, PodQuotaUsage(pod, limitRanges))
+	}
+
+	var workloads []WorkloadUsage
+	for _, w := range byWorkload {
+		for _, quota := range quotas {
+			if quota.Namespace != w.Namespace {
+				continue
+			}
+			for name, hard := range quotaHard(&quota) {
+				used, ok := w.Usage[quotaResourceAlias(name)]
+				if !ok || hard.IsZero() {
+					continue
+				}
+				if share := used.AsApproximateFloat64() / hard.AsApproximateFloat64(); share > w.Share {
+					w.Share = share
+					w.Resource = name
+				}
+			}
+		}
+		workloads = append(workloads, *w)
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		if workloads[i].Share != workloads[j].Share {
+			return workloads[i].Share > workloads[j].Share
+		}
+		return workloads[i].Workload < workloads[j].Workload
+	})
+	if n > 0 && len(workloads) > n {
+		workloads = workloads[:n]
+	}
+	return workloads
+}
+
+// FormatQuotaReport renders, for one namespace, each quota's used and hard
+// values with a usage bar, the LimitRange defaults and the top consumers.
+// It returns the report and one warning per resource at or above a
+// threshold.
+func FormatQuotaReport(namespace string, quotas []corev1.ResourceQuota, limitRanges []corev1.LimitRange, top []WorkloadUsage, thresholds QuotaThresholds) (string, []string) {
+	var result strings.Builder
+	var warnings []string
+	fmt.Fprintf(&result, "Namespace: %s\n\n", namespace)
+
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+	if len(quotas) == 0 {
+		fmt.Fprintln(w, "Quotas:\t<none>")
+	} else {
+		fmt.Fprintln(w, "QUOTA\tRESOURCE\tUSED\tHARD\tUSAGE\t")
+		for i := range quotas {
+			quota := &quotas[i]
+			hard := quotaHard(quota)
+			for _, name := range sortedResourceNames(hard) {
+				limit := hard[name]
+				used := quota.Status.Used[name]
+				percent := -1.0
+				if !limit.IsZero() {
+					percent = used.AsApproximateFloat64() * 100 / limit.AsApproximateFloat64()
+				} else if !used.IsZero() {
+					percent = 100
+				}
+
+				flag := ""
+				switch {
+				case percent >= thresholds.Critical || (limit.IsZero() && !used.IsZero()):
+					flag = "CRITICAL"
+				case percent >= thresholds.Warning:
+					flag = "WARNING"
+				}
+				if flag != "" {
+					warnings = append(warnings, fmt.Sprintf("%s/%s %s at %s of %s (%s)",
+						namespace, quota.Name, name, formatPercent(percent), limit.String(), strings.ToLower(flag)))
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", quota.Name, name, used.String(), limit.String(), usageBar(percent), flag)
+			}
+			if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+				fmt.Fprintf(w, "%s\t(applies only to pods in scope %s)\t\t\t\t\n", quota.Name, formatQuotaScopes(quota))
+			}
+		}
+	}
+	w.Flush()
+
+	fmt.Fprintln(&result)
+	writeLimitRanges(&result, limitRanges)
+
+	if len(top) > 0 {
+		fmt.Fprintln(&result)
+		w = tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TOP CONSUMERS\tPODS\tCPU REQ\tMEM REQ\tCPU LIM\tMEM LIM\tLARGEST SHARE")
+		for _, c := range top {
+			share := "-"
+			if c.Resource != "" {
+				share = fmt.Sprintf("%s of %s", formatPercent(c.Share*100), c.Resource)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", c.Workload, c.Pods,
+				quantityOrDash(c.Usage, corev1.ResourceRequestsCPU), quantityOrDash(c.Usage, corev1.ResourceRequestsMemory),
+				quantityOrDash(c.Usage, corev1.ResourceLimitsCPU), quantityOrDash(c.Usage, corev1.ResourceLimitsMemory), share)
+		}
+		w.Flush()
+	}
+
+	return result.String(), warnings
+}
+
+// writeLimitRanges renders the defaults, minimums and maximums that the
+// LimitRanges of a namespace apply.
+func writeLimitRanges(out *strings.Builder, limitRanges []corev1.LimitRange) {
+	if len(limitRanges) == 0 {
+		fmt.Fprintln(out, "Limit ranges: <none> (containers without requests or limits get none)")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LIMIT RANGE\tTYPE\tRESOURCE\tDEFAULT REQUEST\tDEFAULT LIMIT\tMIN\tMAX\tMAX LIMIT/REQUEST")
+	for _, lr := range limitRanges {
+		for _, item := range lr.Spec.Limits {
+			names := map[corev1.ResourceName]bool{}
+			for _, list := range []corev1.ResourceList{item.DefaultRequest, item.Default, item.Min, item.Max, item.MaxLimitRequestRatio} {
+				for name := range list {
+					names[name] = true
+				}
+			}
+			sorted := make([]string, 0, len(names))
+			for name := range names {
+				sorted = append(sorted, string(name))
+			}
+			sort.Strings(sorted)
+			for _, name := range sorted {
+				r := corev1.ResourceName(name)
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", lr.Name, item.Type, name,
+					quantityOrDash(item.DefaultRequest, r), quantityOrDash(item.Default, r),
+					quantityOrDash(item.Min, r), quantityOrDash(item.Max, r), quantityOrDash(item.MaxLimitRequestRatio, r))
+			}
+		}
+	}
+	w.Flush()
+}
+
+// podWorkload names the workload that owns a pod, following a ReplicaSet
+// to its Deployment through the pod-template-hash suffix.
+func podWorkload(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod/" + pod.Name
+	}
+	if owner.Kind == "ReplicaSet" {
+		if hash := pod.Labels["pod-template-hash"]; hash != "" {
+			if deployment, ok := strings.CutSuffix(owner.Name, "-"+hash); ok {
+				return "Deployment/" + deployment
+			}
+		}
+	}
+	return owner.Kind + "/" + owner.Name
+}
+
+// quotaHard returns the enforced hard limits of a quota, falling back to the
+// spec before the quota controller has synced the status.
+func quotaHard(quota *corev1.ResourceQuota) corev1.ResourceList {
+	if len(quota.Status.Hard) > 0 {
+		return quota.Status.Hard
+	}
+	return quota.Spec.Hard
+}
+
+// quotaResourceAlias maps the short cpu and memory quota names to the
+// requests they stand for.
+func quotaResourceAlias(name corev1.ResourceName) corev1.ResourceName {
+	switch name {
+	case corev1.ResourceCPU:
+		return corev1.ResourceRequestsCPU
+	case corev1.ResourceMemory:
+		return corev1.ResourceRequestsMemory
+	case corev1.ResourceEphemeralStorage:
+		return corev1.ResourceRequestsEphemeralStorage
+	}
+	return name
+}
+
+func formatQuotaScopes(quota *corev1.ResourceQuota) string {
+	var scopes []string
+	for _, s := range quota.Spec.Scopes {
+		scopes = append(scopes, string(s))
+	}
+	if quota.Spec.ScopeSelector != nil {
+		for _, e := range quota.Spec.ScopeSelector.MatchExpressions {
+			scopes = append(scopes, fmt.Sprintf("%s %s %s", e.ScopeName, e.Operator, strings.Join(e.Values, ",")))
+		}
+	}
+	return strings.Join(scopes, ", ")
+}
+
+// usageBar draws percent as a fixed-width bar; usage over 100% fills it.
+func usageBar(percent float64) string {
+	if percent < 0 {
+		return fmt.Sprintf("[%s]  -", strings.Repeat(" ", quotaBarWidth))
+	}
+	filled := int(percent / 100 * quotaBarWidth)
+	if filled > quotaBarWidth {
+		filled = quotaBarWidth
+	}
+	return fmt.Sprintf("[%s%s] %4s", strings.Repeat("=", filled), strings.Repeat(" ", quotaBarWidth-filled), formatPercent(percent))
+}
+
+func formatPercent(percent float64) string {
+	return fmt.Sprintf("%.0f%%", percent)
+}
+
+func quantityOrDash(list corev1.ResourceList, name corev1.ResourceName) string {
+	if q, ok := list[name]; ok {
+		return q.String()
+	}
+	return "-"
+}
+
+func addResources(total, add corev1.ResourceList) {
+	for name, q := range add {
+		if current, ok := total[name]; ok {
+			current.Add(q)
+			total[name] = current
+		} else {
+			total[name] = q.DeepCopy()
+		}
+	}
+}
+
+func sortedResourceNames(list corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(list))
+	for name := range list {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,66 @@
+package inspector
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ConfigReference is a use of a ConfigMap or Secret by a pod spec, through a
+// volume, envFrom or a single environment variable.
+type ConfigReference struct {
+	Kind     string // ConfigMap or Secret
+	Name     string
+	Key      string // set for references to a single key
+	UsedBy   string // e.g. "volume config" or "container app env DB_PASSWORD"
+	Optional bool
+}
+
+// PodSpecReferences lists the ConfigMaps and Secrets that spec mounts or
+// reads environment variables from. Image pull secrets are not included.
+func PodSpecReferences(spec *corev1.PodSpec) []ConfigReference {
+	var refs []ConfigReference
+	isOptional := func(b *bool) bool { return b != nil && *b }
+
+	for _, v := range spec.Volumes {
+		usedBy := "volume " + v.Name
+		switch {
+		case v.ConfigMap != nil:
+			refs = append(refs, ConfigReference{"ConfigMap", v.ConfigMap.Name, "", usedBy, isOptional(v.ConfigMap.Optional)})
+		case v.Secret != nil:
+			refs = append(refs, ConfigReference{"Secret", v.Secret.SecretName, "", usedBy, isOptional(v.Secret.Optional)})
+		case v.Projected != nil:
+			for _, s := range v.Projected.Sources {
+				if s.ConfigMap != nil {
+					refs = append(refs, ConfigReference{"ConfigMap", s.ConfigMap.Name, "", usedBy, isOptional(s.ConfigMap.Optional)})
+				}
+				if s.Secret != nil {
+					refs = append(refs, ConfigReference{"Secret", s.Secret.Name, "", usedBy, isOptional(s.Secret.Optional)})
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		usedBy := "container " + c.Name
+		for _, from := range c.EnvFrom {
+			if from.ConfigMapRef != nil {
+				refs = append(refs, ConfigReference{"ConfigMap", from.ConfigMapRef.Name, "", usedBy, isOptional(from.ConfigMapRef.Optional)})
+			}
+			if from.SecretRef != nil {
+				refs = append(refs, ConfigReference{"Secret", from.SecretRef.Name, "", usedBy, isOptional(from.SecretRef.Optional)})
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if r := env.ValueFrom.ConfigMapKeyRef; r != nil {
+				refs = append(refs, ConfigReference{"ConfigMap", r.Name, r.Key, usedBy + " env " + env.Name, isOptional(r.Optional)})
+			}
+			if r := env.ValueFrom.SecretKeyRef; r != nil {
+				refs = append(refs, ConfigReference{"Secret", r.Name, r.Key, usedBy + " env " + env.Name, isOptional(r.Optional)})
+			}
+		}
+	}
+	return refs
+}
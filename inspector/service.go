@@ -0,0 +1,394 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/k8s-admin-cli/visualizer"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ServiceEndpoint is one address from the EndpointSlices of a service.
+type ServiceEndpoint struct {
+	Address     string
+	Pod         string
+	Node        string
+	Ready       bool
+	Serving     bool
+	Terminating bool
+	Ports       []string
+}
+
+// FormatServiceTable renders services with their TYPE, CLUSTER-IP,
+// EXTERNAL-IP, PORTS, SELECTOR and AGE, like kubectl get svc -o wide.
+func FormatServiceTable(services []corev1.Service, showNamespace bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	header := []string{"NAME", "TYPE", "CLUSTER-IP", "EXTERNAL-IP", "PORTS", "SELECTOR", "AGE"}
+	if showNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for i := range services {
+		svc := &services[i]
+		cols := []string{
+			svc.Name,
+			string(svc.Spec.Type),
+			orNone(svc.Spec.ClusterIP),
+			serviceExternalIP(svc),
+			formatServicePorts(svc.Spec.Ports),
+			formatSelector(svc.Spec.Selector),
+			FormatAge(svc.CreationTimestamp.Time),
+		}
+		if showNamespace {
+			cols = append([]string{svc.Namespace}, cols...)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// DescribeService renders a kubectl-style description of a service: its
+// addresses and ports, the endpoints behind it with their pods and
+// readiness, configuration warnings and events.
+func DescribeService(clientset *kubernetes.Clientset, namespace, name string) (string, error) {
+	svc, err := clientset.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error getting service: %v", err)
+	}
+	endpoints, err := ServiceEndpoints(clientset, svc)
+	if err != nil {
+		return "", err
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error listing pods: %v", err)
+	}
+	services, err := NodePortServices(clientset, namespace)
+	if err != nil {
+		return "", err
+	}
+	events, err := ObjectEvents(clientset, "Service", svc.ObjectMeta)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", svc.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", svc.Namespace)
+	fmt.Fprintf(w, "CreationTimestamp:\t%s\n", svc.CreationTimestamp.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(w, "Labels:\t%s\n", formatMap(svc.Labels))
+	fmt.Fprintf(w, "Annotations:\t%s\n", formatMap(svc.Annotations))
+	fmt.Fprintf(w, "Selector:\t%s\n", formatSelector(svc.Spec.Selector))
+	fmt.Fprintf(w, "Type:\t%s\n", svc.Spec.Type)
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		fmt.Fprintf(w, "External Name:\t%s\n", svc.Spec.ExternalName)
+	}
+	fmt.Fprintf(w, "Cluster IPs:\t%s\n", orNone(strings.Join(svc.Spec.ClusterIPs, ", ")))
+	if len(svc.Spec.ExternalIPs) > 0 {
+		fmt.Fprintf(w, "External IPs:\t%s\n", strings.Join(svc.Spec.ExternalIPs, ", "))
+	}
+	if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		fmt.Fprintf(w, "LoadBalancer Ingress:\t%s\n", serviceExternalIP(svc))
+	}
+	fmt.Fprintf(w, "Session Affinity:\t%s\n", svc.Spec.SessionAffinity)
+	if svc.Spec.ExternalTrafficPolicy != "" {
+		fmt.Fprintf(w, "External Traffic Policy:\t%s\n", svc.Spec.ExternalTrafficPolicy)
+	}
+
+	if len(svc.Spec.Ports) == 0 {
+		fmt.Fprintf(w, "Ports:\t<none>\n")
+	} else {
+		fmt.Fprintf(w, "Ports:\n")
+		for _, p := range svc.Spec.Ports {
+			line := fmt.Sprintf("%d/%s -> target %s", p.Port, p.Protocol, serviceTargetPort(p))
+			if p.NodePort != 0 {
+				line += fmt.Sprintf(", node port %d", p.NodePort)
+			}
+			fmt.Fprintf(w, "  %s:\t%s\n", orNone(p.Name), line)
+		}
+	}
+
+	if len(endpoints) == 0 {
+		fmt.Fprintf(w, "Endpoints:\t<none>\n")
+	} else {
+		ready := 0
+		for _, e := range endpoints {
+			if e.Ready {
+				ready++
+			}
+		}
+		fmt.Fprintf(w, "Endpoints:\t%d ready, %d not ready\n", ready, len(endpoints)-ready)
+		fmt.Fprintf(w, "  ADDRESS\tPOD\tNODE\tREADY\tPORTS\n")
+		for _, e := range endpoints {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", e.Address, orNone(e.Pod), orNone(e.Node), endpointReadiness(e), orNone(strings.Join(e.Ports, ",")))
+		}
+	}
+
+	warnings := ServiceWarnings(svc, pods.Items, services)
+	if len(warnings) == 0 {
+		fmt.Fprintf(w, "Warnings:\t<none>\n")
+	} else {
+		fmt.Fprintf(w, "Warnings:\n")
+		for _, warning := range warnings {
+			fmt.Fprintf(w, "  - %s\n", warning)
+		}
+	}
+
+	writeEvents(w, events)
+	w.Flush()
+
+	return result.String(), nil
+}
+
+// ServiceEndpoints returns the addresses of the EndpointSlices that belong to
+// svc, sorted by pod and address.
+func ServiceEndpoints(clientset *kubernetes.Clientset, svc *corev1.Service) ([]ServiceEndpoint, error) {
+	slices, err := clientset.DiscoveryV1().EndpointSlices(svc.Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: svc.Name}).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing endpoint slices: %v", err)
+	}
+
+	var endpoints []ServiceEndpoint
+	for _, slice := range slices.Items {
+		var ports []string
+		for _, p := range slice.Ports {
+			port := ""
+			if p.Port != nil {
+				port = fmt.Sprintf("%d", *p.Port)
+			}
+			if p.Protocol != nil {
+				port += "/" + string(*p.Protocol)
+			}
+			if p.Name != nil && *p.Name != "" {
+				port = *p.Name + ":" + port
+			}
+			ports = append(ports, port)
+		}
+
+		for _, e := range slice.Endpoints {
+			endpoint := ServiceEndpoint{
+				// A nil ready condition means ready, as for kube-proxy.
+				Ready:       e.Conditions.Ready == nil || *e.Conditions.Ready,
+				Serving:     e.Conditions.Serving == nil || *e.Conditions.Serving,
+				Terminating: e.Conditions.Terminating != nil && *e.Conditions.Terminating,
+				Ports:       ports,
+			}
+			if e.TargetRef != nil && e.TargetRef.Kind == "Pod" {
+				endpoint.Pod = e.TargetRef.Name
+			}
+			if e.NodeName != nil {
+				endpoint.Node = *e.NodeName
+			}
+			for _, address := range e.Addresses {
+				endpoint.Address = address
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if endpoints[i].Pod != endpoints[j].Pod {
+			return endpoints[i].Pod < endpoints[j].Pod
+		}
+		return endpoints[i].Address < endpoints[j].Address
+	})
+	return endpoints, nil
+}
+
+// NodePortServices lists the services of every namespace, which node port
+// collisions are checked against. Without permission to list services
+// cluster-wide it falls back to the services of namespace.
+func NodePortServices(clientset *kubernetes.Clientset, namespace string) ([]corev1.Service, error) {
+	services, err := clientset.CoreV1().Services(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		services, err = clientset.CoreV1().Services(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error listing services: %v", err)
+		}
+	}
+	return services.Items, nil
+}
+
+// SelectedPods returns the pods that svc sends traffic to. Services without
+// a selector have their endpoints managed by hand and select no pods.
+func SelectedPods(svc *corev1.Service, pods []corev1.Pod) []corev1.Pod {
+	if len(svc.Spec.Selector) == 0 {
+		return nil
+	}
+	var selected []corev1.Pod
+	for _, pod := range pods {
+		if pod.Namespace == svc.Namespace && visualizer.LabelsMatch(pod.Labels, svc.Spec.Selector) {
+			selected = append(selected, pod)
+		}
+	}
+	return selected
+}
+
+// ServiceWarnings checks a service for a selector that matches none of pods,
+// named targetPorts that the selected pods do not declare, and node ports
+// that another of services also uses.
+func ServiceWarnings(svc *corev1.Service, pods []corev1.Pod, services []corev1.Service) []string {
+	var warnings []string
+
+	if svc.Spec.Type != corev1.ServiceTypeExternalName && len(svc.Spec.Selector) > 0 {
+		selected := SelectedPods(svc, pods)
+		if len(selected) == 0 {
+			warnings = append(warnings, fmt.Sprintf("selector %s matches no pods", formatSelector(svc.Spec.Selector)))
+		}
+
+		for _, p := range svc.Spec.Ports {
+			if p.TargetPort.Type != intstr.String || len(selected) == 0 {
+				continue
+			}
+			var missing []string
+			for i := range selected {
+				if !declaresPort(&selected[i], p.TargetPort.StrVal, p.Protocol) {
+					missing = append(missing, selected[i].Name)
+				}
+			}
+			if len(missing) > 0 {
+				warnings = append(warnings, fmt.Sprintf("targetPort %q of port %s is not a named %s container port in %d of %d selected pods (%s)",
+					p.TargetPort.StrVal, servicePortName(p), p.Protocol, len(missing), len(selected), abbreviate(missing, 3)))
+			}
+		}
+	}
+
+	for _, p := range svc.Spec.Ports {
+		if p.NodePort == 0 {
+			continue
+		}
+		for _, other := range services {
+			if other.Namespace == svc.Namespace && other.Name == svc.Name {
+				continue
+			}
+			for _, op := range other.Spec.Ports {
+				if op.NodePort == p.NodePort && op.Protocol == p.Protocol {
+					warnings = append(warnings, fmt.Sprintf("node port %d/%s of port %s is also used by service %s/%s",
+						p.NodePort, p.Protocol, servicePortName(p), other.Namespace, other.Name))
+				}
+			}
+		}
+	}
+
+	return warnings
+}
+
+// declaresPort reports whether a container of pod has a port with the given
+// name and protocol. Sidecar init containers can serve traffic too.
+func declaresPort(pod *corev1.Pod, name string, protocol corev1.Protocol) bool {
+	for _, containers := range [][]corev1.Container{pod.Spec.Containers, pod.Spec.InitContainers} {
+		for _, c := range containers {
+			for _, port := range c.Ports {
+				portProtocol := port.Protocol
+				if portProtocol == "" {
+					portProtocol = corev1.ProtocolTCP
+				}
+				if port.Name == name && portProtocol == protocol {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// serviceExternalIP renders the EXTERNAL-IP column the way kubectl does.
+func serviceExternalIP(svc *corev1.Service) string {
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeExternalName:
+		return svc.Spec.ExternalName
+	case corev1.ServiceTypeLoadBalancer:
+		addresses := append([]string{}, svc.Spec.ExternalIPs...)
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				addresses = append(addresses, ingress.IP)
+			} else if ingress.Hostname != "" {
+				addresses = append(addresses, ingress.Hostname)
+			}
+		}
+		if len(addresses) == 0 {
+			return "<pending>"
+		}
+		return strings.Join(addresses, ",")
+	}
+	return orNone(strings.Join(svc.Spec.ExternalIPs, ","))
+}
+
+func formatServicePorts(ports []corev1.ServicePort) string {
+	if len(ports) == 0 {
+		return "<none>"
+	}
+	formatted := make([]string, 0, len(ports))
+	for _, p := range ports {
+		port := fmt.Sprintf("%d", p.Port)
+		if p.NodePort != 0 {
+			port += fmt.Sprintf(":%d", p.NodePort)
+		}
+		formatted = append(formatted, port+"/"+string(p.Protocol))
+	}
+	return strings.Join(formatted, ",")
+}
+
+// serviceTargetPort renders a targetPort, which defaults to the port itself.
+func serviceTargetPort(p corev1.ServicePort) string {
+	if p.TargetPort.Type == intstr.String {
+		return p.TargetPort.StrVal
+	}
+	if p.TargetPort.IntVal == 0 {
+		return fmt.Sprintf("%d", p.Port)
+	}
+	return fmt.Sprintf("%d", p.TargetPort.IntVal)
+}
+
+func servicePortName(p corev1.ServicePort) string {
+	if p.Name != "" {
+		return fmt.Sprintf("%s (%d)", p.Name, p.Port)
+	}
+	return fmt.Sprintf("%d", p.Port)
+}
+
+func formatSelector(selector map[string]string) string {
+	if len(selector) == 0 {
+		return "<none>"
+	}
+	return labels.SelectorFromSet(selector).String()
+}
+
+func endpointReadiness(e ServiceEndpoint) string {
+	switch {
+	case e.Terminating && e.Serving:
+		return "terminating (serving)"
+	case e.Terminating:
+		return "terminating"
+	case e.Ready:
+		return "ready"
+	default:
+		return "not ready"
+	}
+}
+
+// abbreviate joins the first n values and counts the rest.
+func abbreviate(values []string, n int) string {
+	if len(values) <= n {
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(values[:n], ", "), len(values)-n)
+}
@@ -0,0 +1,250 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// FormatStatefulSetTable renders stateful sets with their READY, UPDATED,
+// PARTITION, AGE and IMAGES columns.
+func FormatStatefulSetTable(sets []appsv1.StatefulSet, showNamespace bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	header := []string{"NAME", "READY", "UPDATED", "PARTITION", "AGE", "IMAGES"}
+	if showNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, s := range sets {
+		partition := "-"
+		if p := statefulSetPartition(&s); p > 0 {
+			partition = fmt.Sprintf("%d", p)
+		}
+		cols := []string{
+			s.Name,
+			fmt.Sprintf("%d/%d", s.Status.ReadyReplicas, statefulSetReplicas(&s)),
+			fmt.Sprintf("%d", s.Status.UpdatedReplicas),
+			partition,
+			FormatAge(s.CreationTimestamp.Time),
+			strings.Join(templateImages(s.Spec.Template), ","),
+		}
+		if showNamespace {
+			cols = append([]string{s.Namespace}, cols...)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// DescribeStatefulSet renders a kubectl-style description of a stateful set:
+// its replicas, update strategy and revisions, pod template, volume claim
+// templates and events.
+func DescribeStatefulSet(clientset *kubernetes.Clientset, namespace, name string) (string, error) {
+	s, err := clientset.AppsV1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error getting stateful set: %v", err)
+	}
+	events, err := ObjectEvents(clientset, "StatefulSet", s.ObjectMeta)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", s.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", s.Namespace)
+	fmt.Fprintf(w, "CreationTimestamp:\t%s\n", s.CreationTimestamp.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(w, "Labels:\t%s\n", formatMap(s.Labels))
+	fmt.Fprintf(w, "Annotations:\t%s\n", formatMap(s.Annotations))
+	fmt.Fprintf(w, "Selector:\t%s\n", metav1.FormatLabelSelector(s.Spec.Selector))
+	fmt.Fprintf(w, "Service Name:\t%s\n", orNone(s.Spec.ServiceName))
+	fmt.Fprintf(w, "Replicas:\t%d desired | %d total | %d ready | %d updated\n",
+		statefulSetReplicas(s), s.Status.Replicas, s.Status.ReadyReplicas, s.Status.UpdatedReplicas)
+	fmt.Fprintf(w, "Pod Management Policy:\t%s\n", s.Spec.PodManagementPolicy)
+	fmt.Fprintf(w, "Update Strategy:\t%s\n", s.Spec.UpdateStrategy.Type)
+	if s.Spec.UpdateStrategy.Type == appsv1.RollingUpdateStatefulSetStrategyType {
+		fmt.Fprintf(w, "  Partition:\t%d\n", statefulSetPartition(s))
+	}
+	fmt.Fprintf(w, "Current Revision:\t%s\n", orNone(s.Status.CurrentRevision))
+	fmt.Fprintf(w, "Update Revision:\t%s\n", orNone(s.Status.UpdateRevision))
+
+	writePodTemplate(w, s.Spec.Template)
+
+	if len(s.Spec.VolumeClaimTemplates) == 0 {
+		fmt.Fprintf(w, "Volume Claims:\t<none>\n")
+	} else {
+		fmt.Fprintf(w, "Volume Claims:\n")
+		for _, pvc := range s.Spec.VolumeClaimTemplates {
+			storageClass := "<default>"
+			if pvc.Spec.StorageClassName != nil {
+				storageClass = *pvc.Spec.StorageClassName
+			}
+			size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+			fmt.Fprintf(w, "  %s:\t%s, storage class %s, access modes %s\n",
+				pvc.Name, size.String(), storageClass, formatAccessModes(pvc.Spec.AccessModes))
+		}
+	}
+
+	writeEvents(w, events)
+	w.Flush()
+
+	return result.String(), nil
+}
+
+// StatefulSetPods returns the pods of s indexed by ordinal. Ordinals without
+// a pod are nil.
+func StatefulSetPods(clientset *kubernetes.Clientset, s *appsv1.StatefulSet) ([]*corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(s.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on stateful set %s: %v", s.Name, err)
+	}
+	list, err := clientset.CoreV1().Pods(s.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods: %v", err)
+	}
+
+	count := int(statefulSetReplicas(s))
+	if s.Spec.Ordinals != nil {
+		count += int(s.Spec.Ordinals.Start)
+	}
+	pods := make([]*corev1.Pod, count)
+	for i := range list.Items {
+		pod := &list.Items[i]
+		if owner := metav1.GetControllerOf(pod); owner == nil || owner.UID != s.UID {
+			continue
+		}
+		ordinal, ok := podOrdinal(s.Name, pod.Name)
+		if !ok {
+			continue
+		}
+		// Pods above the desired replicas are still shutting down after a
+		// scale-in; keep them visible.
+		for ordinal >= len(pods) {
+			pods = append(pods, nil)
+		}
+		pods[ordinal] = pod
+	}
+	return pods, nil
+}
+
+// FormatStatefulSetPods renders one row per ordinal with the pod's status and
+// revision. Pods below the partition are held back during a rolling update.
+func FormatStatefulSetPods(s *appsv1.StatefulSet, pods []*corev1.Pod) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ORDINAL\tPOD\tREADY\tSTATUS\tRESTARTS\tREVISION\tNODE\tAGE")
+
+	start := 0
+	if s.Spec.Ordinals != nil {
+		start = int(s.Spec.Ordinals.Start)
+	}
+	partition := int(statefulSetPartition(s))
+	for ordinal := start; ordinal < len(pods); ordinal++ {
+		pod := pods[ordinal]
+		if pod == nil {
+			fmt.Fprintf(w, "%d\t%s-%d\t-\t<missing>\t-\t-\t-\t-\n", ordinal, s.Name, ordinal)
+			continue
+		}
+
+		row := NewPodRow(pod)
+		revision := pod.Labels[appsv1.ControllerRevisionHashLabelKey]
+		switch {
+		case revision == "":
+			revision = "<unknown>"
+		case revision == s.Status.UpdateRevision:
+			revision += " (updated)"
+		case ordinal < partition:
+			revision += " (held by partition)"
+		default:
+			revision += " (pending update)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			ordinal, pod.Name, row.Ready, row.Status, row.Restarts, revision, row.Node, row.Age)
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// StatefulSetRolloutStatus reports the progress of a stateful set's rolling
+// update the way kubectl rollout status does, honouring the partition.
+func StatefulSetRolloutStatus(s *appsv1.StatefulSet) (message string, done bool, err error) {
+	if s.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return "", false, fmt.Errorf("rollout status is only available for the %s update strategy", appsv1.RollingUpdateStatefulSetStrategyType)
+	}
+	if s.Status.ObservedGeneration == 0 || s.Generation > s.Status.ObservedGeneration {
+		return "Waiting for statefulset spec update to be observed...", false, nil
+	}
+
+	replicas := statefulSetReplicas(s)
+	if s.Status.ReadyReplicas < replicas {
+		return fmt.Sprintf("Waiting for %d pods to be ready...", replicas-s.Status.ReadyReplicas), false, nil
+	}
+	if partition := statefulSetPartition(s); partition > 0 {
+		if s.Status.UpdatedReplicas < replicas-partition {
+			return fmt.Sprintf("Waiting for partitioned roll out to finish: %d out of %d new pods have been updated...",
+				s.Status.UpdatedReplicas, replicas-partition), false, nil
+		}
+		return fmt.Sprintf("partitioned roll out complete: %d new pods have been updated (ordinals below %d are held back)",
+			s.Status.UpdatedReplicas, partition), true, nil
+	}
+	if s.Status.UpdateRevision != s.Status.CurrentRevision {
+		return fmt.Sprintf("Waiting for statefulset rolling update to complete %d pods at revision %s...",
+			s.Status.UpdatedReplicas, s.Status.UpdateRevision), false, nil
+	}
+	return fmt.Sprintf("statefulset rolling update complete %d pods at revision %s", s.Status.CurrentReplicas, s.Status.CurrentRevision), true, nil
+}
+
+// podOrdinal parses the ordinal from a stateful set pod name (<set>-<n>).
+func podOrdinal(setName, podName string) (int, bool) {
+	suffix, ok := strings.CutPrefix(podName, setName+"-")
+	if !ok {
+		return 0, false
+	}
+	ordinal, err := strconv.Atoi(suffix)
+	if err != nil || ordinal < 0 {
+		return 0, false
+	}
+	return ordinal, true
+}
+
+func statefulSetReplicas(s *appsv1.StatefulSet) int32 {
+	if s.Spec.Replicas == nil {
+		return 1
+	}
+	return *s.Spec.Replicas
+}
+
+func statefulSetPartition(s *appsv1.StatefulSet) int32 {
+	if ru := s.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil {
+		return *ru.Partition
+	}
+	return 0
+}
+
+func formatAccessModes(modes []corev1.PersistentVolumeAccessMode) string {
+	if len(modes) == 0 {
+		return "<none>"
+	}
+	names := make([]string, 0, len(modes))
+	for _, m := range modes {
+		names = append(names, string(m))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
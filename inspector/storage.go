@@ -0,0 +1,319 @@
+package inspector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/k8s-admin-cli/visualizer"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ClaimStorage is a PersistentVolumeClaim with the volume bound to it, the
+// pods that mount it and, when the kubelet reports it, its usage.
+type ClaimStorage struct {
+	Claim  corev1.PersistentVolumeClaim
+	Volume *corev1.PersistentVolume
+	Pods   []string
+	Stats  *VolumeStats
+}
+
+// VolumeStats is the filesystem usage of a mounted claim as reported by the
+// kubelet.
+type VolumeStats struct {
+	UsedBytes     int64
+	CapacityBytes int64
+}
+
+// kubeletSummary is the part of the kubelet's /stats/summary response that
+// reports the usage of claims.
+type kubeletSummary struct {
+	Pods []struct {
+		Volumes []struct {
+			UsedBytes     *int64 `json:"usedBytes"`
+			CapacityBytes *int64 `json:"capacityBytes"`
+			PVCRef        *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// ClaimStorageReport joins claims with their volumes and the pods that
+// mount them. stats is keyed by namespace/claim and may be nil. Finished
+// pods do not count as consumers.
+func ClaimStorageReport(claims []corev1.PersistentVolumeClaim, volumes []corev1.PersistentVolume, pods []corev1.Pod, stats map[string]VolumeStats) []ClaimStorage {
+	volumesByName := map[string]*corev1.PersistentVolume{}
+	for i := range volumes {
+		volumesByName[volumes[i].Name] = &volumes[i]
+	}
+	podsByClaim := map[string][]string{}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, claim := range visualizer.PodClaimNames(pod) {
+			key := pod.Namespace + "/" + claim
+			podsByClaim[key] = append(podsByClaim[key], pod.Name)
+		}
+	}
+
+	report := make([]ClaimStorage, 0, len(claims))
+	for _, claim := range claims {
+		key := claim.Namespace + "/" + claim.Name
+		entry := ClaimStorage{Claim: claim, Pods: podsByClaim[key]}
+		if claim.Spec.VolumeName != "" {
+			entry.Volume = volumesByName[claim.Spec.VolumeName]
+		}
+		if s, ok := stats[key]; ok {
+			entry.Stats = &s
+		}
+		sort.Strings(entry.Pods)
+		report = append(report, entry)
+	}
+	return report
+}
+
+// KubeletVolumeStats reads the usage of mounted claims from the stats
+// summary of the kubelets of the given nodes, through the API server's node
+// proxy. Nodes whose kubelet cannot be reached are skipped and returned so
+// the caller can mention them.
+func KubeletVolumeStats(clientset *kubernetes.Clientset, nodes []string) (map[string]VolumeStats, []string) {
+	stats := map[string]VolumeStats{}
+	var unreachable []string
+	for _, node := range nodes {
+		raw, err := clientset.CoreV1().RESTClient().Get().
+			Resource("nodes").Name(node).SubResource("proxy").Suffix("stats/summary").
+			DoRaw(context.TODO())
+		if err != nil {
+			unreachable = append(unreachable, node)
+			continue
+		}
+		var summary kubeletSummary
+		if err := json.Unmarshal(raw, &summary); err != nil {
+			unreachable = append(unreachable, node)
+			continue
+		}
+		for _, pod := range summary.Pods {
+			for _, volume := range pod.Volumes {
+				if volume.PVCRef == nil || volume.UsedBytes == nil || volume.CapacityBytes == nil {
+					continue
+				}
+				stats[volume.PVCRef.Namespace+"/"+volume.PVCRef.Name] = VolumeStats{
+					UsedBytes:     *volume.UsedBytes,
+					CapacityBytes: *volume.CapacityBytes,
+				}
+			}
+		}
+	}
+	return stats, unreachable
+}
+
+// FormatClaimTable renders claims with their status, volume and its reclaim
+// policy, capacity, access modes, storage class, usage and the pods that
+// mount them.
+func FormatClaimTable(report []ClaimStorage, showNamespace bool) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+
+	header := []string{"NAME", "STATUS", "VOLUME", "CAPACITY", "ACCESS MODES", "STORAGECLASS", "USED", "PODS", "AGE"}
+	if showNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, c := range report {
+		capacity := "-"
+		if q, ok := c.Claim.Status.Capacity[corev1.ResourceStorage]; ok {
+			capacity = q.String()
+		} else if q, ok := c.Claim.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+			capacity = q.String() + " (requested)"
+		}
+		volume := c.Claim.Spec.VolumeName
+		if volume == "" {
+			volume = "<none>"
+		} else if c.Volume != nil {
+			// The reclaim policy tells whether deleting the claim deletes the disk.
+			volume = fmt.Sprintf("%s (%s)", volume, c.Volume.Spec.PersistentVolumeReclaimPolicy)
+		}
+		used := "-"
+		if c.Stats != nil && c.Stats.CapacityBytes > 0 {
+			used = fmt.Sprintf("%s (%.0f%%)", FormatBytes(c.Stats.UsedBytes), float64(c.Stats.UsedBytes)*100/float64(c.Stats.CapacityBytes))
+		}
+		pods := "<none>"
+		if len(c.Pods) > 0 {
+			pods = abbreviate(c.Pods, 2)
+		}
+
+		cols := []string{
+			c.Claim.Name,
+			string(c.Claim.Status.Phase),
+			volume,
+			capacity,
+			formatAccessModes(c.Claim.Spec.AccessModes),
+			claimStorageClass(&c.Claim),
+			used,
+			pods,
+			FormatAge(c.Claim.CreationTimestamp.Time),
+		}
+		if showNamespace {
+			cols = append([]string{c.Claim.Namespace}, cols...)
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// FormatVolumeTable renders persistent volumes with their capacity, access
+// modes, reclaim policy, status and claim.
+func FormatVolumeTable(volumes []corev1.PersistentVolume) string {
+	var result strings.Builder
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCAPACITY\tACCESS MODES\tRECLAIM POLICY\tSTATUS\tCLAIM\tSTORAGECLASS\tAGE")
+
+	for _, pv := range volumes {
+		capacity := "-"
+		if q, ok := pv.Spec.Capacity[corev1.ResourceStorage]; ok {
+			capacity = q.String()
+		}
+		claim := "<none>"
+		if pv.Spec.ClaimRef != nil {
+			claim = pv.Spec.ClaimRef.Namespace + "/" + pv.Spec.ClaimRef.Name
+		}
+		class := pv.Spec.StorageClassName
+		if class == "" {
+			class = "<none>"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", pv.Name, capacity, formatAccessModes(pv.Spec.AccessModes),
+			pv.Spec.PersistentVolumeReclaimPolicy, pv.Status.Phase, claim, class, FormatAge(pv.CreationTimestamp.Time))
+	}
+
+	w.Flush()
+	return result.String()
+}
+
+// ClaimProblems explains claims that are not bound, bound claims that no
+// pod mounts and claims whose usage is at or above usageWarning percent.
+// classes is nil when the storage classes could not be read, in which case
+// Pending claims are not explained.
+func ClaimProblems(report []ClaimStorage, classes *storagev1.StorageClassList, usageWarning float64) []string {
+	var classesByName map[string]*storagev1.StorageClass
+	if classes != nil {
+		classesByName = map[string]*storagev1.StorageClass{}
+		for i := range classes.Items {
+			classesByName[classes.Items[i].Name] = &classes.Items[i]
+		}
+	}
+
+	var problems []string
+	for _, c := range report {
+		name := c.Claim.Namespace + "/" + c.Claim.Name
+		switch c.Claim.Status.Phase {
+		case corev1.ClaimPending:
+			problems = append(problems, fmt.Sprintf("claim %s is Pending: %s", name, pendingReason(c, classesByName)))
+		case corev1.ClaimLost:
+			problems = append(problems, fmt.Sprintf("claim %s is Lost: its volume %s no longer exists", name, c.Claim.Spec.VolumeName))
+		case corev1.ClaimBound:
+			if len(c.Pods) == 0 {
+				problems = append(problems, fmt.Sprintf("claim %s is not mounted by any pod", name))
+			}
+		}
+		if c.Stats != nil && c.Stats.CapacityBytes > 0 {
+			if percent := float64(c.Stats.UsedBytes) * 100 / float64(c.Stats.CapacityBytes); percent >= usageWarning {
+				problems = append(problems, fmt.Sprintf("claim %s is %.0f%% full (%s of %s)",
+					name, percent, FormatBytes(c.Stats.UsedBytes), FormatBytes(c.Stats.CapacityBytes)))
+			}
+		}
+	}
+	return problems
+}
+
+// VolumeProblems explains volumes that keep a disk without serving a claim:
+// Released volumes, which hold the data of a deleted claim and cannot be
+// bound again until their claim reference is cleared, and Failed volumes.
+func VolumeProblems(volumes []corev1.PersistentVolume) []string {
+	var problems []string
+	for _, pv := range volumes {
+		capacity := ""
+		if q, ok := pv.Spec.Capacity[corev1.ResourceStorage]; ok {
+			capacity = " (" + q.String() + ")"
+		}
+		switch pv.Status.Phase {
+		case corev1.VolumeReleased:
+			claim := "a deleted claim"
+			if pv.Spec.ClaimRef != nil {
+				claim = "deleted claim " + pv.Spec.ClaimRef.Namespace + "/" + pv.Spec.ClaimRef.Name
+			}
+			problems = append(problems, fmt.Sprintf("volume %s%s is Released with reclaim policy %s and still holds the data of %s; delete it or clear its claimRef to reuse the disk",
+				pv.Name, capacity, pv.Spec.PersistentVolumeReclaimPolicy, claim))
+		case corev1.VolumeFailed:
+			message := pv.Status.Message
+			if message == "" {
+				message = "automatic reclamation failed"
+			}
+			problems = append(problems, fmt.Sprintf("volume %s%s is Failed: %s", pv.Name, capacity, message))
+		}
+	}
+	return problems
+}
+
+// pendingReason guesses why a claim has not been bound from its storage
+// class and whether a pod uses it.
+func pendingReason(c ClaimStorage, classes map[string]*storagev1.StorageClass) string {
+	if classDisabled(&c.Claim) {
+		return `its storage class is "", which turns off dynamic provisioning, so only a pre-created volume with a matching size and access mode can bind it`
+	}
+	if classes == nil {
+		return "check the claim's events (storage classes could not be read)"
+	}
+	className := claimStorageClass(&c.Claim)
+	if className == "<none>" {
+		for _, class := range classes {
+			if class.Annotations["storageclass.kubernetes.io/is-default-class"] == "true" {
+				return fmt.Sprintf("it has no storage class yet; the default class %s should be assigned", class.Name)
+			}
+		}
+		return "it has no storage class and there is no default class, so only a pre-created volume with a matching size and access mode can bind it"
+	}
+
+	class, ok := classes[className]
+	if !ok {
+		return fmt.Sprintf("storage class %s does not exist", className)
+	}
+	if class.VolumeBindingMode != nil && *class.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+		if len(c.Pods) == 0 {
+			return fmt.Sprintf("storage class %s waits for a pod to use the claim before provisioning (WaitForFirstConsumer)", className)
+		}
+		return fmt.Sprintf("waiting for the pods %s to be scheduled so that storage class %s can provision a volume", abbreviate(c.Pods, 2), className)
+	}
+	return fmt.Sprintf("provisioner %s has not provisioned a volume; check the claim's events", class.Provisioner)
+}
+
+func claimStorageClass(claim *corev1.PersistentVolumeClaim) string {
+	if claim.Spec.StorageClassName != nil && *claim.Spec.StorageClassName != "" {
+		return *claim.Spec.StorageClassName
+	}
+	if class := claim.Annotations[corev1.BetaStorageClassAnnotation]; class != "" {
+		return class
+	}
+	return "<none>"
+}
+
+// classDisabled reports whether a claim asks for no storage class with an
+// explicit empty class name, rather than leaving the class unset for the
+// default class to be assigned.
+func classDisabled(claim *corev1.PersistentVolumeClaim) bool {
+	if class, ok := claim.Annotations[corev1.BetaStorageClassAnnotation]; ok {
+		return class == ""
+	}
+	return claim.Spec.StorageClassName != nil && *claim.Spec.StorageClassName == ""
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func newJobCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "job",
+		Aliases: []string{"jobs"},
+		Short:   "Manage jobs",
+		Long:    `List jobs, follow their logs and clean up failed jobs.`,
+	}
+
+	cmd.AddCommand(newJobListCmd())
+	cmd.AddCommand(newJobLogsCmd())
+	cmd.AddCommand(newJobCleanupCmd())
+
+	return cmd
+}
+
+func newJobListCmd() *cobra.Command {
+	var (
+		selector string
+		watching bool
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List jobs",
+		Long: `List jobs with their status, completions, how long they ran and the cron job
+that created them.`,
+		Example: `  k8s-admin job list
+  k8s-admin job list -l app=backup -w`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			if watching {
+				jobs := clientset.BatchV1().Jobs(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						opts.LabelSelector = selector
+						return jobs.List(ctx, opts)
+					},
+					watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+						opts.LabelSelector = selector
+						return jobs.Watch(ctx, opts)
+					},
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]batchv1.Job, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*batchv1.Job))
+						}
+						_, err := io.WriteString(w, inspector.FormatJobTable(items, false))
+						return err
+					},
+				}, output)
+			}
+
+			jobs, err := clientset.BatchV1().Jobs(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				return printObject(jobs, output)
+			}
+			if len(jobs.Items) == 0 {
+				fmt.Printf("No jobs found in namespace %s\n", namespace)
+				return nil
+			}
+
+			fmt.Print(inspector.FormatJobTable(jobs.Items, false))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector to filter jobs (e.g. app=backup)")
+	addWatchFlags(cmd, &watching, &output)
+	return cmd
+}
+
+func newJobLogsCmd() *cobra.Command {
+	var (
+		since string
+		grep  string
+		opts  logOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "logs <name>",
+		Short: "Print or follow the logs of a job's pods",
+		Long: `Print the logs of every pod a job has created, each line prefixed with its
+pod and container. With --follow, pods the job creates later (for example
+retries after a failure) are picked up as they start.`,
+		Example: `  k8s-admin job logs backup-28401120
+  k8s-admin job logs backup-28401120 -f --all-containers`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.allContainers && opts.container != "" {
+				return fmt.Errorf("--container and --all-containers are mutually exclusive")
+			}
+			if since != "" {
+				d, err := time.ParseDuration(since)
+				if err != nil {
+					return fmt.Errorf("invalid --since duration: %v", err)
+				}
+				opts.since = d
+			}
+			if grep != "" {
+				re, err := regexp.Compile(grep)
+				if err != nil {
+					return fmt.Errorf("invalid --grep pattern: %v", err)
+				}
+				opts.grep = re
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			job, err := clientset.BatchV1().Jobs(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+			if err != nil {
+				return fmt.Errorf("invalid selector on job %s: %v", job.Name, err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			streamer := &logStreamer{
+				clientset: clientset,
+				namespace: namespace,
+				opts:      opts,
+				out:       os.Stdout,
+				active:    make(map[string]bool),
+			}
+			return streamer.runSelector(ctx, selector.String())
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.container, "container", "c", "", "container to print logs from")
+	cmd.Flags().BoolVar(&opts.allContainers, "all-containers", false, "print logs from all containers, including init containers")
+	cmd.Flags().BoolVarP(&opts.follow, "follow", "f", false, "stream new log lines as they are written")
+	cmd.Flags().StringVar(&since, "since", "", "only return logs newer than a relative duration (e.g. 5m, 1h)")
+	cmd.Flags().Int64Var(&opts.tail, "tail", -1, "number of recent lines to show (-1 shows all)")
+	cmd.Flags().BoolVar(&opts.timestamps, "timestamps", false, "include timestamps on each line")
+	cmd.Flags().StringVar(&grep, "grep", "", "only print lines matching this regular expression")
+
+	return cmd
+}
+
+func newJobCleanupCmd() *cobra.Command {
+	var (
+		olderThan time.Duration
+		selector  string
+		dryRun    bool
+		yes       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Delete failed jobs older than a given age",
+		Long: `Delete jobs that failed longer ago than --older-than, together with their
+pods. Jobs that completed successfully or are still running are never
+touched. Use --dry-run to only list the jobs that would be deleted.`,
+		Example: `  k8s-admin job cleanup --older-than 24h
+  k8s-admin job cleanup --older-than 168h -l app=backup --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if olderThan < 0 {
+				return fmt.Errorf("--older-than must not be negative")
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			jobs, err := clientset.BatchV1().Jobs(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+
+			var stale []batchv1.Job
+			for _, job := range jobs.Items {
+				if !inspector.JobFailed(&job) {
+					continue
+				}
+				if failedAt, ok := inspector.JobFinished(&job); ok && time.Since(failedAt) >= olderThan {
+					stale = append(stale, job)
+				}
+			}
+			if len(stale) == 0 {
+				fmt.Printf("No failed jobs older than %s in namespace %s\n", olderThan, namespace)
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tSTATUS\tFAILED\tCRONJOB")
+			for i := range stale {
+				job := &stale[i]
+				failedAt, _ := inspector.JobFinished(job)
+				cronJob := "<none>"
+				if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == "CronJob" {
+					cronJob = owner.Name
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s ago\t%s\n", job.Name, inspector.JobStatus(job), inspector.FormatAge(failedAt), cronJob)
+			}
+			w.Flush()
+
+			if dryRun {
+				fmt.Printf("%d job(s) would be deleted (dry run)\n", len(stale))
+				return nil
+			}
+			ok, err := confirm(fmt.Sprintf("Delete %d failed job(s) and their pods in namespace %s?", len(stale), namespace), yes)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Cleanup cancelled")
+				return nil
+			}
+
+			propagation := metav1.DeletePropagationBackground
+			var failed []string
+			for _, job := range stale {
+				err := clientset.BatchV1().Jobs(namespace).Delete(context.TODO(), job.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error deleting job %s: %v\n", job.Name, err)
+					failed = append(failed, job.Name)
+					continue
+				}
+				fmt.Printf("Job %s deleted from namespace %s\n", job.Name, namespace)
+			}
+			if len(failed) > 0 {
+				return fmt.Errorf("failed to delete %d job(s): %s", len(failed), strings.Join(failed, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&olderThan, "older-than", 24*time.Hour, "only delete jobs that failed at least this long ago (e.g. 24h, 168h)")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector to choose jobs (e.g. app=backup)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only list the jobs that would be deleted")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	return cmd
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,6 +9,7 @@ import (
 	"github.com/k8s-admin-cli/ui"
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 )
@@ -19,6 +21,11 @@ var (
 	tui        bool
 )
 
+// errDifferences is returned by the diff commands when the compared objects
+// differ. main exits with status 1 for it without printing an error, as
+// diff does.
+var errDifferences = errors.New("differences found")
+
 func main() {
 	if home := homedir.HomeDir(); home != "" {
 		kubeconfig = filepath.Join(home, ".kube", "config")
@@ -30,7 +37,12 @@ func main() {
 		Long:  `A command line tool for managing Kubernetes permissions, service accounts, and administrative tasks.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if tui {
-				if err := ui.New().Start(); err != nil {
+				// Without -n the TUI lists every namespace.
+				ns := ""
+				if cmd.Flags().Changed("namespace") {
+					ns = namespace
+				}
+				if err := ui.New(ns).Start(); err != nil {
 					fmt.Printf("Error running TUI: %v\n", err)
 					os.Exit(1)
 				}
@@ -52,15 +64,43 @@ func main() {
 	rootCmd.AddCommand(newResourceAnalyzerCmd())
 	rootCmd.AddCommand(newVisualizeCmd())
 	rootCmd.AddCommand(newPodCmd())
+	rootCmd.AddCommand(newDeploymentCmd())
+	rootCmd.AddCommand(newStatefulSetCmd())
+	rootCmd.AddCommand(newDaemonSetCmd())
+	rootCmd.AddCommand(newJobCmd())
+	rootCmd.AddCommand(newCronJobCmd())
+	rootCmd.AddCommand(newConfigMapCmd())
+	rootCmd.AddCommand(newSecretCmd())
+	rootCmd.AddCommand(newServiceCmd())
+	rootCmd.AddCommand(newIngressCmd())
+	rootCmd.AddCommand(newNamespaceCmd())
+	rootCmd.AddCommand(newQuotaCmd())
+	rootCmd.AddCommand(newStorageCmd())
+	rootCmd.AddCommand(newPortForwardCmd())
+	rootCmd.AddCommand(newNodeCmd())
 
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		if !errors.Is(err, errDifferences) {
+			fmt.Println(err)
+		}
 		os.Exit(1)
 	}
 }
 
+// differencesFound returns errDifferences from a diff command without cobra
+// reporting it as a failure with the usage text.
+func differencesFound(cmd *cobra.Command) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return errDifferences
+}
+
+func getRestConfig() (*rest.Config, error) {
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
+
 func getClientset() (*kubernetes.Clientset, error) {
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	config, err := getRestConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,523 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// fieldManager is the server-side apply field manager of namespace onboard.
+const fieldManager = "k8s-admin"
+
+// protectedNamespaces can be neither onboarded nor offboarded, nor can any
+// other namespace with the reserved kube- prefix.
+var protectedNamespaces = []string{"default", "kube-system", "kube-public", "kube-node-lease"}
+
+func isProtectedNamespace(name string) bool {
+	for _, protected := range protectedNamespaces {
+		if name == protected {
+			return true
+		}
+	}
+	return strings.HasPrefix(name, "kube-")
+}
+
+func newNamespaceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "namespace",
+		Aliases: []string{"ns"},
+		Short:   "Onboard and offboard team namespaces",
+		Long: `Set up namespaces for teams with quotas, default limits, network policies,
+Pod Security Admission labels and RBAC in one step, and archive and remove
+them when a team leaves.`,
+	}
+
+	cmd.AddCommand(newNamespaceOnboardCmd())
+	cmd.AddCommand(newNamespaceOffboardCmd())
+	cmd.AddCommand(newNamespaceProfileCmd())
+
+	return cmd
+}
+
+func newNamespaceOnboardCmd() *cobra.Command {
+	var (
+		team        string
+		group       string
+		profilePath string
+		dryRun      string
+		adopt       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "onboard <name>",
+		Short: "Create or update a team namespace",
+		Long: `Create a namespace for a team, or bring an existing one in line with the
+profile. The namespace gets team and Pod Security Admission labels, and in it
+are created:
+
+  - a ResourceQuota (team-quota) and a LimitRange (team-limits)
+  - default network policies: deny ingress, allow traffic within the
+    namespace and, optionally, from other namespaces
+  - a team-admin Role bound to the team's group (--group, default: the team)
+  - a CI service account bound to the team-admin Role
+
+Every object is applied with server-side apply, so running the command again
+is safe: unchanged objects are left alone and changed ones are updated.
+Objects that a later profile no longer includes are not removed.
+
+System namespaces cannot be onboarded. An existing namespace that namespace
+onboard did not create is only taken over with --adopt, since its labels,
+quota and network policies are overwritten.
+
+--profile reads a YAML profile; "namespace profile" prints the default one
+to start from. --dry-run=client prints the objects, --dry-run=server
+validates them without saving.`,
+		Example: `  k8s-admin namespace onboard payments --team payments
+  k8s-admin namespace onboard payments --team payments --group oidc:payments-admins --profile team.yaml
+  k8s-admin namespace onboard payments --team payments --dry-run=client`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+				return fmt.Errorf("invalid namespace name %q: %s", name, strings.Join(errs, "; "))
+			}
+			if isProtectedNamespace(name) {
+				return fmt.Errorf("namespace %s is a system namespace and cannot be onboarded", name)
+			}
+			if errs := validation.IsValidLabelValue(team); team == "" || len(errs) > 0 {
+				return fmt.Errorf("--team must be a valid label value: %s", strings.Join(errs, "; "))
+			}
+			switch dryRun {
+			case "none", "client", "server":
+			default:
+				return fmt.Errorf("invalid --dry-run %q: must be none, client or server", dryRun)
+			}
+			if group == "" {
+				group = team
+			}
+
+			profile, err := loadOnboardProfile(profilePath)
+			if err != nil {
+				return err
+			}
+			objects := onboardObjects(name, team, group, profile)
+
+			if dryRun == "client" {
+				for i, obj := range objects {
+					data, err := encodeObject(obj, "yaml")
+					if err != nil {
+						return err
+					}
+					if i > 0 {
+						fmt.Println("---")
+					}
+					fmt.Print(string(data))
+				}
+				return nil
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			existing, err := clientset.CoreV1().Namespaces().Get(context.TODO(), name, metav1.GetOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			if err == nil {
+				if owner := existing.Labels[teamLabel]; owner != "" && owner != team {
+					return fmt.Errorf("namespace %s is onboarded for team %s; offboard it first", name, owner)
+				}
+				if existing.Labels[managedByLabel] != managedByValue && !adopt {
+					return fmt.Errorf("namespace %s already exists and is not managed by k8s-admin; use --adopt to onboard it anyway", name)
+				}
+			}
+
+			force := true
+			opts := metav1.PatchOptions{FieldManager: fieldManager, Force: &force}
+			suffix := ""
+			if dryRun == "server" {
+				opts.DryRun = []string{metav1.DryRunAll}
+				suffix = " (server dry run)"
+			}
+
+			for i, obj := range objects {
+				kind := exportObject(obj).GetObjectKind().GroupVersionKind().Kind
+				accessor, err := meta.Accessor(obj)
+				if err != nil {
+					return err
+				}
+
+				before, after, err := applyObject(context.TODO(), clientset, obj, opts)
+				if err != nil {
+					return fmt.Errorf("error applying %s %s: %v", kind, accessor.GetName(), err)
+				}
+				status := "configured"
+				switch {
+				case before == "":
+					status = "created"
+				case before == after:
+					status = "unchanged"
+				}
+				fmt.Printf("%s %s %s%s\n", kind, accessor.GetName(), status, suffix)
+
+				// A dry run does not create the namespace, so the server
+				// cannot validate what would go in it.
+				if i == 0 && before == "" && dryRun == "server" {
+					fmt.Printf("Namespace %s does not exist yet; the %d object(s) in it were not validated\n", name, len(objects)-1)
+					return nil
+				}
+			}
+
+			if dryRun == "none" {
+				fmt.Printf("Namespace %s is onboarded for team %s\n", name, team)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&team, "team", "", "team that owns the namespace")
+	cmd.Flags().StringVar(&group, "group", "", "group bound to the team-admin role (default: the team name)")
+	cmd.Flags().StringVar(&profilePath, "profile", "", "YAML profile to use instead of the default one")
+	cmd.Flags().StringVar(&dryRun, "dry-run", "none", "Only print (client) or validate on the server (server) instead of applying")
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = "client"
+	cmd.Flags().BoolVar(&adopt, "adopt", false, "onboard an existing namespace that k8s-admin does not manage yet")
+	cmd.MarkFlagRequired("team")
+	return cmd
+}
+
+// applyObject applies obj with server-side apply and returns its resource
+// version before (empty if it did not exist) and after.
+func applyObject(ctx context.Context, clientset *kubernetes.Clientset, obj runtime.Object, opts metav1.PatchOptions) (before, after string, err error) {
+	data, err := encodeObject(obj, "json")
+	if err != nil {
+		return "", "", err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", "", err
+	}
+	name, ns := accessor.GetName(), accessor.GetNamespace()
+	get := metav1.GetOptions{}
+
+	switch obj.(type) {
+	case *corev1.Namespace:
+		c := clientset.CoreV1().Namespaces()
+		if before, err = versionOf(c.Get(ctx, name, get)); err == nil {
+			after, err = versionOf(c.Patch(ctx, name, types.ApplyPatchType, data, opts))
+		}
+	case *corev1.ResourceQuota:
+		c := clientset.CoreV1().ResourceQuotas(ns)
+		if before, err = versionOf(c.Get(ctx, name, get)); err == nil {
+			after, err = versionOf(c.Patch(ctx, name, types.ApplyPatchType, data, opts))
+		}
+	case *corev1.LimitRange:
+		c := clientset.CoreV1().LimitRanges(ns)
+		if before, err = versionOf(c.Get(ctx, name, get)); err == nil {
+			after, err = versionOf(c.Patch(ctx, name, types.ApplyPatchType, data, opts))
+		}
+	case *corev1.ServiceAccount:
+		c := clientset.CoreV1().ServiceAccounts(ns)
+		if before, err = versionOf(c.Get(ctx, name, get)); err == nil {
+			after, err = versionOf(c.Patch(ctx, name, types.ApplyPatchType, data, opts))
+		}
+	case *networkingv1.NetworkPolicy:
+		c := clientset.NetworkingV1().NetworkPolicies(ns)
+		if before, err = versionOf(c.Get(ctx, name, get)); err == nil {
+			after, err = versionOf(c.Patch(ctx, name, types.ApplyPatchType, data, opts))
+		}
+	case *rbacv1.Role:
+		c := clientset.RbacV1().Roles(ns)
+		if before, err = versionOf(c.Get(ctx, name, get)); err == nil {
+			after, err = versionOf(c.Patch(ctx, name, types.ApplyPatchType, data, opts))
+		}
+	case *rbacv1.RoleBinding:
+		c := clientset.RbacV1().RoleBindings(ns)
+		if before, err = versionOf(c.Get(ctx, name, get)); err == nil {
+			after, err = versionOf(c.Patch(ctx, name, types.ApplyPatchType, data, opts))
+		}
+	default:
+		err = fmt.Errorf("applying a %T is not supported", obj)
+	}
+	return before, after, err
+}
+
+// versionOf returns the resource version of an object returned by a client
+// call, or an empty version if it was not found.
+func versionOf[T metav1.Object](obj T, err error) (string, error) {
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return obj.GetResourceVersion(), nil
+}
+
+func newNamespaceOffboardCmd() *cobra.Command {
+	var (
+		backupDir string
+		dryRun    bool
+		yes       bool
+		unmanaged bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "offboard <name>",
+		Short: "Archive and delete a namespace",
+		Long: `Write the configuration in a namespace to a YAML archive and delete the
+namespace with everything in it.
+
+The archive holds the namespace and its workloads, services, ingresses,
+config maps, secrets, persistent volume claims, quotas, limit ranges,
+network policies, RBAC, autoscalers and disruption budgets. Objects created
+by controllers, such as pods and ReplicaSets, are left out, as is the data
+in persistent volumes. The archive contains the namespace's secrets and is
+only readable by you.
+
+The fields the API server fills in are removed so that the archive can be
+created again with kubectl create -f: the generated selectors and labels of
+Jobs, and the volume binding of persistent volume claims, which are bound
+to new volumes.
+
+System namespaces cannot be offboarded. A namespace that namespace onboard
+did not create is only offboarded with --unmanaged.`,
+		Example: `  k8s-admin namespace offboard payments --dry-run
+  k8s-admin namespace offboard payments --backup-dir ./archive`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if isProtectedNamespace(name) {
+				return fmt.Errorf("namespace %s is a system namespace and cannot be offboarded", name)
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			ns, err := clientset.CoreV1().Namespaces().Get(context.TODO(), name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if ns.Status.Phase == corev1.NamespaceTerminating {
+				return fmt.Errorf("namespace %s is already being deleted", name)
+			}
+			if ns.Labels[managedByLabel] != managedByValue && !unmanaged {
+				return fmt.Errorf("namespace %s is not managed by k8s-admin; use --unmanaged to offboard it anyway", name)
+			}
+
+			objects, err := archiveNamespace(clientset, name)
+			if err != nil {
+				return err
+			}
+			objects = append([]runtime.Object{archivedObject(ns)}, objects...)
+
+			counts := map[string]int{}
+			for _, obj := range objects {
+				counts[obj.GetObjectKind().GroupVersionKind().Kind]++
+			}
+			kinds := make([]string, 0, len(counts))
+			for kind := range counts {
+				kinds = append(kinds, kind)
+			}
+			sort.Strings(kinds)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KIND\tCOUNT")
+			for _, kind := range kinds {
+				fmt.Fprintf(w, "%s\t%d\n", kind, counts[kind])
+			}
+			w.Flush()
+			if team := ns.Labels[teamLabel]; team != "" {
+				fmt.Printf("Namespace %s belongs to team %s\n", name, team)
+			}
+
+			if dryRun {
+				fmt.Printf("%d object(s) would be archived and namespace %s deleted (dry run)\n", len(objects), name)
+				return nil
+			}
+			ok, err := confirm(fmt.Sprintf("Archive and delete namespace %s and everything in it?", name), yes)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Offboard cancelled")
+				return nil
+			}
+
+			path, err := writeBackup(backupDir, "namespace-"+name, objects)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Archive written to %s\n", path)
+
+			if err := clientset.CoreV1().Namespaces().Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
+				return fmt.Errorf("error deleting namespace: %v", err)
+			}
+			fmt.Printf("Namespace %s is being deleted\n", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backupDir, "backup-dir", ".", "directory to write the YAML archive to")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only show what would be archived")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	cmd.Flags().BoolVar(&unmanaged, "unmanaged", false, "offboard a namespace that k8s-admin does not manage")
+	return cmd
+}
+
+// archiveNamespace returns the objects in ns worth keeping, ready to be
+// created again: objects owned by a controller and the ones Kubernetes
+// creates in every namespace are skipped.
+func archiveNamespace(clientset *kubernetes.Clientset, ns string) ([]runtime.Object, error) {
+	ctx := context.TODO()
+	opts := metav1.ListOptions{}
+	lists := []func() (runtime.Object, error){
+		func() (runtime.Object, error) { return clientset.CoreV1().ResourceQuotas(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.CoreV1().LimitRanges(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.CoreV1().ServiceAccounts(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.RbacV1().Roles(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.RbacV1().RoleBindings(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.NetworkingV1().NetworkPolicies(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.CoreV1().ConfigMaps(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.CoreV1().Secrets(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.CoreV1().PersistentVolumeClaims(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.CoreV1().Services(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.NetworkingV1().Ingresses(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.AppsV1().Deployments(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.AppsV1().StatefulSets(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.AppsV1().DaemonSets(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.BatchV1().CronJobs(ns).List(ctx, opts) },
+		func() (runtime.Object, error) { return clientset.BatchV1().Jobs(ns).List(ctx, opts) },
+		func() (runtime.Object, error) {
+			return clientset.AutoscalingV2().HorizontalPodAutoscalers(ns).List(ctx, opts)
+		},
+		func() (runtime.Object, error) { return clientset.PolicyV1().PodDisruptionBudgets(ns).List(ctx, opts) },
+	}
+
+	var objects []runtime.Object
+	for _, list := range lists {
+		result, err := list()
+		if err != nil {
+			return nil, fmt.Errorf("error listing objects in namespace %s: %v", ns, err)
+		}
+		items, err := meta.ExtractList(result)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if skipArchive(item) {
+				continue
+			}
+			objects = append(objects, archivedObject(item))
+		}
+	}
+	return objects, nil
+}
+
+func skipArchive(obj runtime.Object) bool {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return true
+	}
+	for _, owner := range accessor.GetOwnerReferences() {
+		if owner.Controller != nil && *owner.Controller {
+			return true
+		}
+	}
+	switch o := obj.(type) {
+	case *corev1.ServiceAccount:
+		return o.Name == "default"
+	case *corev1.ConfigMap:
+		return o.Name == "kube-root-ca.crt"
+	case *corev1.Secret:
+		return o.Type == corev1.SecretTypeServiceAccountToken
+	}
+	return false
+}
+
+// generatedJobLabels are set by the Job controller on a Job and its pod
+// template, and rejected on create unless the Job sets manualSelector.
+var generatedJobLabels = []string{
+	"controller-uid", "job-name",
+	batchv1.ControllerUidLabel, batchv1.JobNameLabel,
+}
+
+// claimBindAnnotations record how a claim was bound or provisioned; a
+// re-created claim is bound to a new volume.
+var claimBindAnnotations = []string{
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/selected-node",
+}
+
+// archivedObject strips the fields that the API server sets, so that the
+// archive can be created again with kubectl create -f.
+func archivedObject(obj runtime.Object) runtime.Object {
+	obj = exportObject(obj)
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetResourceVersion("")
+		accessor.SetUID("")
+		accessor.SetGeneration(0)
+		accessor.SetCreationTimestamp(metav1.Time{})
+	}
+
+	switch o := obj.(type) {
+	case *batchv1.Job:
+		if o.Spec.ManualSelector == nil || !*o.Spec.ManualSelector {
+			o.Spec.Selector = nil
+			for _, label := range generatedJobLabels {
+				delete(o.Labels, label)
+				delete(o.Spec.Template.Labels, label)
+			}
+		}
+	case *corev1.PersistentVolumeClaim:
+		o.Spec.VolumeName = ""
+		for _, annotation := range claimBindAnnotations {
+			delete(o.Annotations, annotation)
+		}
+	}
+	return obj
+}
+
+func newNamespaceProfileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Print the default onboarding profile",
+		Long: `Print the default profile of namespace onboard as YAML. Save it, adjust it
+and pass it with --profile; fields removed from the file keep their
+defaults.`,
+		Example: `  k8s-admin namespace profile > team.yaml`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := yaml.Marshal(defaultOnboardProfile())
+			if err != nil {
+				return fmt.Errorf("error encoding profile: %v", err)
+			}
+			fmt.Print(string(data))
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// teamLabel records which team owns an onboarded namespace.
+	teamLabel = "k8s-admin/team"
+	// managedByLabel marks every object created by namespace onboard.
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "k8s-admin"
+
+	// Names of the objects namespace onboard creates in each namespace.
+	quotaName              = "team-quota"
+	limitRangeName         = "team-limits"
+	denyIngressPolicyName  = "default-deny-ingress"
+	sameNamespacePolicy    = "allow-same-namespace"
+	fromNamespacesPolicy   = "allow-from-namespaces"
+	teamAdminRoleName      = "team-admin"
+	ciBindingName          = "ci-team-admin"
+	podSecurityLabelPrefix = "pod-security.kubernetes.io/"
+)
+
+// onboardProfile describes what namespace onboard sets up. Fields left out
+// of a profile file keep their defaults; see "namespace profile".
+type onboardProfile struct {
+	// Labels are added to the namespace besides the team label.
+	Labels map[string]string `json:"labels,omitempty"`
+	// PodSecurity holds the Pod Security Admission levels (privileged,
+	// baseline or restricted) per mode.
+	PodSecurity *podSecurityProfile `json:"podSecurity,omitempty"`
+	// Quota is the hard limit of the namespace's ResourceQuota.
+	Quota corev1.ResourceList `json:"quota,omitempty"`
+	// LimitRange holds the container defaults applied to pods that do not
+	// set their own requests and limits.
+	LimitRange *limitRangeProfile `json:"limitRange,omitempty"`
+	// NetworkPolicies selects the default network policies.
+	NetworkPolicies *networkPolicyProfile `json:"networkPolicies,omitempty"`
+	// AdminRules are the rules of the team admin role.
+	AdminRules []rbacv1.PolicyRule `json:"adminRules,omitempty"`
+	// CIServiceAccount is the name of the service account for pipelines,
+	// which is bound to the team admin role. Empty disables it.
+	CIServiceAccount *string `json:"ciServiceAccount,omitempty"`
+}
+
+type podSecurityProfile struct {
+	Enforce string `json:"enforce,omitempty"`
+	Warn    string `json:"warn,omitempty"`
+	Audit   string `json:"audit,omitempty"`
+}
+
+type limitRangeProfile struct {
+	DefaultRequest corev1.ResourceList `json:"defaultRequest,omitempty"`
+	Default        corev1.ResourceList `json:"default,omitempty"`
+	Max            corev1.ResourceList `json:"max,omitempty"`
+}
+
+type networkPolicyProfile struct {
+	// DenyIngress blocks all ingress traffic that another policy does not
+	// allow.
+	DenyIngress *bool `json:"denyIngress,omitempty"`
+	// AllowSameNamespace allows traffic between the pods of the namespace.
+	AllowSameNamespace *bool `json:"allowSameNamespace,omitempty"`
+	// AllowFromNamespaces allows ingress from every pod of these
+	// namespaces, such as the ingress controller's.
+	AllowFromNamespaces []string `json:"allowFromNamespaces,omitempty"`
+}
+
+// defaultOnboardProfile is the profile used without --profile, and the
+// source of the defaults for fields a profile file leaves out.
+func defaultOnboardProfile() *onboardProfile {
+	enabled := true
+	ci := "ci"
+	return &onboardProfile{
+		PodSecurity: &podSecurityProfile{Enforce: "baseline", Warn: "restricted", Audit: "restricted"},
+		Quota: corev1.ResourceList{
+			corev1.ResourceRequestsCPU:            resource.MustParse("4"),
+			corev1.ResourceRequestsMemory:         resource.MustParse("8Gi"),
+			corev1.ResourceLimitsCPU:              resource.MustParse("8"),
+			corev1.ResourceLimitsMemory:           resource.MustParse("16Gi"),
+			corev1.ResourcePods:                   resource.MustParse("50"),
+			corev1.ResourcePersistentVolumeClaims: resource.MustParse("10"),
+			corev1.ResourceRequestsStorage:        resource.MustParse("100Gi"),
+		},
+		LimitRange: &limitRangeProfile{
+			DefaultRequest: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("128Mi"),
+			},
+			Default: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			},
+		},
+		NetworkPolicies: &networkPolicyProfile{
+			DenyIngress:        &enabled,
+			AllowSameNamespace: &enabled,
+		},
+		AdminRules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "pods/log", "pods/exec", "pods/portforward", "pods/ephemeralcontainers",
+					"services", "endpoints", "configmaps", "secrets", "persistentvolumeclaims", "serviceaccounts"},
+				Verbs: []string{"*"},
+			},
+			{APIGroups: []string{"apps"}, Resources: []string{"deployments", "deployments/scale", "statefulsets", "statefulsets/scale", "daemonsets", "replicasets"}, Verbs: []string{"*"}},
+			{APIGroups: []string{"batch"}, Resources: []string{"jobs", "cronjobs"}, Verbs: []string{"*"}},
+			{APIGroups: []string{"autoscaling"}, Resources: []string{"horizontalpodautoscalers"}, Verbs: []string{"*"}},
+			{APIGroups: []string{"policy"}, Resources: []string{"poddisruptionbudgets"}, Verbs: []string{"*"}},
+			{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"ingresses"}, Verbs: []string{"*"}},
+			// The guard rails set up by onboarding are visible, not editable.
+			{APIGroups: []string{""}, Resources: []string{"events", "resourcequotas", "limitranges"}, Verbs: []string{"get", "list", "watch"}},
+			{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"networkpolicies"}, Verbs: []string{"get", "list", "watch"}},
+			{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"roles", "rolebindings"}, Verbs: []string{"get", "list", "watch"}},
+		},
+		CIServiceAccount: &ci,
+	}
+}
+
+// loadOnboardProfile reads a profile file, or returns the default profile
+// when path is empty.
+func loadOnboardProfile(path string) (*onboardProfile, error) {
+	defaults := defaultOnboardProfile()
+	if path == "" {
+		return defaults, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading profile: %v", err)
+	}
+	profile := &onboardProfile{}
+	if err := yaml.UnmarshalStrict(data, profile); err != nil {
+		return nil, fmt.Errorf("error parsing profile %s: %v", path, err)
+	}
+
+	// Quota, limit range and rules are taken from the file as a whole, so
+	// that a profile can drop a default quota resource rather than only add
+	// to it.
+	if profile.PodSecurity == nil {
+		profile.PodSecurity = defaults.PodSecurity
+	}
+	// Within podSecurity and networkPolicies each setting has its own
+	// default; a level of privileged or false turns one off.
+	if profile.PodSecurity.Enforce == "" {
+		profile.PodSecurity.Enforce = defaults.PodSecurity.Enforce
+	}
+	if profile.PodSecurity.Warn == "" {
+		profile.PodSecurity.Warn = defaults.PodSecurity.Warn
+	}
+	if profile.PodSecurity.Audit == "" {
+		profile.PodSecurity.Audit = defaults.PodSecurity.Audit
+	}
+	if profile.Quota == nil {
+		profile.Quota = defaults.Quota
+	}
+	if profile.LimitRange == nil {
+		profile.LimitRange = defaults.LimitRange
+	}
+	if profile.NetworkPolicies == nil {
+		profile.NetworkPolicies = defaults.NetworkPolicies
+	}
+	if profile.NetworkPolicies.DenyIngress == nil {
+		profile.NetworkPolicies.DenyIngress = defaults.NetworkPolicies.DenyIngress
+	}
+	if profile.NetworkPolicies.AllowSameNamespace == nil {
+		profile.NetworkPolicies.AllowSameNamespace = defaults.NetworkPolicies.AllowSameNamespace
+	}
+	if profile.AdminRules == nil {
+		profile.AdminRules = defaults.AdminRules
+	}
+	if profile.CIServiceAccount == nil {
+		profile.CIServiceAccount = defaults.CIServiceAccount
+	}
+
+	for mode, level := range map[string]string{"enforce": profile.PodSecurity.Enforce, "warn": profile.PodSecurity.Warn, "audit": profile.PodSecurity.Audit} {
+		switch level {
+		case "", "privileged", "baseline", "restricted":
+		default:
+			return nil, fmt.Errorf("invalid podSecurity.%s %q in %s: must be privileged, baseline or restricted", mode, level, path)
+		}
+	}
+	return profile, nil
+}
+
+// onboardObjects builds the objects for a team namespace in the order they
+// must be applied: the namespace first.
+func onboardObjects(name, team, group string, profile *onboardProfile) []runtime.Object {
+	managed := func(objectName string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:      objectName,
+			Namespace: name,
+			Labels:    map[string]string{managedByLabel: managedByValue, teamLabel: team},
+		}
+	}
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+	for k, v := range profile.Labels {
+		ns.Labels[k] = v
+	}
+	ns.Labels[teamLabel] = team
+	ns.Labels[managedByLabel] = managedByValue
+	for mode, level := range map[string]string{"enforce": profile.PodSecurity.Enforce, "warn": profile.PodSecurity.Warn, "audit": profile.PodSecurity.Audit} {
+		if level != "" {
+			ns.Labels[podSecurityLabelPrefix+mode] = level
+		}
+	}
+	objects := []runtime.Object{ns}
+
+	if len(profile.Quota) > 0 {
+		objects = append(objects, &corev1.ResourceQuota{
+			ObjectMeta: managed(quotaName),
+			Spec:       corev1.ResourceQuotaSpec{Hard: profile.Quota},
+		})
+	}
+
+	if lr := profile.LimitRange; lr != nil && (len(lr.DefaultRequest) > 0 || len(lr.Default) > 0 || len(lr.Max) > 0) {
+		objects = append(objects, &corev1.LimitRange{
+			ObjectMeta: managed(limitRangeName),
+			Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+				Type:           corev1.LimitTypeContainer,
+				DefaultRequest: lr.DefaultRequest,
+				Default:        lr.Default,
+				Max:            lr.Max,
+			}}},
+		})
+	}
+
+	np := profile.NetworkPolicies
+	ingressOnly := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	if np.DenyIngress != nil && *np.DenyIngress {
+		objects = append(objects, &networkingv1.NetworkPolicy{
+			ObjectMeta: managed(denyIngressPolicyName),
+			Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: ingressOnly},
+		})
+	}
+	if np.AllowSameNamespace != nil && *np.AllowSameNamespace {
+		objects = append(objects, &networkingv1.NetworkPolicy{
+			ObjectMeta: managed(sameNamespacePolicy),
+			Spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: ingressOnly,
+				Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}}},
+			},
+		})
+	}
+	if len(np.AllowFromNamespaces) > 0 {
+		var peers []networkingv1.NetworkPolicyPeer
+		for _, from := range np.AllowFromNamespaces {
+			peers = append(peers, networkingv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{corev1.LabelMetadataName: from},
+			}})
+		}
+		objects = append(objects, &networkingv1.NetworkPolicy{
+			ObjectMeta: managed(fromNamespacesPolicy),
+			Spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: ingressOnly,
+				Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: peers}},
+			},
+		})
+	}
+
+	objects = append(objects,
+		&rbacv1.Role{ObjectMeta: managed(teamAdminRoleName), Rules: profile.AdminRules},
+		&rbacv1.RoleBinding{
+			ObjectMeta: managed(teamAdminRoleName),
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: group}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: teamAdminRoleName},
+		},
+	)
+
+	if sa := *profile.CIServiceAccount; sa != "" {
+		objects = append(objects,
+			&corev1.ServiceAccount{ObjectMeta: managed(sa)},
+			&rbacv1.RoleBinding{
+				ObjectMeta: managed(ciBindingName),
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: sa, Namespace: name}},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: teamAdminRoleName},
+			},
+		)
+	}
+
+	return objects
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/k8s-admin-cli/remote"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newNodeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "node",
+		Short: "Work with cluster nodes",
+		Long:  `Troubleshoot cluster nodes.`,
+	}
+
+	cmd.AddCommand(newNodeDebugCmd())
+
+	return cmd
+}
+
+func newNodeDebugCmd() *cobra.Command {
+	var (
+		image   string
+		keep    bool
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "debug <node> [-- command [args...]]",
+		Short: "Start a privileged debug pod on a node",
+		Long: `Create a privileged pod pinned to the node that shares the host PID,
+network and IPC namespaces, with the host filesystem mounted at /host, and
+attach to it. The pod tolerates every taint so it also schedules on cordoned
+or control-plane nodes. It is deleted when the session ends unless --keep is
+given.
+
+Run "chroot /host" inside the pod to use the node's own tools.`,
+		Example: `  k8s-admin node debug worker-1
+  k8s-admin node debug worker-1 --image nicolaka/netshoot`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.ArgsLenAtDash() > 1 || (cmd.ArgsLenAtDash() == -1 && len(args) > 1) {
+				return fmt.Errorf("usage: node debug <node> [-- command [args...]]")
+			}
+			nodeName := args[0]
+			command := args[1:]
+
+			config, err := getRestConfig()
+			if err != nil {
+				return err
+			}
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			if _, err := clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{}); err != nil {
+				return err
+			}
+
+			ns := namespace
+			if ns == "" {
+				ns = "default"
+			}
+			tty := remote.IsTerminal(os.Stdin)
+			pod, err := clientset.CoreV1().Pods(ns).Create(context.TODO(), nodeDebugPod(nodeName, image, command, tty), metav1.CreateOptions{})
+			if err != nil {
+				return fmt.Errorf("error creating debug pod: %v", err)
+			}
+			fmt.Printf("Debug pod %s/%s created on node %s\n", ns, pod.Name, nodeName)
+
+			if !keep {
+				defer func() {
+					zero := int64(0)
+					err := clientset.CoreV1().Pods(ns).Delete(context.Background(), pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &zero})
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error deleting debug pod %s: %v\n", pod.Name, err)
+						return
+					}
+					fmt.Printf("Debug pod %s deleted\n", pod.Name)
+				}()
+			}
+
+			// The pod has to be removed even if the user gives up while it is
+			// still being scheduled or pulling its image.
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			fmt.Println("Waiting for the debug pod to start...")
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- waitForContainerRunning(clientset, ns, pod.Name, "debugger", timeout)
+			}()
+			select {
+			case err := <-errCh:
+				if err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return fmt.Errorf("interrupted")
+			}
+
+			fmt.Println("If you don't see a command prompt, try pressing enter.")
+			return remote.Attach(ctx, config, clientset, remote.StreamOptions{
+				Namespace: ns,
+				Pod:       pod.Name,
+				Container: "debugger",
+				Stdin:     os.Stdin,
+				Stdout:    os.Stdout,
+				Stderr:    os.Stderr,
+				TTY:       tty,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&image, "image", "busybox", "image of the debug pod")
+	cmd.Flags().BoolVar(&keep, "keep", false, "leave the debug pod running after the session ends")
+	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "how long to wait for the debug pod to start")
+	return cmd
+}
+
+// nodeDebugPod builds a privileged pod bound to nodeName that runs in the
+// host namespaces with the root filesystem mounted at /host.
+func nodeDebugPod(nodeName, image string, command []string, tty bool) *corev1.Pod {
+	privileged := true
+	hostPathType := corev1.HostPathDirectory
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "node-debugger-" + nodeName + "-",
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "k8s-admin",
+				"k8s-admin/node-debugger":      "true",
+			},
+			// Node names can be longer than the 63 characters a label
+			// value allows.
+			Annotations: map[string]string{
+				"k8s-admin/node-debugger": nodeName,
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:      nodeName,
+			HostPID:       true,
+			HostNetwork:   true,
+			HostIPC:       true,
+			RestartPolicy: corev1.RestartPolicyNever,
+			Tolerations: []corev1.Toleration{
+				{Operator: corev1.TolerationOpExists},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:            "debugger",
+					Image:           image,
+					Command:         command,
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Stdin:           true,
+					StdinOnce:       true,
+					TTY:             tty,
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: &privileged,
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "host-root", MountPath: "/host"},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "host-root",
+					VolumeSource: corev1.VolumeSource{
+						HostPath: &corev1.HostPathVolumeSource{Path: "/", Type: &hostPathType},
+					},
+				},
+			},
+		},
+	}
+}
@@ -3,12 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
 
+	"github.com/k8s-admin-cli/inspector"
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 func newPodCmd() *cobra.Command {
@@ -21,189 +24,235 @@ func newPodCmd() *cobra.Command {
 	cmd.AddCommand(newPodListCmd())
 	cmd.AddCommand(newPodCreateCmd())
 	cmd.AddCommand(newPodDeleteCmd())
+	cmd.AddCommand(newPodDescribeCmd())
+	cmd.AddCommand(newPodLogsCmd())
+	cmd.AddCommand(newPodExecCmd())
+	cmd.AddCommand(newPodAttachCmd())
+	cmd.AddCommand(newPodPortForwardCmd())
+	cmd.AddCommand(newPodCpCmd())
+	cmd.AddCommand(newPodDebugCmd())
+	cmd.AddCommand(newPodDiagnoseCmd())
+	cmd.AddCommand(newPodTopCmd())
 
 	return cmd
 }
 
 func newPodListCmd() *cobra.Command {
-	return &cobra.Command{
+	var (
+		selector   string
+		sortBy     string
+		showLabels bool
+		watchPods  bool
+		output     string
+	)
+
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List pods",
+		Long: `List pods with their ready containers, status, restarts, age, IP, node and
+nominated node. STATUS reports container waiting and termination reasons
+such as CrashLoopBackOff or Init:0/2 rather than just the pod phase.
+
+With --watch the table is refreshed in place as pods change; combine it with
+-o json to get a stream of ADDED, MODIFIED and DELETED events instead.`,
+		Example: `  k8s-admin pod list --sort-by restarts
+  k8s-admin pod list -l app=web --show-labels
+  k8s-admin pod list -w -o json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+			tableOptions := inspector.PodTableOptions{
+				SortBy:     sortBy,
+				ShowLabels: showLabels,
+			}
+			if err := inspector.SortPodRows(nil, sortBy); err != nil {
+				return err
+			}
+
 			clientset, err := getClientset()
 			if err != nil {
 				return err
 			}
 
-			pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+			if watchPods {
+				pods := clientset.CoreV1().Pods(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						opts.LabelSelector = selector
+						return pods.List(ctx, opts)
+					},
+					watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+						opts.LabelSelector = selector
+						return pods.Watch(ctx, opts)
+					},
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]corev1.Pod, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*corev1.Pod))
+						}
+						table, err := inspector.FormatPodTable(items, tableOptions)
+						if err != nil {
+							return err
+						}
+						_, err = io.WriteString(w, table)
+						return err
+					},
+				}, output)
+			}
+
+			pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
 			if err != nil {
 				return err
 			}
 
-			fmt.Printf("Pods in namespace %s:\n", namespace)
-			for _, pod := range pods.Items {
-				fmt.Printf("- %s (Status: %s)\n", pod.Name, pod.Status.Phase)
-				if len(pod.Spec.Containers) > 0 {
-					container := pod.Spec.Containers[0]
-					fmt.Printf("  Image: %s\n", container.Image)
-					if len(container.Ports) > 0 {
-						fmt.Printf("  Ports: ")
-						for _, port := range container.Ports {
-							fmt.Printf("%d/%s ", port.ContainerPort, port.Protocol)
-						}
-						fmt.Println()
-					}
-				}
+			if output == "json" {
+				return printObject(pods, output)
 			}
+			if len(pods.Items) == 0 {
+				fmt.Printf("No pods found in namespace %s\n", namespace)
+				return nil
+			}
+
+			table, err := inspector.FormatPodTable(pods.Items, tableOptions)
+			if err != nil {
+				return err
+			}
+			fmt.Print(table)
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector to filter pods (e.g. app=web)")
+	cmd.Flags().StringVar(&sortBy, "sort-by", "", "column to sort on: "+strings.Join(inspector.PodTableColumns, ", "))
+	cmd.Flags().BoolVar(&showLabels, "show-labels", false, "show pod labels as the last column")
+	addWatchFlags(cmd, &watchPods, &output)
+	return cmd
+}
+
+// podCreateOptions collects the pod create flags. The same flags build a pod
+// from scratch or override a pod read from a manifest or cloned from a
+// running pod.
+type podCreateOptions struct {
+	primary        containerOptions
+	cpu            string
+	memory         string
+	labels         []string
+	configMapNames []string
+	secretNames    []string
+	restartPolicy  string
+	nodeSelector   []string
+	tolerations    []string
+	serviceAccount string
+	containers     []string
+	initContainers []string
+
+	file       string
+	fromPod    string
+	keepLabels bool
+	dryRun     string
+	output     string
 }
 
 func newPodCreateCmd() *cobra.Command {
-	var (
-		name           string
-		image          string
-		cpu            string
-		memory         string
-		envVars        []string
-		labels         []string
-		ports          []string
-		volumeMounts   []string
-		configMapNames []string
-		secretNames    []string
-	)
+	o := &podCreateOptions{}
 
 	cmd := &cobra.Command{
 		Use:   "create",
 		Short: "Create a pod",
+		Long: `Create a pod from flags, from a manifest (-f) or by cloning a running pod
+(--from-pod).
+
+The primary container is described by the top-level flags. Further containers
+are added with repeated --container blocks and init containers with
+--init-container, both written as comma-separated KEY=VALUE pairs:
+
+  name, image, command, arg, image-pull-policy, env, env-from-secret,
+  env-from-configmap, port, mount, request-cpu, limit-cpu, request-memory,
+  limit-memory
+
+Environment values of the form secret:NAME/KEY, configmap:NAME/KEY and
+field:PATH (for example field:status.podIP) become references rather than
+literals. --env takes one variable per flag, so its value may contain
+commas; inside a block, env and arg values may contain commas as well, as
+long as the text after a comma does not start with one of the keys above.
+
+Probes take ACTION:TARGET[,OPTION=SECONDS...] where ACTION is http-get
+(PORT[/PATH]), tcp (PORT), grpc (PORT) or exec (COMMAND) and the options are
+initial-delay, period, timeout, success-threshold and failure-threshold.
+
+With -f or --from-pod, container flags that are given explicitly override the
+default container of the pod, and --label, --node-selector and --toleration
+are merged into it. A cloned pod drops its status, node assignment, owner and
+generated fields, and its labels unless --keep-labels is set, so that the
+original controller does not adopt it.
+
+--dry-run=client prints the pod instead of creating it; combine it with
+-o yaml to author manifests.
+
+Before the pod is sent, it is checked against the resource quotas of the
+namespace with the limit range defaults applied: a container missing a
+request or limit that a quota tracks, or a pod that would exceed what is
+left of a quota, fails with a readable message.`,
+		Example: `  k8s-admin pod create --name web --image nginx:1.27 --port 80 \
+    --request-cpu 100m --limit-cpu 500m --request-memory 64Mi --limit-memory 256Mi \
+    --readiness-probe http-get:80/healthz,period=5 --liveness-probe tcp:80,initial-delay=10 \
+    --container name=exporter,image=nginx/nginx-prometheus-exporter,port=9113 \
+    --init-container name=migrate,image=example/web:1.4,command=/app/migrate,arg=up
+  k8s-admin pod create --name web --image nginx:1.27 --dry-run=client -o yaml > pod.yaml
+  k8s-admin pod create -f pod.yaml
+  k8s-admin pod create --from-pod web-7d9f8b-x2kq --name web-canary --image example/web:1.5`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if name == "" {
-				return fmt.Errorf("pod name is required")
+			if o.file != "" && o.fromPod != "" {
+				return fmt.Errorf("-f and --from-pod cannot be used together")
 			}
-			if image == "" {
-				return fmt.Errorf("container image is required")
+			if err := validateCreateFlags(o.dryRun, o.output); err != nil {
+				return err
 			}
 
-			// Parse environment variables
-			envSlice := []corev1.EnvVar{}
-			for _, env := range envVars {
-				parts := strings.Split(env, "=")
-				if len(parts) != 2 {
-					return fmt.Errorf("invalid environment variable format: %s", env)
+			var pod *corev1.Pod
+			var err error
+			switch {
+			case o.file != "":
+				pod, err = readPodManifest(o.file)
+				if err != nil {
+					return err
 				}
-				envSlice = append(envSlice, corev1.EnvVar{
-					Name:  parts[0],
-					Value: parts[1],
-				})
-			}
-
-			// Parse labels
-			labelMap := make(map[string]string)
-			for _, label := range labels {
-				parts := strings.Split(label, "=")
-				if len(parts) != 2 {
-					return fmt.Errorf("invalid label format: %s", label)
+				if pod.Namespace != "" && cmd.Flag("namespace").Changed && pod.Namespace != namespace {
+					return fmt.Errorf("the namespace in %s (%s) does not match --namespace (%s)", o.file, pod.Namespace, namespace)
 				}
-				labelMap[parts[0]] = parts[1]
-			}
-
-			// Parse ports
-			portSlice := []corev1.ContainerPort{}
-			for _, port := range ports {
-				var containerPort int32
-				var protocol string
-				if strings.Contains(port, "/") {
-					parts := strings.Split(port, "/")
-					fmt.Sscanf(parts[0], "%d", &containerPort)
-					protocol = strings.ToUpper(parts[1])
-				} else {
-					fmt.Sscanf(port, "%d", &containerPort)
-					protocol = "TCP"
+				if err := o.applyOverrides(cmd, pod); err != nil {
+					return err
+				}
+			case o.fromPod != "":
+				clientset, err := getClientset()
+				if err != nil {
+					return err
+				}
+				source, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), o.fromPod, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				pod = clonePod(source, o.keepLabels)
+				pod.Name = source.Name + "-copy"
+				if err := o.applyOverrides(cmd, pod); err != nil {
+					return err
+				}
+			default:
+				pod, err = o.buildPod()
+				if err != nil {
+					return err
 				}
-				portSlice = append(portSlice, corev1.ContainerPort{
-					ContainerPort: containerPort,
-					Protocol:      corev1.Protocol(protocol),
-				})
-			}
-
-			// Create volumes and volume mounts
-			volumes := []corev1.Volume{}
-			volumeMountSlice := []corev1.VolumeMount{}
-
-			// Add ConfigMap volumes
-			for i, configMapName := range configMapNames {
-				volumeName := fmt.Sprintf("config-volume-%d", i)
-				volumes = append(volumes, corev1.Volume{
-					Name: volumeName,
-					VolumeSource: corev1.VolumeSource{
-						ConfigMap: &corev1.ConfigMapVolumeSource{
-							LocalObjectReference: corev1.LocalObjectReference{
-								Name: configMapName,
-							},
-						},
-					},
-				})
-				volumeMountSlice = append(volumeMountSlice, corev1.VolumeMount{
-					Name:      volumeName,
-					MountPath: fmt.Sprintf("/etc/config/%s", configMapName),
-				})
 			}
-
-			// Add Secret volumes
-			for i, secretName := range secretNames {
-				volumeName := fmt.Sprintf("secret-volume-%d", i)
-				volumes = append(volumes, corev1.Volume{
-					Name: volumeName,
-					VolumeSource: corev1.VolumeSource{
-						Secret: &corev1.SecretVolumeSource{
-							SecretName: secretName,
-						},
-					},
-				})
-				volumeMountSlice = append(volumeMountSlice, corev1.VolumeMount{
-					Name:      volumeName,
-					MountPath: fmt.Sprintf("/etc/secrets/%s", secretName),
-				})
+			if pod.Name == "" {
+				return fmt.Errorf("pod name is required")
 			}
-
-			// Parse custom volume mounts
-			for _, mount := range volumeMounts {
-				parts := strings.Split(mount, ":")
-				if len(parts) != 2 {
-					return fmt.Errorf("invalid volume mount format: %s", mount)
-				}
-				volumeName := parts[0]
-				mountPath := parts[1]
-				volumeMountSlice = append(volumeMountSlice, corev1.VolumeMount{
-					Name:      volumeName,
-					MountPath: mountPath,
-				})
+			if pod.Namespace == "" {
+				pod.Namespace = namespace
 			}
 
-			// Create resource requirements
-			resources := corev1.ResourceRequirements{}
-			if cpu != "" || memory != "" {
-				resources.Limits = corev1.ResourceList{}
-				resources.Requests = corev1.ResourceList{}
-
-				if cpu != "" {
-					cpuResource, err := resource.ParseQuantity(cpu)
-					if err != nil {
-						return fmt.Errorf("invalid CPU format: %v", err)
-					}
-					resources.Limits[corev1.ResourceCPU] = cpuResource
-					resources.Requests[corev1.ResourceCPU] = cpuResource
-				}
-
-				if memory != "" {
-					memoryResource, err := resource.ParseQuantity(memory)
-					if err != nil {
-						return fmt.Errorf("invalid memory format: %v", err)
-					}
-					resources.Limits[corev1.ResourceMemory] = memoryResource
-					resources.Requests[corev1.ResourceMemory] = memoryResource
-				}
+			if o.dryRun == "client" {
+				return printObject(pod, o.output)
 			}
 
 			clientset, err := getClientset()
@@ -211,80 +260,222 @@ func newPodCreateCmd() *cobra.Command {
 				return err
 			}
 
-			pod := &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      name,
-					Namespace: namespace,
-					Labels:    labelMap,
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:         name,
-							Image:        image,
-							Env:          envSlice,
-							Ports:        portSlice,
-							VolumeMounts: volumeMountSlice,
-							Resources:    resources,
-						},
-					},
-					Volumes: volumes,
-				},
+			if err := checkPodQuota(clientset, pod); err != nil {
+				return err
 			}
 
-			pod, err = clientset.CoreV1().Pods(namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+			createOptions := metav1.CreateOptions{}
+			if o.dryRun == "server" {
+				createOptions.DryRun = []string{metav1.DryRunAll}
+			}
+			pod, err = clientset.CoreV1().Pods(pod.Namespace).Create(context.TODO(), pod, createOptions)
 			if err != nil {
 				return err
 			}
 
-			fmt.Printf("Pod %s created in namespace %s\n", name, namespace)
+			if o.output != "" {
+				return printObject(pod, o.output)
+			}
+			if o.dryRun == "server" {
+				fmt.Printf("Pod %s validated in namespace %s (server dry run)\n", pod.Name, pod.Namespace)
+				return nil
+			}
+			fmt.Printf("Pod %s created in namespace %s\n", pod.Name, pod.Namespace)
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVar(&name, "name", "", "Name of the pod")
-	cmd.Flags().StringVar(&image, "image", "", "Container image to use")
-	cmd.Flags().StringVar(&cpu, "cpu", "", "CPU resource limit (e.g., '200m' or '0.2')")
-	cmd.Flags().StringVar(&memory, "memory", "", "Memory resource limit (e.g., '128Mi' or '1Gi')")
-	cmd.Flags().StringSliceVar(&envVars, "env", []string{}, "Environment variables (format: KEY=VALUE)")
-	cmd.Flags().StringSliceVar(&labels, "label", []string{}, "Pod labels (format: KEY=VALUE)")
-	cmd.Flags().StringSliceVar(&ports, "port", []string{}, "Container ports (format: PORT[/PROTOCOL])")
-	cmd.Flags().StringSliceVar(&volumeMounts, "volume-mount", []string{}, "Volume mounts (format: VOLUME_NAME:MOUNT_PATH)")
-	cmd.Flags().StringSliceVar(&configMapNames, "configmap", []string{}, "ConfigMap names to mount")
-	cmd.Flags().StringSliceVar(&secretNames, "secret", []string{}, "Secret names to mount")
-
-	cmd.MarkFlagRequired("name")
-	cmd.MarkFlagRequired("image")
+	cmd.Flags().StringVar(&o.primary.name, "name", "", "Name of the pod")
+	cmd.Flags().StringVar(&o.primary.image, "image", "", "Container image to use")
+	cmd.Flags().StringArrayVar(&o.primary.command, "command", []string{}, "Container entrypoint, one element per flag")
+	cmd.Flags().StringArrayVar(&o.primary.args, "args", []string{}, "Arguments to the entrypoint, one element per flag")
+	cmd.Flags().StringVar(&o.primary.imagePullPolicy, "image-pull-policy", "", "Image pull policy (Always, IfNotPresent or Never)")
+	cmd.Flags().StringVar(&o.primary.requestCPU, "request-cpu", "", "CPU request (e.g., '100m')")
+	cmd.Flags().StringVar(&o.primary.limitCPU, "limit-cpu", "", "CPU limit (e.g., '500m' or '0.5')")
+	cmd.Flags().StringVar(&o.primary.requestMemory, "request-memory", "", "Memory request (e.g., '64Mi')")
+	cmd.Flags().StringVar(&o.primary.limitMemory, "limit-memory", "", "Memory limit (e.g., '256Mi' or '1Gi')")
+	cmd.Flags().StringVar(&o.cpu, "cpu", "", "CPU request and limit (e.g., '200m' or '0.2')")
+	cmd.Flags().StringVar(&o.memory, "memory", "", "Memory request and limit (e.g., '128Mi' or '1Gi')")
+	cmd.Flags().StringVar(&o.primary.livenessProbe, "liveness-probe", "", "Liveness probe (format: ACTION:TARGET[,OPTION=SECONDS...])")
+	cmd.Flags().StringVar(&o.primary.readinessProbe, "readiness-probe", "", "Readiness probe (format: ACTION:TARGET[,OPTION=SECONDS...])")
+	cmd.Flags().StringVar(&o.primary.startupProbe, "startup-probe", "", "Startup probe (format: ACTION:TARGET[,OPTION=SECONDS...])")
+	cmd.Flags().StringArrayVar(&o.primary.envVars, "env", []string{}, "Environment variables (format: KEY=VALUE, KEY=secret:NAME/KEY, KEY=configmap:NAME/KEY or KEY=field:PATH)")
+	cmd.Flags().StringArrayVar(&o.primary.envFromSecrets, "env-from-secret", []string{}, "Import every key of a Secret as environment variables (format: NAME[:PREFIX])")
+	cmd.Flags().StringArrayVar(&o.primary.envFromConfigMaps, "env-from-configmap", []string{}, "Import every key of a ConfigMap as environment variables (format: NAME[:PREFIX])")
+	cmd.Flags().StringSliceVar(&o.labels, "label", []string{}, "Pod labels (format: KEY=VALUE)")
+	cmd.Flags().StringSliceVar(&o.primary.ports, "port", []string{}, "Container ports (format: PORT[/PROTOCOL])")
+	cmd.Flags().StringSliceVar(&o.primary.volumeMounts, "volume-mount", []string{}, "Volume mounts (format: VOLUME_NAME:MOUNT_PATH)")
+	cmd.Flags().StringSliceVar(&o.configMapNames, "configmap", []string{}, "ConfigMap names to mount")
+	cmd.Flags().StringSliceVar(&o.secretNames, "secret", []string{}, "Secret names to mount")
+	cmd.Flags().StringVar(&o.restartPolicy, "restart", "", "Restart policy (Always, OnFailure or Never)")
+	cmd.Flags().StringSliceVar(&o.nodeSelector, "node-selector", []string{}, "Node selector (format: KEY=VALUE)")
+	cmd.Flags().StringArrayVar(&o.tolerations, "toleration", []string{}, "Tolerations (format: KEY[=VALUE][:EFFECT], or * for all taints)")
+	cmd.Flags().StringVar(&o.serviceAccount, "service-account", "", "Service account the pod runs as")
+	cmd.Flags().StringArrayVar(&o.containers, "container", []string{}, "Additional container (format: name=NAME,image=IMAGE[,KEY=VALUE...])")
+	cmd.Flags().StringArrayVar(&o.initContainers, "init-container", []string{}, "Init container (format: name=NAME,image=IMAGE[,KEY=VALUE...])")
+	cmd.Flags().StringVarP(&o.file, "filename", "f", "", "Create the pod from a YAML or JSON manifest ('-' reads stdin)")
+	cmd.Flags().StringVar(&o.fromPod, "from-pod", "", "Clone the spec of an existing pod, applying the given flags as overrides")
+	cmd.Flags().BoolVar(&o.keepLabels, "keep-labels", false, "Keep the labels of the pod cloned with --from-pod")
+	cmd.Flags().StringVar(&o.dryRun, "dry-run", "none", "Only print (client) or validate on the server (server) instead of creating")
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = "client"
+	cmd.Flags().StringVarP(&o.output, "output", "o", "", "Print the pod as yaml or json")
 
 	return cmd
 }
 
-func newPodDeleteCmd() *cobra.Command {
-	var name string
-	cmd := &cobra.Command{
-		Use:   "delete",
-		Short: "Delete a pod",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			if name == "" {
-				return fmt.Errorf("pod name is required")
-			}
+// buildPod assembles a pod entirely from flags.
+func (o *podCreateOptions) buildPod() (*corev1.Pod, error) {
+	if o.primary.name == "" {
+		return nil, fmt.Errorf("pod name is required")
+	}
+	if o.primary.image == "" {
+		return nil, fmt.Errorf("container image is required")
+	}
+	o.applyLegacyResources()
 
-			clientset, err := getClientset()
-			if err != nil {
-				return err
-			}
+	labelMap, err := parseKeyValues("label", o.labels)
+	if err != nil {
+		return nil, err
+	}
+	nodeSelectorMap, err := parseKeyValues("node-selector", o.nodeSelector)
+	if err != nil {
+		return nil, err
+	}
 
-			err = clientset.CoreV1().Pods(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
-			if err != nil {
-				return err
-			}
+	// Create volumes and volume mounts
+	volumes := []corev1.Volume{}
+	volumeMountSlice := []corev1.VolumeMount{}
+
+	// Add ConfigMap volumes
+	for i, configMapName := range o.configMapNames {
+		volumeName := fmt.Sprintf("config-volume-%d", i)
+		volumes = append(volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: configMapName,
+					},
+				},
+			},
+		})
+		volumeMountSlice = append(volumeMountSlice, corev1.VolumeMount{
+			Name:      volumeName,
+			MountPath: fmt.Sprintf("/etc/config/%s", configMapName),
+		})
+	}
 
-			fmt.Printf("Pod %s deleted from namespace %s\n", name, namespace)
-			return nil
+	// Add Secret volumes
+	for i, secretName := range o.secretNames {
+		volumeName := fmt.Sprintf("secret-volume-%d", i)
+		volumes = append(volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: secretName,
+				},
+			},
+		})
+		volumeMountSlice = append(volumeMountSlice, corev1.VolumeMount{
+			Name:      volumeName,
+			MountPath: fmt.Sprintf("/etc/secrets/%s", secretName),
+		})
+	}
+
+	mainContainer, err := o.primary.build()
+	if err != nil {
+		return nil, err
+	}
+	mainContainer.VolumeMounts = append(volumeMountSlice, mainContainer.VolumeMounts...)
+
+	podSpec := corev1.PodSpec{
+		Containers:         []corev1.Container{mainContainer},
+		Volumes:            volumes,
+		NodeSelector:       nodeSelectorMap,
+		ServiceAccountName: o.serviceAccount,
+	}
+
+	for _, block := range o.containers {
+		opts, err := parseContainerBlock("container", block)
+		if err != nil {
+			return nil, err
+		}
+		container, err := opts.build()
+		if err != nil {
+			return nil, err
+		}
+		podSpec.Containers = append(podSpec.Containers, container)
+	}
+	for _, block := range o.initContainers {
+		opts, err := parseContainerBlock("init-container", block)
+		if err != nil {
+			return nil, err
+		}
+		container, err := opts.build()
+		if err != nil {
+			return nil, err
+		}
+		podSpec.InitContainers = append(podSpec.InitContainers, container)
+	}
+
+	if err := checkDuplicateContainers(&podSpec); err != nil {
+		return nil, err
+	}
+
+	if o.restartPolicy != "" {
+		policy, err := parseRestartPolicy(o.restartPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --restart: %v", err)
+		}
+		podSpec.RestartPolicy = policy
+	}
+
+	for _, value := range o.tolerations {
+		toleration, err := parseToleration(value)
+		if err != nil {
+			return nil, err
+		}
+		podSpec.Tolerations = append(podSpec.Tolerations, toleration)
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      o.primary.name,
+			Namespace: namespace,
+			Labels:    labelMap,
 		},
+		Spec: podSpec,
+	}, nil
+}
+
+// applyLegacyResources maps --cpu and --memory, which predate the split
+// flags and set both the request and the limit.
+func (o *podCreateOptions) applyLegacyResources() {
+	if o.cpu != "" {
+		if o.primary.requestCPU == "" {
+			o.primary.requestCPU = o.cpu
+		}
+		if o.primary.limitCPU == "" {
+			o.primary.limitCPU = o.cpu
+		}
+	}
+	if o.memory != "" {
+		if o.primary.requestMemory == "" {
+			o.primary.requestMemory = o.memory
+		}
+		if o.primary.limitMemory == "" {
+			o.primary.limitMemory = o.memory
+		}
 	}
+}
 
-	cmd.Flags().StringVar(&name, "name", "", "name of the pod")
-	cmd.MarkFlagRequired("name")
-	return cmd
+func checkDuplicateContainers(spec *corev1.PodSpec) error {
+	seen := map[string]bool{}
+	for _, c := range append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...) {
+		if seen[c.Name] {
+			return fmt.Errorf("duplicate container name %s", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return nil
 }
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/k8s-admin-cli/remote"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// podPath is the remote side of a copy, written as [NAMESPACE/]POD:PATH.
+type podPath struct {
+	namespace string
+	pod       string
+	path      string
+}
+
+// podCopier copies files between the local machine and a container by
+// running tar (or cat/tee) through the exec subresource.
+type podCopier struct {
+	config     *rest.Config
+	clientset  *kubernetes.Clientset
+	container  string
+	noPreserve bool
+}
+
+func newPodCpCmd() *cobra.Command {
+	var (
+		container  string
+		noTar      bool
+		noPreserve bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cp <src> <dest>",
+		Short: "Copy files and directories to and from containers",
+		Long: `Copy files and directories between the local machine and a container.
+One side is a local path, the other is [NAMESPACE/]POD:PATH.
+
+Directories are copied recursively by streaming a tar archive, which
+requires a tar binary in the container. For images without tar, --no-tar
+copies a single file by streaming it through cat (download) or tee (upload);
+file permissions are not preserved in that mode.`,
+		Example: `  k8s-admin pod cp my-pod:/var/log/app ./logs
+  k8s-admin pod cp ./config.yaml my-pod:/etc/app/config.yaml -c app
+  k8s-admin pod cp --no-tar my-pod:/data/dump.bin ./dump.bin`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, srcRemote := parseCopyPath(args[0])
+			dest, destRemote := parseCopyPath(args[1])
+			if (srcRemote == nil) == (destRemote == nil) {
+				return fmt.Errorf("exactly one of source and destination must be POD:PATH")
+			}
+
+			config, err := getRestConfig()
+			if err != nil {
+				return err
+			}
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			c := &podCopier{
+				config:     config,
+				clientset:  clientset,
+				container:  container,
+				noPreserve: noPreserve,
+			}
+
+			if srcRemote != nil {
+				if err := c.resolveContainer(srcRemote); err != nil {
+					return err
+				}
+				if noTar {
+					return c.downloadFile(srcRemote, dest)
+				}
+				return c.download(srcRemote, dest)
+			}
+
+			if err := c.resolveContainer(destRemote); err != nil {
+				return err
+			}
+			if noTar {
+				return c.uploadFile(src, destRemote)
+			}
+			return c.upload(src, destRemote)
+		},
+	}
+
+	cmd.Flags().StringVarP(&container, "container", "c", "", "container name (defaults to the pod's default container)")
+	cmd.Flags().BoolVar(&noTar, "no-tar", false, "copy a single file with cat/tee instead of tar")
+	cmd.Flags().BoolVar(&noPreserve, "no-preserve", false, "do not preserve file permissions and modification times")
+	return cmd
+}
+
+// parseCopyPath returns the remote location for POD:PATH arguments, or the
+// argument itself when it is a local path. Windows drive letters such as
+// C:\ are treated as local.
+func parseCopyPath(arg string) (string, *podPath) {
+	if len(arg) >= 2 && arg[1] == ':' && (len(arg) == 2 || arg[2] == '\\' || arg[2] == '/') {
+		return arg, nil
+	}
+
+	target, p, ok := strings.Cut(arg, ":")
+	if !ok || target == "" || strings.HasPrefix(arg, ".") || strings.HasPrefix(arg, "/") {
+		return arg, nil
+	}
+
+	ns, pod := namespace, target
+	if before, after, ok := strings.Cut(target, "/"); ok {
+		ns, pod = before, after
+	}
+	if p == "" {
+		p = "."
+	}
+	return "", &podPath{namespace: ns, pod: pod, path: p}
+}
+
+func (c *podCopier) resolveContainer(p *podPath) error {
+	pod, err := c.clientset.CoreV1().Pods(p.namespace).Get(context.TODO(), p.pod, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if c.container == "" {
+		c.container = inspector.DefaultContainer(pod)
+	} else if !podHasContainer(pod, c.container) {
+		return fmt.Errorf("container %s not found in pod %s", c.container, p.pod)
+	}
+	return nil
+}
+
+// exec runs command in the target container, returning the container's
+// stderr in the error when it fails.
+func (c *podCopier) exec(p *podPath, command []string, stdin io.Reader, stdout io.Writer) error {
+	var stderr bytes.Buffer
+	err := remote.Exec(context.Background(), c.config, c.clientset, remote.StreamOptions{
+		Namespace: p.namespace,
+		Pod:       p.pod,
+		Container: c.container,
+		Command:   command,
+		Stdin:     stdin,
+		Stdout:    stdout,
+		Stderr:    &stderr,
+	})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%v: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// upload streams src as a tar archive whose root entry is named after the
+// destination, and unpacks it in the destination's parent directory.
+func (c *podCopier) upload(src string, dest *podPath) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	total, err := localSize(src)
+	if err != nil {
+		return err
+	}
+
+	// A trailing slash copies into the directory rather than onto the path.
+	destDir, destBase := path.Split(path.Clean(dest.path))
+	if strings.HasSuffix(dest.path, "/") {
+		destDir, destBase = dest.path, filepath.Base(src)
+	}
+	if destDir == "" {
+		destDir = "."
+	}
+
+	command := []string{"tar", "-xmf", "-", "-C", destDir}
+	if c.noPreserve {
+		command = []string{"tar", "--no-same-permissions", "--no-same-owner", "-xmf", "-", "-C", destDir}
+	}
+
+	progress := newProgressWriter(fmt.Sprintf("%s -> %s:%s", src, dest.pod, dest.path), total)
+	reader, writer := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(writer)
+		err := writeTar(tw, src, destBase, info, progress)
+		if err == nil {
+			err = tw.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	err = c.exec(dest, command, reader, io.Discard)
+	reader.Close()
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("error copying to %s:%s: %v", dest.pod, dest.path, err)
+	}
+
+	fmt.Printf("Copied %s to %s:%s\n", src, dest.pod, dest.path)
+	return nil
+}
+
+// writeTar adds src and, for directories, everything below it to tw under
+// the archive name base.
+func writeTar(tw *tar.Writer, src, base string, info fs.FileInfo, progress io.Writer) error {
+	if !info.IsDir() {
+		return writeTarFile(tw, src, base, info, progress)
+	}
+
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		name := path.Join(base, filepath.ToSlash(rel))
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return writeTarFile(tw, p, name, info, progress)
+	})
+}
+
+func writeTarFile(tw *tar.Writer, src, name string, info fs.FileInfo, progress io.Writer) error {
+	link := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		link = target
+	}
+
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(io.MultiWriter(tw, progress), f)
+	return err
+}
+
+// download asks the container for a tar archive of src and unpacks it at
+// dest, renaming the archive's root entry to dest. An existing local
+// directory receives a copy named after src.
+func (c *podCopier) download(src *podPath, dest string) error {
+	srcPath := path.Clean(src.path)
+	srcDir, srcBase := path.Split(srcPath)
+	if srcDir == "" {
+		srcDir = "."
+	}
+	if srcBase == "" || srcBase == "." {
+		// "." or "/": archive the directory's contents, which are copied
+		// into dest.
+		srcDir, srcBase = srcPath, "."
+	}
+
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		dest = filepath.Join(dest, srcBase)
+	}
+
+	progress := newProgressWriter(fmt.Sprintf("%s:%s -> %s", src.pod, src.path, dest), c.remoteSize(src))
+	reader, writer := io.Pipe()
+	errCh := make(chan error, 1)
+	go func() {
+		err := c.exec(src, []string{"tar", "cf", "-", "-C", srcDir, srcBase}, nil, writer)
+		writer.CloseWithError(err)
+		errCh <- err
+	}()
+
+	err := extractTar(io.TeeReader(reader, progress), srcBase, dest, !c.noPreserve)
+	reader.CloseWithError(err)
+	progress.Done()
+	if execErr := <-errCh; execErr != nil {
+		return fmt.Errorf("error copying from %s:%s: %v", src.pod, src.path, execErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Copied %s:%s to %s\n", src.pod, src.path, dest)
+	return nil
+}
+
+// extractTar writes the entries of an archive rooted at prefix into dest;
+// a prefix of "." takes every entry. Entries that would escape dest and
+// symlinks are skipped. An archive with nothing to extract is an error, so
+// that an empty copy does not pass for a successful one.
+func extractTar(r io.Reader, prefix, dest string, preserve bool) error {
+	tr := tar.NewReader(r)
+	dest = filepath.Clean(dest)
+	extracted := 0
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			if extracted == 0 {
+				return fmt.Errorf("nothing was copied: the archive has no entries under %s", prefix)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading archive: %v", err)
+		}
+
+		name := path.Clean(hdr.Name)
+		rel := name
+		if prefix != "." {
+			if name != prefix && !strings.HasPrefix(name, prefix+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(strings.TrimPrefix(name, prefix), "/")
+		} else if name == "." {
+			rel = ""
+		}
+		target := filepath.Join(dest, filepath.FromSlash(rel))
+		if target != dest && !strings.HasPrefix(target, dest+string(filepath.Separator)) {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: outside of the destination\n", hdr.Name)
+			continue
+		}
+
+		mode := fs.FileMode(0o644)
+		if preserve {
+			mode = hdr.FileInfo().Mode().Perm()
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if !preserve {
+				mode = 0o755
+			}
+			if err := os.MkdirAll(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		case tar.TypeSymlink, tar.TypeLink:
+			fmt.Fprintf(os.Stderr, "Warning: skipping link %s -> %s\n", hdr.Name, hdr.Linkname)
+			continue
+		default:
+			continue
+		}
+		extracted++
+
+		if preserve {
+			os.Chmod(target, mode)
+			os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+		}
+	}
+}
+
+// downloadFile copies a single file with cat.
+func (c *podCopier) downloadFile(src *podPath, dest string) error {
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		dest = filepath.Join(dest, path.Base(src.path))
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	progress := newProgressWriter(fmt.Sprintf("%s:%s -> %s", src.pod, src.path, dest), c.remoteSize(src))
+	err = c.exec(src, []string{"cat", src.path}, nil, io.MultiWriter(f, progress))
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("error copying from %s:%s: %v", src.pod, src.path, err)
+	}
+
+	fmt.Printf("Copied %s:%s to %s\n", src.pod, src.path, dest)
+	return nil
+}
+
+// uploadFile copies a single file with tee, which unlike cat can write to a
+// path without a shell for redirection.
+func (c *podCopier) uploadFile(src string, dest *podPath) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("--no-tar can only copy single files; %s is a directory", src)
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	progress := newProgressWriter(fmt.Sprintf("%s -> %s:%s", src, dest.pod, dest.path), info.Size())
+	err = c.exec(dest, []string{"tee", dest.path}, io.TeeReader(f, progress), io.Discard)
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("error copying to %s:%s: %v", dest.pod, dest.path, err)
+	}
+
+	fmt.Printf("Copied %s to %s:%s\n", src, dest.pod, dest.path)
+	return nil
+}
+
+// remoteSize estimates the size of a remote path with du so the progress bar
+// can show a percentage. It returns 0 when du is unavailable.
+func (c *podCopier) remoteSize(p *podPath) int64 {
+	var out bytes.Buffer
+	if err := c.exec(p, []string{"du", "-sk", p.path}, nil, &out); err != nil {
+		return 0
+	}
+	fields := strings.Fields(out.String())
+	if len(fields) == 0 {
+		return 0
+	}
+	kb, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return kb * 1024
+}
+
+// localSize sums the sizes of the regular files under p.
+func localSize(p string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(p, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
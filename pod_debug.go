@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/k8s-admin-cli/remote"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+func newPodDebugCmd() *cobra.Command {
+	var (
+		image     string
+		target    string
+		container string
+		attach    bool
+		timeout   time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "debug <name> [-- command [args...]]",
+		Short: "Add an ephemeral debug container to a running pod",
+		Long: `Add an ephemeral container to a running pod through the
+pods/ephemeralcontainers subresource and attach to it. With --target the
+debug container shares the process namespace of that container, which is
+useful for inspecting distroless images.
+
+Ephemeral containers cannot be removed; they stay in the pod spec until the
+pod is deleted.`,
+		Example: `  k8s-admin pod debug my-pod --image busybox --target app
+  k8s-admin pod debug my-pod --image nicolaka/netshoot -- tcpdump -i any`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.ArgsLenAtDash() > 1 || (cmd.ArgsLenAtDash() == -1 && len(args) > 1) {
+				return fmt.Errorf("usage: pod debug <name> [-- command [args...]]")
+			}
+			podName := args[0]
+			command := args[1:]
+
+			config, err := getRestConfig()
+			if err != nil {
+				return err
+			}
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if target != "" && !podHasContainer(pod, target) {
+				return fmt.Errorf("target container %s not found in pod %s", target, podName)
+			}
+
+			if container == "" {
+				container = "debugger-" + utilrand.String(5)
+			}
+			if podHasContainer(pod, container) {
+				return fmt.Errorf("pod %s already has a container named %s", podName, container)
+			}
+
+			tty := attach && remote.IsTerminal(os.Stdin)
+			pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+				EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+					Name:                     container,
+					Image:                    image,
+					Command:                  command,
+					ImagePullPolicy:          corev1.PullIfNotPresent,
+					Stdin:                    attach,
+					TTY:                      tty,
+					TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+				},
+				TargetContainerName: target,
+			})
+
+			_, err = clientset.CoreV1().Pods(namespace).UpdateEphemeralContainers(context.TODO(), podName, pod, metav1.UpdateOptions{})
+			if err != nil {
+				return fmt.Errorf("error adding ephemeral container: %v", err)
+			}
+			fmt.Printf("Ephemeral container %s added to pod %s\n", container, podName)
+
+			if !attach {
+				return nil
+			}
+
+			fmt.Printf("Waiting for container %s to start...\n", container)
+			if err := waitForContainerRunning(clientset, namespace, podName, container, timeout); err != nil {
+				return err
+			}
+
+			fmt.Println("If you don't see a command prompt, try pressing enter.")
+			return exitWithRemoteStatus(remote.Attach(context.Background(), config, clientset, remote.StreamOptions{
+				Namespace: namespace,
+				Pod:       podName,
+				Container: container,
+				Stdin:     os.Stdin,
+				Stdout:    os.Stdout,
+				Stderr:    os.Stderr,
+				TTY:       tty,
+			}))
+		},
+	}
+
+	cmd.Flags().StringVar(&image, "image", "busybox", "image of the debug container")
+	cmd.Flags().StringVar(&target, "target", "", "container whose process namespace the debug container joins")
+	cmd.Flags().StringVarP(&container, "container", "c", "", "name of the debug container (generated by default)")
+	cmd.Flags().BoolVar(&attach, "attach", true, "attach to the debug container once it is running")
+	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "how long to wait for the debug container to start")
+	return cmd
+}
+
+// waitForContainerRunning polls the pod until the named container, which may
+// be a regular, init or ephemeral container, is running.
+func waitForContainerRunning(clientset *kubernetes.Clientset, ns, podName, container string, timeout time.Duration) error {
+	err := wait.PollUntilContextTimeout(context.Background(), time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		pod, err := clientset.CoreV1().Pods(ns).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			return false, fmt.Errorf("pod %s is %s", podName, pod.Status.Phase)
+		}
+
+		statuses := append(append(append([]corev1.ContainerStatus{},
+			pod.Status.ContainerStatuses...),
+			pod.Status.InitContainerStatuses...),
+			pod.Status.EphemeralContainerStatuses...)
+		for _, status := range statuses {
+			if status.Name != container {
+				continue
+			}
+			if status.State.Running != nil {
+				return true, nil
+			}
+			if t := status.State.Terminated; t != nil {
+				return false, fmt.Errorf("container %s terminated: %s (exit code %d)", container, t.Reason, t.ExitCode)
+			}
+			if w := status.State.Waiting; w != nil && (w.Reason == "ErrImagePull" || w.Reason == "ImagePullBackOff" || w.Reason == "InvalidImageName") {
+				return false, fmt.Errorf("container %s cannot start: %s: %s", container, w.Reason, w.Message)
+			}
+		}
+		return false, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("timed out waiting for container %s to start", container)
+	}
+	return err
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// podOwner is the top-level controller of a pod, e.g. the Deployment above
+// its ReplicaSet.
+type podOwner struct {
+	Kind  string
+	Name  string
+	Chain string
+}
+
+func newPodDeleteCmd() *cobra.Command {
+	var (
+		name        string
+		selector    string
+		gracePeriod int64
+		force       bool
+		evict       bool
+		deleteOwner bool
+		yes         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "delete [name]",
+		Short: "Delete a pod",
+		Long: `Delete a pod, or every pod matching a label selector.
+
+Pods that belong to a controller are recreated by it; delete warns about this
+and --delete-owner deletes the top-level controller (for example the
+Deployment rather than its ReplicaSet) instead.
+
+--evict goes through the Eviction API, which respects PodDisruptionBudgets;
+an eviction that a budget blocks is reported together with that budget.`,
+		Example: `  k8s-admin pod delete my-pod
+  k8s-admin pod delete -l app=web --grace-period 5
+  k8s-admin pod delete my-pod --evict
+  k8s-admin pod delete web-7d9f8b-x2kq --delete-owner`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				if name != "" && name != args[0] {
+					return fmt.Errorf("pod name given both as an argument and with --name")
+				}
+				name = args[0]
+			}
+			if name == "" && selector == "" {
+				return fmt.Errorf("pod name or --selector is required")
+			}
+			if name != "" && selector != "" {
+				return fmt.Errorf("specify either a pod name or --selector, not both")
+			}
+			if evict && deleteOwner {
+				return fmt.Errorf("--evict and --delete-owner cannot be used together")
+			}
+			if force {
+				if cmd.Flags().Changed("grace-period") && gracePeriod != 0 {
+					return fmt.Errorf("--force requires --grace-period=0")
+				}
+				gracePeriod = 0
+				fmt.Fprintln(os.Stderr, "Warning: immediate deletion does not wait for confirmation that the running containers have been terminated")
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			var pods []corev1.Pod
+			if name != "" {
+				pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				pods = append(pods, *pod)
+			} else {
+				list, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+				if err != nil {
+					return err
+				}
+				if len(list.Items) == 0 {
+					fmt.Printf("No pods match %s in namespace %s\n", selector, namespace)
+					return nil
+				}
+				pods = list.Items
+			}
+
+			owners := make([]*podOwner, len(pods))
+			for i := range pods {
+				owners[i] = topPodOwner(clientset, &pods[i])
+			}
+
+			if deleteOwner {
+				return deletePodOwners(clientset, pods, owners, yes)
+			}
+
+			if selector != "" {
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "NAME\tSTATUS\tNODE\tCONTROLLED BY")
+				for i, pod := range pods {
+					controlledBy := "<none>"
+					if owners[i] != nil {
+						controlledBy = owners[i].Chain
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", pod.Name, pod.Status.Phase, pod.Spec.NodeName, controlledBy)
+				}
+				w.Flush()
+
+				action := "Delete"
+				if evict {
+					action = "Evict"
+				}
+				ok, err := confirm(fmt.Sprintf("%s %d pod(s) in namespace %s?", action, len(pods), namespace), yes)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Println("Delete cancelled")
+					return nil
+				}
+			}
+
+			deleteOptions := metav1.DeleteOptions{}
+			if cmd.Flags().Changed("grace-period") || force {
+				deleteOptions.GracePeriodSeconds = &gracePeriod
+			}
+
+			var failed []string
+			for i, pod := range pods {
+				if owners[i] != nil && name != "" {
+					fmt.Fprintf(os.Stderr, "Warning: pod %s is managed by %s and will be recreated; use --delete-owner to remove the controller instead\n", pod.Name, owners[i].Chain)
+				}
+
+				if evict {
+					err = evictPod(clientset, &pod, deleteOptions)
+				} else {
+					err = clientset.CoreV1().Pods(namespace).Delete(context.TODO(), pod.Name, deleteOptions)
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					failed = append(failed, pod.Name)
+					continue
+				}
+
+				if evict {
+					fmt.Printf("Pod %s evicted from namespace %s\n", pod.Name, namespace)
+				} else {
+					fmt.Printf("Pod %s deleted from namespace %s\n", pod.Name, namespace)
+				}
+			}
+
+			if len(failed) > 0 {
+				return fmt.Errorf("failed to remove %d pod(s): %s", len(failed), strings.Join(failed, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "name of the pod")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector to choose pods (e.g. app=web)")
+	cmd.Flags().Int64Var(&gracePeriod, "grace-period", -1, "seconds to give the pod to terminate (default: the pod's own setting)")
+	cmd.Flags().BoolVar(&force, "force", false, "delete immediately without waiting for the containers to stop (implies --grace-period=0)")
+	cmd.Flags().BoolVar(&evict, "evict", false, "use the Eviction API so that PodDisruptionBudgets are respected")
+	cmd.Flags().BoolVar(&deleteOwner, "delete-owner", false, "delete the controller that owns the pod instead of the pod")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	return cmd
+}
+
+// topPodOwner returns the outermost controller of pod, or nil for a bare pod.
+func topPodOwner(clientset *kubernetes.Clientset, pod *corev1.Pod) *podOwner {
+	chain := inspector.OwnerChain(clientset, pod.Namespace, pod.OwnerReferences)
+	if len(chain) == 0 {
+		return nil
+	}
+	kind, name, _ := strings.Cut(chain[len(chain)-1], "/")
+	return &podOwner{Kind: kind, Name: name, Chain: strings.Join(chain, " -> ")}
+}
+
+// deletePodOwners deletes the distinct top-level controllers of pods after
+// confirmation. Dependents are removed in the background.
+func deletePodOwners(clientset *kubernetes.Clientset, pods []corev1.Pod, owners []*podOwner, yes bool) error {
+	var targets []podOwner
+	seen := map[string]bool{}
+	for i, owner := range owners {
+		if owner == nil {
+			fmt.Fprintf(os.Stderr, "Warning: pod %s has no controller; skipping\n", pods[i].Name)
+			continue
+		}
+		id := owner.Kind + "/" + owner.Name
+		if !seen[id] {
+			seen[id] = true
+			targets = append(targets, *owner)
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no controllers to delete")
+	}
+
+	fmt.Println("The following controllers and all of their pods will be deleted:")
+	for _, t := range targets {
+		fmt.Printf("  %s/%s\n", t.Kind, t.Name)
+	}
+	ok, err := confirm(fmt.Sprintf("Delete %d controller(s) in namespace %s?", len(targets), namespace), yes)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Delete cancelled")
+		return nil
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	opts := metav1.DeleteOptions{PropagationPolicy: &propagation}
+	ctx := context.TODO()
+	for _, t := range targets {
+		switch t.Kind {
+		case "Deployment":
+			err = clientset.AppsV1().Deployments(namespace).Delete(ctx, t.Name, opts)
+		case "ReplicaSet":
+			err = clientset.AppsV1().ReplicaSets(namespace).Delete(ctx, t.Name, opts)
+		case "StatefulSet":
+			err = clientset.AppsV1().StatefulSets(namespace).Delete(ctx, t.Name, opts)
+		case "DaemonSet":
+			err = clientset.AppsV1().DaemonSets(namespace).Delete(ctx, t.Name, opts)
+		case "Job":
+			err = clientset.BatchV1().Jobs(namespace).Delete(ctx, t.Name, opts)
+		case "CronJob":
+			err = clientset.BatchV1().CronJobs(namespace).Delete(ctx, t.Name, opts)
+		case "ReplicationController":
+			err = clientset.CoreV1().ReplicationControllers(namespace).Delete(ctx, t.Name, opts)
+		default:
+			err = fmt.Errorf("deleting a %s is not supported", t.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("error deleting %s/%s: %v", t.Kind, t.Name, err)
+		}
+		fmt.Printf("%s %s deleted from namespace %s\n", t.Kind, t.Name, namespace)
+	}
+	return nil
+}
+
+// evictPod evicts pod through the Eviction API. When a PodDisruptionBudget
+// blocks the eviction the returned error names the budgets covering the pod.
+func evictPod(clientset *kubernetes.Clientset, pod *corev1.Pod, opts metav1.DeleteOptions) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: &opts,
+	}
+	err := clientset.CoreV1().Pods(pod.Namespace).EvictV1(context.TODO(), eviction)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsTooManyRequests(err) {
+		return fmt.Errorf("error evicting pod %s: %v", pod.Name, err)
+	}
+
+	budgets, listErr := clientset.PolicyV1().PodDisruptionBudgets(pod.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if listErr != nil {
+		return fmt.Errorf("eviction of pod %s is blocked: %v", pod.Name, err)
+	}
+
+	var blocking []string
+	for _, pdb := range budgets.Items {
+		// In policy/v1 a nil selector matches no pods and an empty one
+		// matches every pod in the namespace.
+		if pdb.Spec.Selector == nil {
+			continue
+		}
+		sel, selErr := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if selErr != nil || !sel.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		blocking = append(blocking, fmt.Sprintf("%s (healthy %d/%d desired, %d disruptions allowed)",
+			pdb.Name, pdb.Status.CurrentHealthy, pdb.Status.DesiredHealthy, pdb.Status.DisruptionsAllowed))
+	}
+	if len(blocking) == 0 {
+		return fmt.Errorf("eviction of pod %s is blocked: %v", pod.Name, err)
+	}
+	return fmt.Errorf("eviction of pod %s is blocked by PodDisruptionBudget %s", pod.Name, strings.Join(blocking, ", "))
+}
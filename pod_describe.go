@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+)
+
+func newPodDescribeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "describe <name>",
+		Short: "Show detailed information about a pod",
+		Long: `Show a pod's containers and init containers with their states, exit codes,
+restart counts, probes and resources, along with its QoS class, volumes,
+conditions, tolerations, node selector, owner chain and events.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			description, err := inspector.DescribePod(clientset, namespace, args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(description)
+			return nil
+		},
+	}
+}
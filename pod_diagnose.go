@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+func newPodDiagnoseCmd() *cobra.Command {
+	var (
+		selector string
+		logLines int64
+		all      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diagnose [name]",
+		Short: "Explain why a pod is failing",
+		Long: `Analyze a pod, or every pod matching a label selector, and explain the likely
+cause of any failure in plain language. The checks cover:
+
+  - CrashLoopBackOff, with the last exit code and log lines
+  - ImagePullBackOff, with registry and pull secret hints
+  - OOMKilled, comparing the memory limit with current usage
+  - Pending pods, decoding FailedScheduling events
+  - Containers failing their readiness probe
+  - ConfigMaps, Secrets and keys that the pod references but do not exist
+  - Init containers stuck in a restart loop
+
+With a selector, healthy pods are summarized on one line unless --all is set.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && selector == "" {
+				return fmt.Errorf("pod name or --selector is required")
+			}
+			if len(args) == 1 && selector != "" {
+				return fmt.Errorf("specify either a pod name or --selector, not both")
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			var pods []corev1.Pod
+			if len(args) == 1 {
+				pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				pods = append(pods, *pod)
+			} else {
+				list, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+				if err != nil {
+					return err
+				}
+				if len(list.Items) == 0 {
+					fmt.Printf("No pods match %s in namespace %s\n", selector, namespace)
+					return nil
+				}
+				pods = list.Items
+			}
+
+			opts := inspector.DiagnoseOptions{LogLines: logLines}
+			// Memory usage is a nice-to-have for OOMKilled findings; the
+			// diagnosis still works without metrics-server.
+			if config, err := getRestConfig(); err == nil {
+				if metrics, err := versioned.NewForConfig(config); err == nil {
+					opts.Metrics = metrics
+				}
+			}
+
+			healthy := 0
+			for i := range pods {
+				pod := &pods[i]
+				findings, err := inspector.DiagnosePod(clientset, pod, opts)
+				if err != nil {
+					return err
+				}
+				if len(findings) == 0 && selector != "" && !all {
+					healthy++
+					continue
+				}
+				fmt.Print(inspector.FormatDiagnosis(pod, findings))
+				fmt.Println()
+			}
+			if healthy > 0 {
+				fmt.Printf("%d of %d pods have no problems.\n", healthy, len(pods))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector to choose pods (e.g. app=web)")
+	cmd.Flags().Int64Var(&logLines, "tail", 10, "log lines to show from the previous run of a crashing container")
+	cmd.Flags().BoolVar(&all, "all", false, "with --selector, also print pods without problems")
+	return cmd
+}
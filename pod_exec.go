@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/k8s-admin-cli/remote"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/exec"
+)
+
+func newPodExecCmd() *cobra.Command {
+	var (
+		container string
+		stdin     bool
+		tty       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "exec <name> -- <command> [args...]",
+		Short: "Run a command in a pod container",
+		Long: `Run a command inside a running container. Use -it for an interactive
+shell; terminal resizes are forwarded to the container.`,
+		Example: `  k8s-admin pod exec my-pod -- ls /app
+  k8s-admin pod exec my-pod -c sidecar -it -- /bin/sh`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.ArgsLenAtDash() != 1 {
+				return fmt.Errorf("usage: pod exec <name> -- <command> [args...]")
+			}
+
+			config, err := getRestConfig()
+			if err != nil {
+				return err
+			}
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			opts, err := remoteStreamOptions(clientset, args[0], container, stdin, tty)
+			if err != nil {
+				return err
+			}
+			opts.Command = args[1:]
+
+			return exitWithRemoteStatus(remote.Exec(context.Background(), config, clientset, opts))
+		},
+	}
+
+	cmd.Flags().StringVarP(&container, "container", "c", "", "container name (defaults to the pod's default container)")
+	cmd.Flags().BoolVarP(&stdin, "stdin", "i", false, "pass stdin to the container")
+	cmd.Flags().BoolVarP(&tty, "tty", "t", false, "allocate a TTY")
+	return cmd
+}
+
+func newPodAttachCmd() *cobra.Command {
+	var (
+		container string
+		stdin     bool
+		tty       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "attach <name>",
+		Short: "Attach to the main process of a running container",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := getRestConfig()
+			if err != nil {
+				return err
+			}
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			opts, err := remoteStreamOptions(clientset, args[0], container, stdin, tty)
+			if err != nil {
+				return err
+			}
+
+			return exitWithRemoteStatus(remote.Attach(context.Background(), config, clientset, opts))
+		},
+	}
+
+	cmd.Flags().StringVarP(&container, "container", "c", "", "container name (defaults to the pod's default container)")
+	cmd.Flags().BoolVarP(&stdin, "stdin", "i", false, "pass stdin to the container")
+	cmd.Flags().BoolVarP(&tty, "tty", "t", false, "allocate a TTY")
+	return cmd
+}
+
+// remoteStreamOptions resolves the target container of a running pod and
+// wires up the local standard streams.
+func remoteStreamOptions(clientset *kubernetes.Clientset, podName, container string, stdin, tty bool) (remote.StreamOptions, error) {
+	pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		return remote.StreamOptions{}, err
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return remote.StreamOptions{}, fmt.Errorf("cannot connect to a container in a completed pod; current phase is %s", pod.Status.Phase)
+	}
+
+	if container == "" {
+		container = inspector.DefaultContainer(pod)
+	} else if !podHasContainer(pod, container) {
+		return remote.StreamOptions{}, fmt.Errorf("container %s not found in pod %s", container, podName)
+	}
+
+	if tty && !stdin {
+		fmt.Fprintln(os.Stderr, "Warning: -t has no effect without -i; not allocating a TTY")
+		tty = false
+	}
+	if tty && !remote.IsTerminal(os.Stdin) {
+		fmt.Fprintln(os.Stderr, "Warning: stdin is not a terminal; not allocating a TTY")
+		tty = false
+	}
+
+	opts := remote.StreamOptions{
+		Namespace: namespace,
+		Pod:       podName,
+		Container: container,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+		TTY:       tty,
+	}
+	if stdin {
+		opts.Stdin = os.Stdin
+	}
+	return opts, nil
+}
+
+func podHasContainer(pod *corev1.Pod, name string) bool {
+	for _, c := range allPodContainers(pod) {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// exitWithRemoteStatus propagates the exit code of a remote command so that
+// scripts can rely on it, and returns any other error unchanged.
+func exitWithRemoteStatus(err error) error {
+	var exitErr exec.CodeExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitStatus())
+	}
+	return err
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// logPrefixColors are cycled through to tell multiplexed streams apart.
+var logPrefixColors = []string{"39", "205", "82", "214", "141", "45", "203", "226"}
+
+// logReconnectDelay is how long a followed stream waits before reconnecting
+// after its container exits or the stream drops.
+const logReconnectDelay = 2 * time.Second
+
+// logOptions holds the user-facing flags of "pod logs".
+type logOptions struct {
+	container     string
+	allContainers bool
+	follow        bool
+	previous      bool
+	since         time.Duration
+	tail          int64
+	timestamps    bool
+	grep          *regexp.Regexp
+}
+
+// logStreamer multiplexes the logs of several pod containers onto a single
+// writer, one line at a time.
+type logStreamer struct {
+	clientset *kubernetes.Clientset
+	namespace string
+	opts      logOptions
+	prefix    bool
+
+	mu     sync.Mutex
+	out    io.Writer
+	wg     sync.WaitGroup
+	active map[string]bool // keyed by pod UID and container name
+	colors int
+}
+
+func newPodLogsCmd() *cobra.Command {
+	var (
+		selector string
+		since    string
+		grep     string
+		opts     logOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "logs [name]",
+		Short: "Print or follow pod logs",
+		Long: `Print the logs of a pod, or of every pod matching a label selector.
+
+When several pods or containers are selected their logs are streamed
+concurrently, each line prefixed with a colored pod/container tag. With
+--follow, streams reconnect when a container restarts, and pods that start
+matching the selector later are picked up automatically.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && selector == "" {
+				return fmt.Errorf("pod name or --selector is required")
+			}
+			if len(args) == 1 && selector != "" {
+				return fmt.Errorf("specify either a pod name or --selector, not both")
+			}
+			if opts.allContainers && opts.container != "" {
+				return fmt.Errorf("--container and --all-containers are mutually exclusive")
+			}
+
+			if since != "" {
+				d, err := time.ParseDuration(since)
+				if err != nil {
+					return fmt.Errorf("invalid --since duration: %v", err)
+				}
+				opts.since = d
+			}
+			if grep != "" {
+				re, err := regexp.Compile(grep)
+				if err != nil {
+					return fmt.Errorf("invalid --grep pattern: %v", err)
+				}
+				opts.grep = re
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			streamer := &logStreamer{
+				clientset: clientset,
+				namespace: namespace,
+				opts:      opts,
+				out:       os.Stdout,
+				active:    make(map[string]bool),
+			}
+
+			if len(args) == 1 {
+				pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, args[0], metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				containers, err := logContainers(pod, opts)
+				if err != nil {
+					return err
+				}
+				streamer.prefix = len(containers) > 1
+				for _, c := range containers {
+					streamer.start(ctx, pod, c)
+				}
+				streamer.wg.Wait()
+				return nil
+			}
+
+			return streamer.runSelector(ctx, selector)
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector to choose pods (e.g. app=web)")
+	cmd.Flags().StringVarP(&opts.container, "container", "c", "", "container to print logs from")
+	cmd.Flags().BoolVar(&opts.allContainers, "all-containers", false, "print logs from all containers, including init containers")
+	cmd.Flags().BoolVarP(&opts.follow, "follow", "f", false, "stream new log lines as they are written")
+	cmd.Flags().BoolVarP(&opts.previous, "previous", "p", false, "print logs of the previous container instance")
+	cmd.Flags().StringVar(&since, "since", "", "only return logs newer than a relative duration (e.g. 5m, 1h)")
+	cmd.Flags().Int64Var(&opts.tail, "tail", -1, "number of recent lines to show (-1 shows all)")
+	cmd.Flags().BoolVar(&opts.timestamps, "timestamps", false, "include timestamps on each line")
+	cmd.Flags().StringVar(&grep, "grep", "", "only print lines matching this regular expression")
+
+	return cmd
+}
+
+// runSelector streams every matching pod. When following, the selector is
+// re-evaluated periodically so replacement pods are picked up.
+func (s *logStreamer) runSelector(ctx context.Context, selector string) error {
+	s.prefix = true
+
+	discover := func() error {
+		pods, err := s.clientset.CoreV1().Pods(s.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return err
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			containers, err := logContainers(pod, s.opts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: skipping pod %s: %v\n", pod.Name, err)
+				continue
+			}
+			for _, c := range containers {
+				s.start(ctx, pod, c)
+			}
+		}
+		if len(pods.Items) == 0 && !s.opts.follow {
+			return fmt.Errorf("no pods found matching selector %q in namespace %s", selector, s.namespace)
+		}
+		return nil
+	}
+
+	if err := discover(); err != nil {
+		return err
+	}
+
+	if s.opts.follow {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				s.wg.Wait()
+				return nil
+			case <-ticker.C:
+				if err := discover(); err != nil && ctx.Err() == nil {
+					fmt.Fprintf(os.Stderr, "Warning: error listing pods: %v\n", err)
+				}
+			}
+		}
+	}
+
+	s.wg.Wait()
+	return nil
+}
+
+// start launches a stream for pod/container unless one has already been
+// started. Streams are tracked by pod UID so a pod recreated under the same
+// name gets a fresh stream while a finished one is not replayed.
+func (s *logStreamer) start(ctx context.Context, pod *corev1.Pod, container string) {
+	key := pod.Name + "/" + container
+	id := string(pod.UID) + "/" + container
+
+	s.mu.Lock()
+	if s.active[id] {
+		s.mu.Unlock()
+		return
+	}
+	s.active[id] = true
+	color := lipgloss.Color(logPrefixColors[s.colors%len(logPrefixColors)])
+	s.colors++
+	s.mu.Unlock()
+
+	prefix := ""
+	if s.prefix {
+		prefix = lipgloss.NewStyle().Foreground(color).Render(key) + " "
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.stream(ctx, pod.Name, container, prefix); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Error streaming logs for %s: %v\n", key, err)
+		}
+	}()
+}
+
+// stream copies the logs of a single container to the output. When
+// following, it reconnects after the container restarts and only stops once
+// the pod is gone or has terminated.
+func (s *logStreamer) stream(ctx context.Context, pod, container, prefix string) error {
+	var resumeAt *metav1.Time
+
+	for {
+		opts := &corev1.PodLogOptions{
+			Container:  container,
+			Follow:     s.opts.follow,
+			Previous:   s.opts.previous,
+			Timestamps: s.opts.timestamps,
+		}
+		if resumeAt != nil {
+			opts.SinceTime = resumeAt
+		} else {
+			if s.opts.since > 0 {
+				seconds := int64(s.opts.since.Seconds())
+				opts.SinceSeconds = &seconds
+			}
+			if s.opts.tail >= 0 {
+				tail := s.opts.tail
+				opts.TailLines = &tail
+			}
+		}
+
+		rc, err := s.clientset.CoreV1().Pods(s.namespace).GetLogs(pod, opts).Stream(ctx)
+		if err == nil {
+			err = s.copyLines(rc, prefix)
+			rc.Close()
+		}
+		if !s.opts.follow || s.opts.previous || ctx.Err() != nil {
+			return err
+		}
+
+		now := metav1.Now()
+		resumeAt = &now
+
+		current, getErr := s.clientset.CoreV1().Pods(s.namespace).Get(ctx, pod, metav1.GetOptions{})
+		if apierrors.IsNotFound(getErr) {
+			s.writeLine(prefix, "--- pod deleted ---")
+			return nil
+		}
+		if getErr == nil && (current.Status.Phase == corev1.PodSucceeded || current.Status.Phase == corev1.PodFailed) {
+			s.writeLine(prefix, fmt.Sprintf("--- pod %s ---", current.Status.Phase))
+			return nil
+		}
+		if getErr == nil && initContainerCompleted(current, container) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logReconnectDelay):
+		}
+	}
+}
+
+func (s *logStreamer) copyLines(r io.Reader, prefix string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if s.opts.grep != nil && !s.opts.grep.MatchString(line) {
+			continue
+		}
+		s.writeLine(prefix, line)
+	}
+	return scanner.Err()
+}
+
+func (s *logStreamer) writeLine(prefix, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.out, "%s%s\n", prefix, line)
+}
+
+// logContainers returns the containers of pod whose logs should be shown.
+func logContainers(pod *corev1.Pod, opts logOptions) ([]string, error) {
+	if opts.container != "" {
+		for _, c := range allPodContainers(pod) {
+			if c == opts.container {
+				return []string{c}, nil
+			}
+		}
+		return nil, fmt.Errorf("container %s not found in pod %s", opts.container, pod.Name)
+	}
+
+	if opts.allContainers {
+		return allPodContainers(pod), nil
+	}
+
+	return []string{inspector.DefaultContainer(pod)}, nil
+}
+
+// allPodContainers lists init, regular and ephemeral container names in the
+// order they start.
+func allPodContainers(pod *corev1.Pod) []string {
+	var names []string
+	for _, c := range pod.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// initContainerCompleted reports whether the named init container has run to
+// completion and will not produce any more output.
+func initContainerCompleted(pod *corev1.Pod, container string) bool {
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name == container && status.State.Terminated != nil && status.State.Terminated.ExitCode == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// Labels that controllers stamp onto their pods. A clone that kept them
+// would be counted, and possibly deleted, by the original controller.
+var controllerPodLabels = []string{
+	"pod-template-hash",
+	"controller-revision-hash",
+	"pod-template-generation",
+	"statefulset.kubernetes.io/pod-name",
+	"apps.kubernetes.io/pod-index",
+	"batch.kubernetes.io/controller-uid",
+	"batch.kubernetes.io/job-name",
+	"controller-uid",
+	"job-name",
+}
+
+// readPodManifest reads a single Pod from a YAML or JSON file, or from stdin
+// when path is "-".
+func readPodManifest(path string) (*corev1.Pod, error) {
+	pod := &corev1.Pod{}
+	if err := readManifest(path, pod); err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
+
+// readManifest decodes a single object from a YAML or JSON file, or from
+// stdin when path is "-", into obj. The manifest must be of obj's kind.
+func readManifest(path string, obj runtime.Object) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("error reading manifest: %v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil || len(gvks) == 0 {
+		return fmt.Errorf("unsupported object type %T", obj)
+	}
+	want := gvks[0]
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bufio.NewReader(r), 4096)
+	if err := decoder.Decode(obj); err != nil {
+		return fmt.Errorf("error parsing manifest %s: %v", path, err)
+	}
+	got := obj.GetObjectKind().GroupVersionKind()
+	if got.Kind != want.Kind || (got.Version != "" && got.GroupVersion() != want.GroupVersion()) {
+		return fmt.Errorf("manifest %s is a %s %s, not a %s %s", path, got.GroupVersion(), got.Kind, want.GroupVersion(), want.Kind)
+	}
+
+	var extra map[string]interface{}
+	if err := decoder.Decode(&extra); err != io.EOF && len(extra) > 0 {
+		return fmt.Errorf("manifest %s contains more than one object; only a single %s is supported", path, want.Kind)
+	}
+	return nil
+}
+
+// clonePod copies the spec of a running pod into a new, unsaved pod. Status,
+// node assignment, ownership and everything the API server or admission
+// plugins generated are left out so the copy can be created as-is.
+func clonePod(source *corev1.Pod, keepLabels bool) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: source.Namespace,
+		},
+		Spec: *source.Spec.DeepCopy(),
+	}
+
+	if keepLabels && len(source.Labels) > 0 {
+		pod.Labels = make(map[string]string)
+		for k, v := range source.Labels {
+			pod.Labels[k] = v
+		}
+		for _, k := range controllerPodLabels {
+			delete(pod.Labels, k)
+		}
+	}
+	for k, v := range source.Annotations {
+		if k == corev1.LastAppliedConfigAnnotation || strings.HasPrefix(k, "kubernetes.io/config.") {
+			continue
+		}
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		pod.Annotations[k] = v
+	}
+
+	spec := &pod.Spec
+	spec.NodeName = ""
+	spec.EphemeralContainers = nil
+	// Priority is resolved from PriorityClassName at admission and is
+	// rejected if it is set by the client.
+	spec.Priority = nil
+	if spec.Hostname == source.Name {
+		spec.Hostname = ""
+	}
+
+	// The service account token volume is injected by admission and would
+	// be injected a second time under a new name.
+	generated := map[string]bool{}
+	var volumes []corev1.Volume
+	for _, v := range spec.Volumes {
+		if v.Projected != nil && strings.HasPrefix(v.Name, "kube-api-access-") {
+			generated[v.Name] = true
+			continue
+		}
+		volumes = append(volumes, v)
+	}
+	spec.Volumes = volumes
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for i := range containers {
+			var mounts []corev1.VolumeMount
+			for _, m := range containers[i].VolumeMounts {
+				if !generated[m.Name] {
+					mounts = append(mounts, m)
+				}
+			}
+			containers[i].VolumeMounts = mounts
+		}
+	}
+
+	return pod
+}
+
+// applyOverrides applies the pod create flags that were given explicitly to
+// a pod read from a manifest or cloned from another pod. Container flags
+// target the pod's default container.
+func (o *podCreateOptions) applyOverrides(cmd *cobra.Command, pod *corev1.Pod) error {
+	flags := cmd.Flags()
+	if flags.Changed("configmap") || flags.Changed("secret") {
+		return fmt.Errorf("--configmap and --secret can only be used when building a pod from flags")
+	}
+
+	if flags.Changed("name") {
+		pod.Name = o.primary.name
+	}
+	if len(pod.Spec.Containers) == 0 {
+		return fmt.Errorf("pod %s has no containers", pod.Name)
+	}
+
+	target := &pod.Spec.Containers[0]
+	name := inspector.DefaultContainer(pod)
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == name {
+			target = &pod.Spec.Containers[i]
+		}
+	}
+	if err := o.overrideContainer(cmd, target); err != nil {
+		return err
+	}
+
+	if flags.Changed("label") {
+		labels, err := parseKeyValues("label", o.labels)
+		if err != nil {
+			return err
+		}
+		if pod.Labels == nil {
+			pod.Labels = make(map[string]string)
+		}
+		for k, v := range labels {
+			pod.Labels[k] = v
+		}
+	}
+	if flags.Changed("node-selector") {
+		selector, err := parseKeyValues("node-selector", o.nodeSelector)
+		if err != nil {
+			return err
+		}
+		if pod.Spec.NodeSelector == nil {
+			pod.Spec.NodeSelector = make(map[string]string)
+		}
+		for k, v := range selector {
+			pod.Spec.NodeSelector[k] = v
+		}
+	}
+	for _, value := range o.tolerations {
+		toleration, err := parseToleration(value)
+		if err != nil {
+			return err
+		}
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, toleration)
+	}
+	if flags.Changed("service-account") {
+		pod.Spec.ServiceAccountName = o.serviceAccount
+		pod.Spec.DeprecatedServiceAccount = ""
+	}
+	if flags.Changed("restart") {
+		policy, err := parseRestartPolicy(o.restartPolicy)
+		if err != nil {
+			return fmt.Errorf("invalid --restart: %v", err)
+		}
+		pod.Spec.RestartPolicy = policy
+	}
+
+	for _, block := range o.containers {
+		opts, err := parseContainerBlock("container", block)
+		if err != nil {
+			return err
+		}
+		container, err := opts.build()
+		if err != nil {
+			return err
+		}
+		pod.Spec.Containers = append(pod.Spec.Containers, container)
+	}
+	for _, block := range o.initContainers {
+		opts, err := parseContainerBlock("init-container", block)
+		if err != nil {
+			return err
+		}
+		container, err := opts.build()
+		if err != nil {
+			return err
+		}
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, container)
+	}
+	return checkDuplicateContainers(&pod.Spec)
+}
+
+func (o *podCreateOptions) overrideContainer(cmd *cobra.Command, c *corev1.Container) error {
+	flags := cmd.Flags()
+	p := o.primary
+
+	if flags.Changed("image") {
+		c.Image = p.image
+	}
+	if flags.Changed("command") {
+		c.Command = p.command
+	}
+	if flags.Changed("args") {
+		c.Args = p.args
+	}
+	if flags.Changed("image-pull-policy") {
+		policy, err := parsePullPolicy(p.imagePullPolicy)
+		if err != nil {
+			return fmt.Errorf("invalid --image-pull-policy: %v", err)
+		}
+		c.ImagePullPolicy = policy
+	}
+
+	env, err := parseEnvVars("--env", p.envVars)
+	if err != nil {
+		return err
+	}
+	for _, e := range env {
+		replaced := false
+		for i := range c.Env {
+			if c.Env[i].Name == e.Name {
+				c.Env[i] = e
+				replaced = true
+			}
+		}
+		if !replaced {
+			c.Env = append(c.Env, e)
+		}
+	}
+
+	envFrom, err := parseEnvFrom("--env-from-configmap", p.envFromConfigMaps, false)
+	if err != nil {
+		return err
+	}
+	c.EnvFrom = append(c.EnvFrom, envFrom...)
+	envFrom, err = parseEnvFrom("--env-from-secret", p.envFromSecrets, true)
+	if err != nil {
+		return err
+	}
+	c.EnvFrom = append(c.EnvFrom, envFrom...)
+
+	ports, err := parseContainerPorts("--port", p.ports)
+	if err != nil {
+		return err
+	}
+	c.Ports = append(c.Ports, ports...)
+
+	for _, mount := range p.volumeMounts {
+		parts := strings.Split(mount, ":")
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid volume mount format: %s", mount)
+		}
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: parts[0], MountPath: parts[1]})
+	}
+
+	o.applyLegacyResources()
+	resources, err := o.primary.resources()
+	if err != nil {
+		return err
+	}
+	for name, quantity := range resources.Requests {
+		if c.Resources.Requests == nil {
+			c.Resources.Requests = corev1.ResourceList{}
+		}
+		c.Resources.Requests[name] = quantity
+	}
+	for name, quantity := range resources.Limits {
+		if c.Resources.Limits == nil {
+			c.Resources.Limits = corev1.ResourceList{}
+		}
+		c.Resources.Limits[name] = quantity
+	}
+
+	probes := []struct {
+		flag  string
+		value string
+		probe **corev1.Probe
+	}{
+		{"liveness-probe", p.livenessProbe, &c.LivenessProbe},
+		{"readiness-probe", p.readinessProbe, &c.ReadinessProbe},
+		{"startup-probe", p.startupProbe, &c.StartupProbe},
+	}
+	for _, pr := range probes {
+		if pr.value == "" {
+			continue
+		}
+		probe, err := parseProbe(pr.value)
+		if err != nil {
+			return fmt.Errorf("invalid --%s %q: %v", pr.flag, pr.value, err)
+		}
+		*pr.probe = probe
+	}
+	return nil
+}
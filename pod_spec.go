@@ -0,0 +1,475 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// containerOptions holds everything needed to build one container of a pod,
+// whether it comes from the top-level pod create flags or from a repeated
+// --container/--init-container block.
+type containerOptions struct {
+	name              string
+	image             string
+	command           []string
+	args              []string
+	imagePullPolicy   string
+	envVars           []string
+	envFromSecrets    []string
+	envFromConfigMaps []string
+	ports             []string
+	volumeMounts      []string
+	requestCPU        string
+	limitCPU          string
+	requestMemory     string
+	limitMemory       string
+	livenessProbe     string
+	readinessProbe    string
+	startupProbe      string
+
+	// flagPrefix is prepended to option names in error messages so that a
+	// bad value inside a --container block points at that block.
+	flagPrefix string
+}
+
+func (o containerOptions) flag(name string) string {
+	if o.flagPrefix == "" {
+		return "--" + name
+	}
+	return o.flagPrefix + name
+}
+
+func (o containerOptions) build() (corev1.Container, error) {
+	if o.name == "" {
+		return corev1.Container{}, fmt.Errorf("%s is required", o.flag("name"))
+	}
+	if o.image == "" {
+		return corev1.Container{}, fmt.Errorf("%s is required", o.flag("image"))
+	}
+
+	container := corev1.Container{
+		Name:    o.name,
+		Image:   o.image,
+		Command: o.command,
+		Args:    o.args,
+	}
+
+	if o.imagePullPolicy != "" {
+		policy, err := parsePullPolicy(o.imagePullPolicy)
+		if err != nil {
+			return container, fmt.Errorf("invalid %s: %v", o.flag("image-pull-policy"), err)
+		}
+		container.ImagePullPolicy = policy
+	}
+
+	env, err := parseEnvVars(o.flag("env"), o.envVars)
+	if err != nil {
+		return container, err
+	}
+	container.Env = env
+
+	envFromSecrets, err := parseEnvFrom(o.flag("env-from-secret"), o.envFromSecrets, true)
+	if err != nil {
+		return container, err
+	}
+	envFromConfigMaps, err := parseEnvFrom(o.flag("env-from-configmap"), o.envFromConfigMaps, false)
+	if err != nil {
+		return container, err
+	}
+	container.EnvFrom = append(envFromConfigMaps, envFromSecrets...)
+
+	ports, err := parseContainerPorts(o.flag("port"), o.ports)
+	if err != nil {
+		return container, err
+	}
+	container.Ports = ports
+
+	for _, mount := range o.volumeMounts {
+		parts := strings.Split(mount, ":")
+		if len(parts) != 2 {
+			return container, fmt.Errorf("invalid volume mount format: %s", mount)
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      parts[0],
+			MountPath: parts[1],
+		})
+	}
+
+	resources, err := o.resources()
+	if err != nil {
+		return container, err
+	}
+	container.Resources = resources
+
+	probes := []struct {
+		flag  string
+		value string
+		probe **corev1.Probe
+	}{
+		{"liveness-probe", o.livenessProbe, &container.LivenessProbe},
+		{"readiness-probe", o.readinessProbe, &container.ReadinessProbe},
+		{"startup-probe", o.startupProbe, &container.StartupProbe},
+	}
+	for _, p := range probes {
+		if p.value == "" {
+			continue
+		}
+		probe, err := parseProbe(p.value)
+		if err != nil {
+			return container, fmt.Errorf("invalid %s %q: %v", o.flag(p.flag), p.value, err)
+		}
+		*p.probe = probe
+	}
+
+	return container, nil
+}
+
+// resources builds requests and limits separately and rejects a request that
+// exceeds its limit, which the API server would refuse anyway.
+func (o containerOptions) resources() (corev1.ResourceRequirements, error) {
+	resources := corev1.ResourceRequirements{}
+
+	quantities := []struct {
+		flag  string
+		value string
+		name  corev1.ResourceName
+		list  *corev1.ResourceList
+	}{
+		{"request-cpu", o.requestCPU, corev1.ResourceCPU, &resources.Requests},
+		{"limit-cpu", o.limitCPU, corev1.ResourceCPU, &resources.Limits},
+		{"request-memory", o.requestMemory, corev1.ResourceMemory, &resources.Requests},
+		{"limit-memory", o.limitMemory, corev1.ResourceMemory, &resources.Limits},
+	}
+	for _, q := range quantities {
+		if q.value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(q.value)
+		if err != nil {
+			return resources, fmt.Errorf("invalid %s %q: %v", o.flag(q.flag), q.value, err)
+		}
+		if *q.list == nil {
+			*q.list = corev1.ResourceList{}
+		}
+		(*q.list)[q.name] = quantity
+	}
+
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		request, hasRequest := resources.Requests[name]
+		limit, hasLimit := resources.Limits[name]
+		if hasRequest && hasLimit && request.Cmp(limit) > 0 {
+			return resources, fmt.Errorf("%s request %s exceeds its limit %s", name, request.String(), limit.String())
+		}
+	}
+	return resources, nil
+}
+
+// containerBlockKeys are the keys of a --container or --init-container block.
+var containerBlockKeys = map[string]bool{
+	"name": true, "image": true, "command": true, "arg": true, "image-pull-policy": true,
+	"env": true, "env-from-secret": true, "env-from-configmap": true, "port": true, "mount": true,
+	"request-cpu": true, "limit-cpu": true, "request-memory": true, "limit-memory": true,
+}
+
+// parseContainerBlock parses the value of a --container or --init-container
+// flag: comma-separated KEY=VALUE pairs such as
+//
+//	name=sidecar,image=envoy:v1.29,port=9901,limit-memory=128Mi
+//
+// env, env-from-secret, env-from-configmap, port, mount and arg may be
+// repeated; command is split on spaces. The values of env and arg may contain
+// commas: a piece that does not start with a known key continues the
+// previous one, as in env=JAVA_OPTS=-Xms1g,-Xmx2g.
+func parseContainerBlock(flag, value string) (containerOptions, error) {
+	opts := containerOptions{flagPrefix: "--" + flag + " "}
+
+	var pairs []string
+	for _, piece := range strings.Split(value, ",") {
+		key, _, _ := strings.Cut(piece, "=")
+		if len(pairs) > 0 && !containerBlockKeys[key] {
+			last := pairs[len(pairs)-1]
+			if strings.HasPrefix(last, "env=") || strings.HasPrefix(last, "arg=") {
+				pairs[len(pairs)-1] = last + "," + piece
+				continue
+			}
+		}
+		pairs = append(pairs, piece)
+	}
+
+	for _, pair := range pairs {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || val == "" {
+			return opts, fmt.Errorf("invalid --%s %q: expected KEY=VALUE pairs, got %q", flag, value, pair)
+		}
+		switch key {
+		case "name":
+			opts.name = val
+		case "image":
+			opts.image = val
+		case "command":
+			opts.command = strings.Fields(val)
+		case "arg":
+			opts.args = append(opts.args, val)
+		case "image-pull-policy":
+			opts.imagePullPolicy = val
+		case "env":
+			opts.envVars = append(opts.envVars, val)
+		case "env-from-secret":
+			opts.envFromSecrets = append(opts.envFromSecrets, val)
+		case "env-from-configmap":
+			opts.envFromConfigMaps = append(opts.envFromConfigMaps, val)
+		case "port":
+			opts.ports = append(opts.ports, val)
+		case "mount":
+			opts.volumeMounts = append(opts.volumeMounts, val)
+		case "request-cpu":
+			opts.requestCPU = val
+		case "limit-cpu":
+			opts.limitCPU = val
+		case "request-memory":
+			opts.requestMemory = val
+		case "limit-memory":
+			opts.limitMemory = val
+		default:
+			return opts, fmt.Errorf("invalid --%s %q: unknown key %q", flag, value, key)
+		}
+	}
+	return opts, nil
+}
+
+// parseEnvVars parses --env values. The name ends at the first "=", so the
+// value may itself contain "=". A value of the form secret:NAME/KEY,
+// configmap:NAME/KEY or field:PATH is turned into a reference instead of a
+// literal.
+func parseEnvVars(flag string, envVars []string) ([]corev1.EnvVar, error) {
+	envSlice := []corev1.EnvVar{}
+	for _, env := range envVars {
+		name, value, ok := strings.Cut(env, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s %q: expected KEY=VALUE", flag, env)
+		}
+		if errs := validation.IsEnvVarName(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid %s %q: %s", flag, env, strings.Join(errs, "; "))
+		}
+
+		envVar := corev1.EnvVar{Name: name}
+		source, ref, isRef := strings.Cut(value, ":")
+		if !isRef {
+			source = ""
+		}
+		switch source {
+		case "secret", "configmap":
+			objName, key, ok := strings.Cut(ref, "/")
+			if !ok || objName == "" || key == "" {
+				return nil, fmt.Errorf("invalid %s %q: expected %s=%s:NAME/KEY", flag, env, name, source)
+			}
+			if source == "secret" {
+				envVar.ValueFrom = &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: objName},
+					Key:                  key,
+				}}
+			} else {
+				envVar.ValueFrom = &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: objName},
+					Key:                  key,
+				}}
+			}
+		case "field":
+			if ref == "" {
+				return nil, fmt.Errorf("invalid %s %q: expected %s=field:PATH, e.g. field:status.podIP", flag, env, name)
+			}
+			envVar.ValueFrom = &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: ref}}
+		default:
+			envVar.Value = value
+		}
+		envSlice = append(envSlice, envVar)
+	}
+	return envSlice, nil
+}
+
+// parseEnvFrom parses --env-from-secret and --env-from-configmap values of
+// the form NAME[:PREFIX].
+func parseEnvFrom(flag string, values []string, secret bool) ([]corev1.EnvFromSource, error) {
+	var sources []corev1.EnvFromSource
+	for _, value := range values {
+		name, prefix, _ := strings.Cut(value, ":")
+		if name == "" {
+			return nil, fmt.Errorf("invalid %s %q: expected NAME[:PREFIX]", flag, value)
+		}
+		if prefix != "" {
+			if errs := validation.IsEnvVarName(prefix); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid %s %q: prefix %s", flag, value, strings.Join(errs, "; "))
+			}
+		}
+
+		source := corev1.EnvFromSource{Prefix: prefix}
+		ref := corev1.LocalObjectReference{Name: name}
+		if secret {
+			source.SecretRef = &corev1.SecretEnvSource{LocalObjectReference: ref}
+		} else {
+			source.ConfigMapRef = &corev1.ConfigMapEnvSource{LocalObjectReference: ref}
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// parseContainerPorts parses PORT[/PROTOCOL] values and rejects anything the
+// API server would, so that the error names the flag rather than a field
+// path in the generated spec.
+func parseContainerPorts(flag string, ports []string) ([]corev1.ContainerPort, error) {
+	portSlice := []corev1.ContainerPort{}
+	for _, port := range ports {
+		number, protocol, hasProtocol := strings.Cut(port, "/")
+
+		containerPort, err := strconv.ParseInt(number, 10, 32)
+		if err != nil || containerPort < 1 || containerPort > 65535 {
+			return nil, fmt.Errorf("invalid %s %q: port must be a number between 1 and 65535", flag, port)
+		}
+
+		proto := corev1.ProtocolTCP
+		if hasProtocol {
+			proto = corev1.Protocol(strings.ToUpper(protocol))
+			switch proto {
+			case corev1.ProtocolTCP, corev1.ProtocolUDP, corev1.ProtocolSCTP:
+			default:
+				return nil, fmt.Errorf("invalid %s %q: protocol must be TCP, UDP or SCTP", flag, port)
+			}
+		}
+
+		portSlice = append(portSlice, corev1.ContainerPort{
+			ContainerPort: int32(containerPort),
+			Protocol:      proto,
+		})
+	}
+	return portSlice, nil
+}
+
+// parseProbe parses ACTION:TARGET[,KEY=VALUE...] where ACTION is one of
+// http-get (PORT[/PATH]), tcp (PORT), grpc (PORT) or exec (COMMAND, split
+// on spaces). The optional keys tune the probe timings in seconds:
+// initial-delay, period, timeout, success-threshold and failure-threshold.
+func parseProbe(value string) (*corev1.Probe, error) {
+	parts := strings.Split(value, ",")
+	action, target, ok := strings.Cut(parts[0], ":")
+	if !ok || target == "" {
+		return nil, fmt.Errorf("expected ACTION:TARGET")
+	}
+
+	probe := &corev1.Probe{}
+	switch action {
+	case "http-get":
+		port, path, _ := strings.Cut(target, "/")
+		probe.HTTPGet = &corev1.HTTPGetAction{
+			Path: "/" + path,
+			Port: intstr.Parse(port),
+		}
+	case "tcp":
+		probe.TCPSocket = &corev1.TCPSocketAction{Port: intstr.Parse(target)}
+	case "grpc":
+		port, err := strconv.ParseInt(target, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("grpc probes need a numeric port")
+		}
+		probe.GRPC = &corev1.GRPCAction{Port: int32(port)}
+	case "exec":
+		probe.Exec = &corev1.ExecAction{Command: strings.Fields(target)}
+	default:
+		return nil, fmt.Errorf("unknown probe action %q (want http-get, tcp, grpc or exec)", action)
+	}
+
+	for _, option := range parts[1:] {
+		key, val, ok := strings.Cut(option, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid probe option %q", option)
+		}
+		n, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("probe option %s must be a non-negative integer", key)
+		}
+		switch key {
+		case "initial-delay":
+			probe.InitialDelaySeconds = int32(n)
+		case "period":
+			probe.PeriodSeconds = int32(n)
+		case "timeout":
+			probe.TimeoutSeconds = int32(n)
+		case "success-threshold":
+			probe.SuccessThreshold = int32(n)
+		case "failure-threshold":
+			probe.FailureThreshold = int32(n)
+		default:
+			return nil, fmt.Errorf("unknown probe option %q", key)
+		}
+	}
+	return probe, nil
+}
+
+func parsePullPolicy(value string) (corev1.PullPolicy, error) {
+	for _, policy := range []corev1.PullPolicy{corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever} {
+		if strings.EqualFold(value, string(policy)) {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("%q is not one of Always, IfNotPresent or Never", value)
+}
+
+func parseRestartPolicy(value string) (corev1.RestartPolicy, error) {
+	for _, policy := range []corev1.RestartPolicy{corev1.RestartPolicyAlways, corev1.RestartPolicyOnFailure, corev1.RestartPolicyNever} {
+		if strings.EqualFold(value, string(policy)) {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("%q is not one of Always, OnFailure or Never", value)
+}
+
+// parseToleration accepts the taint syntax used by kubectl taint:
+// KEY[=VALUE][:EFFECT]. Without a value the toleration matches any value of
+// the key, and without an effect it matches every effect. A lone "*"
+// tolerates everything.
+func parseToleration(value string) (corev1.Toleration, error) {
+	if value == "*" {
+		return corev1.Toleration{Operator: corev1.TolerationOpExists}, nil
+	}
+
+	keyValue, effect, hasEffect := strings.Cut(value, ":")
+	key, val, hasValue := strings.Cut(keyValue, "=")
+	if key == "" {
+		return corev1.Toleration{}, fmt.Errorf("invalid --toleration %q: key is required", value)
+	}
+
+	toleration := corev1.Toleration{Key: key, Operator: corev1.TolerationOpExists}
+	if hasValue {
+		toleration.Operator = corev1.TolerationOpEqual
+		toleration.Value = val
+	}
+	if hasEffect {
+		switch corev1.TaintEffect(effect) {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+			toleration.Effect = corev1.TaintEffect(effect)
+		default:
+			return corev1.Toleration{}, fmt.Errorf("invalid --toleration %q: effect must be NoSchedule, PreferNoSchedule or NoExecute", value)
+		}
+	}
+	return toleration, nil
+}
+
+// parseKeyValues parses repeated KEY=VALUE flags such as --label and
+// --node-selector.
+func parseKeyValues(flag string, values []string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, value := range values {
+		parts := strings.Split(value, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid %s format: %s", flag, value)
+		}
+		result[parts[0]] = parts[1]
+	}
+	return result, nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// usageRow is the resource usage of a pod or of one of its containers,
+// alongside what it requested and is limited to. Zero means unset.
+type usageRow struct {
+	pod       string
+	container string
+
+	cpuUsage, cpuRequest, cpuLimit int64 // millicores
+	memUsage, memRequest, memLimit int64 // bytes
+
+	// memPeak is the highest memory usage of a container as a percentage
+	// of its own limit, so that a pod row shows a container at risk of
+	// being OOM killed even when its siblings have room to spare.
+	memPeak float64
+}
+
+func newPodTopCmd() *cobra.Command {
+	var (
+		selector      string
+		containers    bool
+		sortBy        string
+		watching      bool
+		interval      time.Duration
+		memoryWarning float64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "top [name]",
+		Short: "Show CPU and memory usage of pods",
+		Long: `Show the CPU and memory usage of pods from metrics-server, as absolute values
+and as a percentage of the requests and limits. Containers whose memory
+usage is close to their limit, and the pods they belong to, are marked with
+"!" because they risk being OOM killed.
+
+--watch refreshes the table every --interval.`,
+		Example: `  k8s-admin pod top
+  k8s-admin pod top -l app=web --containers --sort-by memory
+  k8s-admin pod top -w --interval 10s`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if sortBy != "" && sortBy != "cpu" && sortBy != "memory" {
+				return fmt.Errorf("invalid --sort-by %q: must be cpu or memory", sortBy)
+			}
+			if len(args) == 1 && selector != "" {
+				return fmt.Errorf("specify either a pod name or --selector, not both")
+			}
+			if watching && interval <= 0 {
+				return fmt.Errorf("invalid --interval %s: must be positive", interval)
+			}
+
+			config, err := getRestConfig()
+			if err != nil {
+				return err
+			}
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+			metricsClient, err := versioned.NewForConfig(config)
+			if err != nil {
+				return fmt.Errorf("error creating metrics client: %v", err)
+			}
+
+			name := ""
+			if len(args) == 1 {
+				name = args[0]
+			}
+			render := func(w io.Writer) error {
+				rows, err := collectPodUsage(clientset, metricsClient, name, selector, containers)
+				if err != nil {
+					return err
+				}
+				sortUsageRows(rows, sortBy)
+				writeUsageTable(w, rows, containers, memoryWarning)
+				return nil
+			}
+
+			if !watching {
+				return render(os.Stdout)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			tty := term.IsTerminal(int(os.Stdout.Fd()))
+			for {
+				var out strings.Builder
+				if err := render(&out); err != nil {
+					return err
+				}
+				if tty {
+					fmt.Print("\033[H\033[2J")
+				} else {
+					fmt.Println()
+				}
+				fmt.Print(out.String())
+				fmt.Printf("\nRefreshing every %s (updated %s, Ctrl+C to stop)\n", interval, time.Now().Format("15:04:05"))
+
+				if !sleepCtx(ctx, interval) {
+					return nil
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector to filter pods (e.g. app=web)")
+	cmd.Flags().BoolVar(&containers, "containers", false, "show one row per container")
+	cmd.Flags().StringVar(&sortBy, "sort-by", "", "sort by cpu or memory usage, highest first")
+	cmd.Flags().BoolVarP(&watching, "watch", "w", false, "keep refreshing the table")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "refresh interval with --watch")
+	cmd.Flags().Float64Var(&memoryWarning, "memory-warning", 90, "mark usage at or above this percentage of the memory limit")
+	return cmd
+}
+
+// collectPodUsage joins pod metrics with the requests and limits from the
+// pod specs. Pods without metrics yet (e.g. just started) are skipped.
+func collectPodUsage(clientset *kubernetes.Clientset, metricsClient versioned.Interface, name, selector string, perContainer bool) ([]usageRow, error) {
+	var pods []corev1.Pod
+	if name != "" {
+		pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, *pod)
+	} else {
+		list, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		pods = list.Items
+	}
+
+	metricsList, err := metricsClient.MetricsV1beta1().PodMetricses(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("error getting pod metrics (is metrics-server installed?): %v", err)
+	}
+	usage := make(map[string]map[string]corev1.ResourceList)
+	for _, m := range metricsList.Items {
+		containers := make(map[string]corev1.ResourceList)
+		for _, c := range m.Containers {
+			containers[c.Name] = c.Usage
+		}
+		usage[m.Name] = containers
+	}
+
+	var rows []usageRow
+	for _, pod := range pods {
+		containerUsage, ok := usage[pod.Name]
+		if !ok {
+			continue
+		}
+
+		total := usageRow{pod: pod.Name}
+		// A pod-level request or limit only exists if every container
+		// has one.
+		cpuRequested, memRequested := true, true
+		cpuLimited, memLimited := true, true
+		for _, c := range pod.Spec.Containers {
+			row := usageRow{pod: pod.Name, container: c.Name}
+			if u, ok := containerUsage[c.Name]; ok {
+				row.cpuUsage = u.Cpu().MilliValue()
+				row.memUsage = u.Memory().Value()
+			}
+			if q, ok := c.Resources.Requests[corev1.ResourceCPU]; ok {
+				row.cpuRequest = q.MilliValue()
+			}
+			if q, ok := c.Resources.Limits[corev1.ResourceCPU]; ok {
+				row.cpuLimit = q.MilliValue()
+			}
+			if q, ok := c.Resources.Requests[corev1.ResourceMemory]; ok {
+				row.memRequest = q.Value()
+			}
+			if q, ok := c.Resources.Limits[corev1.ResourceMemory]; ok {
+				row.memLimit = q.Value()
+			}
+			if row.memLimit > 0 {
+				row.memPeak = float64(row.memUsage) * 100 / float64(row.memLimit)
+			}
+
+			if perContainer {
+				rows = append(rows, row)
+				continue
+			}
+			total.cpuUsage += row.cpuUsage
+			total.memUsage += row.memUsage
+			total.cpuRequest += row.cpuRequest
+			total.memRequest += row.memRequest
+			total.cpuLimit += row.cpuLimit
+			total.memLimit += row.memLimit
+			total.memPeak = max(total.memPeak, row.memPeak)
+			cpuRequested = cpuRequested && row.cpuRequest > 0
+			memRequested = memRequested && row.memRequest > 0
+			cpuLimited = cpuLimited && row.cpuLimit > 0
+			memLimited = memLimited && row.memLimit > 0
+		}
+		if !perContainer {
+			if !cpuRequested {
+				total.cpuRequest = 0
+			}
+			if !memRequested {
+				total.memRequest = 0
+			}
+			if !cpuLimited {
+				total.cpuLimit = 0
+			}
+			if !memLimited {
+				total.memLimit = 0
+			}
+			rows = append(rows, total)
+		}
+	}
+	return rows, nil
+}
+
+func sortUsageRows(rows []usageRow, sortBy string) {
+	switch sortBy {
+	case "cpu":
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].cpuUsage > rows[j].cpuUsage })
+	case "memory":
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].memUsage > rows[j].memUsage })
+	}
+}
+
+func writeUsageTable(out io.Writer, rows []usageRow, perContainer bool, memoryWarning float64) {
+	if len(rows) == 0 {
+		fmt.Fprintf(out, "No pod metrics available in namespace %s\n", namespace)
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if perContainer {
+		fmt.Fprintln(w, "POD\tCONTAINER\tCPU\tCPU/REQ\tCPU/LIM\tMEMORY\tMEM/REQ\tMEM/LIM\t")
+	} else {
+		fmt.Fprintln(w, "POD\tCPU\tCPU/REQ\tCPU/LIM\tMEMORY\tMEM/REQ\tMEM/LIM\t")
+	}
+
+	warned := 0
+	for _, r := range rows {
+		flag := ""
+		if r.memPeak > 0 && r.memPeak >= memoryWarning {
+			flag = "!"
+			warned++
+		}
+
+		cols := []string{r.pod}
+		if perContainer {
+			cols = append(cols, r.container)
+		}
+		cols = append(cols,
+			fmt.Sprintf("%dm", r.cpuUsage),
+			usagePercent(r.cpuUsage, r.cpuRequest),
+			usagePercent(r.cpuUsage, r.cpuLimit),
+			fmt.Sprintf("%dMi", r.memUsage/(1024*1024)),
+			usagePercent(r.memUsage, r.memRequest),
+			usagePercent(r.memUsage, r.memLimit),
+			flag,
+		)
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+	w.Flush()
+
+	if warned > 0 {
+		if perContainer {
+			fmt.Fprintf(out, "\n! %d container(s) use at least %.0f%% of their memory limit and risk being OOM killed\n", warned, memoryWarning)
+		} else {
+			fmt.Fprintf(out, "\n! %d pod(s) have a container using at least %.0f%% of its memory limit, which risks being OOM killed\n", warned, memoryWarning)
+		}
+	}
+}
+
+func usagePercent(usage, reference int64) string {
+	if reference <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", float64(usage)*100/float64(reference))
+}
@@ -0,0 +1,643 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/k8s-admin-cli/remote"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/homedir"
+)
+
+const (
+	forwardPollInterval = 2 * time.Second
+	forwardMaxBackoff   = 30 * time.Second
+)
+
+// forwardTarget is a pod or service together with the ports to forward to it.
+// Remote ports may be numbers or port names.
+type forwardTarget struct {
+	Kind  string   `json:"kind"`
+	Name  string   `json:"name"`
+	Ports []string `json:"ports"`
+	// Pod and Bound describe the current connection and are refreshed on
+	// every re-establishment.
+	Pod   string   `json:"pod,omitempty"`
+	Bound []string `json:"bound,omitempty"`
+}
+
+func (t forwardTarget) String() string {
+	return fmt.Sprintf("%s/%s", t.Kind, t.Name)
+}
+
+// forwardSession is one port-forward invocation. It is recorded on disk so
+// that "port-forward list" and "port-forward stop" can find it.
+type forwardSession struct {
+	ID        int              `json:"id"`
+	Namespace string           `json:"namespace"`
+	Started   time.Time        `json:"started"`
+	Targets   []*forwardTarget `json:"targets"`
+
+	mu sync.Mutex
+	// lock is held for as long as the session runs, so that "stop" never
+	// signals a process that merely reuses the session's PID.
+	lock *os.File
+}
+
+func newPortForwardCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "port-forward",
+		Short: "Manage port forwards to pods and services",
+		Long: `Start, list and stop port forwards. Each invocation of "start",
+"pod port-forward" or "svc port-forward" is a session that keeps its
+forwards alive until it is stopped, re-establishing them when pods are
+replaced.`,
+	}
+
+	cmd.AddCommand(newPortForwardStartCmd())
+	cmd.AddCommand(newPortForwardListCmd())
+	cmd.AddCommand(newPortForwardStopCmd())
+
+	return cmd
+}
+
+func newPortForwardStartCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "start TYPE/NAME PORT... [TYPE/NAME PORT...]",
+		Short:   "Forward ports to several pods and services at once",
+		Example: `  k8s-admin port-forward start pod/web 8080:80 svc/api 9090:http :5432`,
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			targets, err := parseForwardArgs(args)
+			if err != nil {
+				return err
+			}
+			return runForwardSession(targets)
+		},
+	}
+}
+
+func newPodPortForwardCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "port-forward <name> LOCAL:REMOTE...",
+		Short:   "Forward local ports to a pod",
+		Example: `  k8s-admin pod port-forward my-pod 8080:80 9090:metrics`,
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runForwardSession([]*forwardTarget{{Kind: "pod", Name: args[0], Ports: args[1:]}})
+		},
+	}
+}
+
+func newServicePortForwardCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "port-forward <name> LOCAL:PORT...",
+		Short: "Forward local ports to a ready pod backing a service",
+		Long: `Forward local ports to a service. PORT is a service port number or name;
+it is translated to the target port of a ready pod selected by the service.
+If that pod goes away the forward moves to another ready pod.`,
+		Example: `  k8s-admin svc port-forward my-service 8080:http`,
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runForwardSession([]*forwardTarget{{Kind: "service", Name: args[0], Ports: args[1:]}})
+		},
+	}
+}
+
+func newPortForwardListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List active port-forward sessions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sessions, err := listForwardSessions()
+			if err != nil {
+				return err
+			}
+
+			if len(sessions) == 0 {
+				fmt.Println("No active port-forward sessions")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "ID\tNAMESPACE\tTARGET\tPOD\tPORTS\tAGE\n")
+			for _, s := range sessions {
+				for _, t := range s.Targets {
+					pod, ports := t.Pod, strings.Join(t.Bound, ",")
+					if pod == "" {
+						pod = "<connecting>"
+					}
+					if ports == "" {
+						ports = strings.Join(t.Ports, ",")
+					}
+					fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", s.ID, s.Namespace, t, pod, ports, inspector.FormatAge(s.Started))
+				}
+			}
+			w.Flush()
+			return nil
+		},
+	}
+}
+
+func newPortForwardStopCmd() *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "stop [ID...]",
+		Short: "Stop port-forward sessions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !all {
+				return fmt.Errorf("session ID or --all is required")
+			}
+
+			sessions, err := listForwardSessions()
+			if err != nil {
+				return err
+			}
+
+			wanted := make(map[int]bool)
+			for _, arg := range args {
+				id, err := strconv.Atoi(arg)
+				if err != nil {
+					return fmt.Errorf("invalid session ID: %s", arg)
+				}
+				wanted[id] = true
+			}
+
+			stopped := 0
+			for _, s := range sessions {
+				if !all && !wanted[s.ID] {
+					continue
+				}
+				// The session may have ended since it was listed.
+				if !fileLocked(s.lockPath()) {
+					s.remove()
+					delete(wanted, s.ID)
+					fmt.Printf("Port-forward session %d had already ended\n", s.ID)
+					continue
+				}
+				if err := stopProcess(s.ID); err != nil {
+					return fmt.Errorf("error stopping session %d: %v", s.ID, err)
+				}
+				delete(wanted, s.ID)
+				stopped++
+				fmt.Printf("Port-forward session %d stopped\n", s.ID)
+			}
+
+			if len(wanted) > 0 {
+				var missing []string
+				for id := range wanted {
+					missing = append(missing, strconv.Itoa(id))
+				}
+				sort.Strings(missing)
+				return fmt.Errorf("no active port-forward session with ID %s", strings.Join(missing, ", "))
+			}
+			if stopped == 0 {
+				fmt.Println("No active port-forward sessions")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "stop every active session")
+	return cmd
+}
+
+// parseForwardArgs splits "TYPE/NAME PORT... TYPE/NAME PORT..." into targets.
+func parseForwardArgs(args []string) ([]*forwardTarget, error) {
+	var targets []*forwardTarget
+	for _, arg := range args {
+		if kind, name, ok := strings.Cut(arg, "/"); ok {
+			switch kind {
+			case "pod", "pods", "po":
+				kind = "pod"
+			case "svc", "service", "services":
+				kind = "service"
+			default:
+				return nil, fmt.Errorf("unsupported target type %q: use pod/NAME or svc/NAME", kind)
+			}
+			targets = append(targets, &forwardTarget{Kind: kind, Name: name})
+			continue
+		}
+
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("port %s must follow a pod/NAME or svc/NAME target", arg)
+		}
+		current := targets[len(targets)-1]
+		current.Ports = append(current.Ports, arg)
+	}
+
+	for _, t := range targets {
+		if len(t.Ports) == 0 {
+			return nil, fmt.Errorf("no ports given for %s", t)
+		}
+	}
+	return targets, nil
+}
+
+// runForwardSession keeps every target forwarded until interrupted.
+func runForwardSession(targets []*forwardTarget) error {
+	for _, t := range targets {
+		for _, spec := range t.Ports {
+			if _, _, err := splitPortSpec(spec); err != nil {
+				return err
+			}
+		}
+	}
+
+	config, err := getRestConfig()
+	if err != nil {
+		return err
+	}
+	clientset, err := getClientset()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	session := &forwardSession{
+		ID:        os.Getpid(),
+		Namespace: namespace,
+		Started:   time.Now(),
+		Targets:   targets,
+	}
+	if err := session.register(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: session will not appear in \"port-forward list\": %v\n", err)
+	}
+	defer session.remove()
+
+	fmt.Printf("Port-forward session %d started (Ctrl+C or \"port-forward stop %d\" to end)\n", session.ID, session.ID)
+
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t *forwardTarget) {
+			defer wg.Done()
+			superviseForward(ctx, config, clientset, session, t)
+		}(t)
+	}
+	wg.Wait()
+	return nil
+}
+
+// superviseForward (re-)establishes the forward for a target until ctx is
+// cancelled. Local ports chosen at random on the first connection are kept
+// for later ones so clients can reconnect to the same address.
+func superviseForward(ctx context.Context, config *rest.Config, clientset *kubernetes.Clientset, session *forwardSession, t *forwardTarget) {
+	var mu sync.Mutex
+	pinned := make(map[int]uint16)
+	backoff := forwardPollInterval
+
+	for ctx.Err() == nil {
+		pod, ports, err := resolveForwardTarget(clientset, namespace, t)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v (retrying in %s)\n", t, err, backoff)
+			if !sleepCtx(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, forwardMaxBackoff)
+			continue
+		}
+		backoff = forwardPollInterval
+
+		mu.Lock()
+		for i := range ports {
+			if local, ok := pinned[i]; ok {
+				_, remotePort, _ := strings.Cut(ports[i], ":")
+				ports[i] = fmt.Sprintf("%d:%s", local, remotePort)
+			}
+		}
+		mu.Unlock()
+
+		stopCh := make(chan struct{})
+		var once sync.Once
+		closeStop := func() { once.Do(func() { close(stopCh) }) }
+		// A pod named explicitly is forwarded to while it runs, ready or
+		// not; pods picked for a service or workload must stay ready.
+		go watchForwardedPod(ctx, clientset, pod, t.Kind != "pod", closeStop)
+
+		onReady := func(bound []remote.ForwardedPort) {
+			var desc []string
+			mu.Lock()
+			defer mu.Unlock()
+			for i, p := range bound {
+				pinned[i] = p.Local
+				desc = append(desc, fmt.Sprintf("%d:%d", p.Local, p.Remote))
+				fmt.Printf("%s: forwarding 127.0.0.1:%d -> %s:%d\n", t, p.Local, pod.Name, p.Remote)
+			}
+			session.update(t, pod.Name, desc)
+		}
+
+		err = remote.Forward(config, clientset, namespace, pod.Name, ports, stopCh, onReady, io.Discard, os.Stderr)
+		closeStop()
+		session.update(t, "", nil)
+
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: forward to pod %s ended: %v\n", t, pod.Name, err)
+		}
+		fmt.Printf("%s: re-establishing forward\n", t)
+		if !sleepCtx(ctx, forwardPollInterval) {
+			return
+		}
+	}
+}
+
+// watchForwardedPod calls stop once the pod is deleted, replaced, or, with
+// requireReady, no longer ready, so the supervisor can move the forward
+// elsewhere.
+func watchForwardedPod(ctx context.Context, clientset *kubernetes.Clientset, pod *corev1.Pod, requireReady bool, stop func()) {
+	ticker := time.NewTicker(forwardPollInterval)
+	defer ticker.Stop()
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current, err := clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+			if err != nil || current.UID != pod.UID || current.DeletionTimestamp != nil || (requireReady && !isPodReady(current)) {
+				return
+			}
+		}
+	}
+}
+
+// resolveForwardTarget picks the pod to forward to and translates the
+// target's port specs into numeric LOCAL:CONTAINERPORT pairs.
+func resolveForwardTarget(clientset *kubernetes.Clientset, ns string, t *forwardTarget) (*corev1.Pod, []string, error) {
+	switch t.Kind {
+	case "pod":
+		pod, err := clientset.CoreV1().Pods(ns).Get(context.TODO(), t.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		if pod.Status.Phase != corev1.PodRunning {
+			return nil, nil, fmt.Errorf("pod %s is %s, not Running", pod.Name, pod.Status.Phase)
+		}
+
+		var ports []string
+		for _, spec := range t.Ports {
+			local, remotePort, _ := splitPortSpec(spec)
+			containerPort, err := resolveContainerPort(pod, remotePort)
+			if err != nil {
+				return nil, nil, err
+			}
+			if local == "" && !strings.Contains(spec, ":") {
+				local = strconv.Itoa(int(containerPort))
+			}
+			ports = append(ports, fmt.Sprintf("%s:%d", local, containerPort))
+		}
+		return pod, ports, nil
+
+	case "service":
+		svc, err := clientset.CoreV1().Services(ns).Get(context.TODO(), t.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		pod, err := readyServicePod(clientset, svc)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var ports []string
+		for _, spec := range t.Ports {
+			local, remotePort, _ := splitPortSpec(spec)
+			svcPort, err := findServicePort(svc, remotePort)
+			if err != nil {
+				return nil, nil, err
+			}
+			containerPort, err := serviceTargetPort(pod, svcPort)
+			if err != nil {
+				return nil, nil, err
+			}
+			if local == "" && !strings.Contains(spec, ":") {
+				local = strconv.Itoa(int(svcPort.Port))
+			}
+			ports = append(ports, fmt.Sprintf("%s:%d", local, containerPort))
+		}
+		return pod, ports, nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported target type %s", t.Kind)
+}
+
+// splitPortSpec splits LOCAL:REMOTE, REMOTE or :REMOTE. An empty local port
+// with a colon asks for a random local port.
+func splitPortSpec(spec string) (string, string, error) {
+	local, remotePort, ok := strings.Cut(spec, ":")
+	if !ok {
+		local, remotePort = "", spec
+	}
+	if remotePort == "" {
+		return "", "", fmt.Errorf("invalid port %q: remote port is required", spec)
+	}
+	if local != "" {
+		if n, err := strconv.Atoi(local); err != nil || n < 0 || n > 65535 {
+			return "", "", fmt.Errorf("invalid port %q: local port must be a number between 0 and 65535", spec)
+		}
+	}
+	return local, remotePort, nil
+}
+
+// resolveContainerPort maps a port number or container port name to a
+// numeric container port.
+func resolveContainerPort(pod *corev1.Pod, port string) (int32, error) {
+	if n, err := strconv.Atoi(port); err == nil {
+		if n <= 0 || n > 65535 {
+			return 0, fmt.Errorf("invalid port %s", port)
+		}
+		return int32(n), nil
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == port {
+				return p.ContainerPort, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod %s has no container port named %s", pod.Name, port)
+}
+
+func findServicePort(svc *corev1.Service, port string) (corev1.ServicePort, error) {
+	for _, p := range svc.Spec.Ports {
+		if p.Name == port || strconv.Itoa(int(p.Port)) == port {
+			return p, nil
+		}
+	}
+	return corev1.ServicePort{}, fmt.Errorf("service %s has no port %s", svc.Name, port)
+}
+
+// serviceTargetPort resolves a service port's targetPort on a backing pod.
+func serviceTargetPort(pod *corev1.Pod, svcPort corev1.ServicePort) (int32, error) {
+	if svcPort.TargetPort.String() == "" || svcPort.TargetPort.String() == "0" {
+		return svcPort.Port, nil
+	}
+	return resolveContainerPort(pod, svcPort.TargetPort.String())
+}
+
+// readyServicePod returns a ready, non-terminating pod selected by svc,
+// preferring the one that has been ready the longest.
+func readyServicePod(clientset *kubernetes.Clientset, svc *corev1.Service) (*corev1.Pod, error) {
+	if len(svc.Spec.Selector) == 0 {
+		return nil, fmt.Errorf("service %s has no selector", svc.Name)
+	}
+
+	pods, err := clientset.CoreV1().Pods(svc.Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ready []corev1.Pod
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp == nil && isPodReady(&pod) {
+			ready = append(ready, pod)
+		}
+	}
+	if len(ready) == 0 {
+		return nil, fmt.Errorf("service %s has no ready pods", svc.Name)
+	}
+
+	sort.Slice(ready, func(i, j int) bool {
+		return ready[i].CreationTimestamp.Before(&ready[j].CreationTimestamp)
+	})
+	return &ready[0], nil
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func forwardSessionDir() string {
+	return filepath.Join(homedir.HomeDir(), ".k8s-admin", "port-forwards")
+}
+
+func (s *forwardSession) path() string {
+	return filepath.Join(forwardSessionDir(), fmt.Sprintf("%d.json", s.ID))
+}
+
+func (s *forwardSession) lockPath() string {
+	return filepath.Join(forwardSessionDir(), fmt.Sprintf("%d.lock", s.ID))
+}
+
+// register records the session and takes its lock. A session is only
+// listed while its lock is held.
+func (s *forwardSession) register() error {
+	if err := os.MkdirAll(forwardSessionDir(), 0o700); err != nil {
+		return err
+	}
+	lock, err := lockFile(s.lockPath())
+	if err != nil {
+		return err
+	}
+	s.lock = lock
+	return s.save()
+}
+
+func (s *forwardSession) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(forwardSessionDir(), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(), data, 0o600)
+}
+
+func (s *forwardSession) update(t *forwardTarget, pod string, bound []string) {
+	s.mu.Lock()
+	t.Pod = pod
+	t.Bound = bound
+	s.mu.Unlock()
+	s.save()
+}
+
+func (s *forwardSession) remove() {
+	os.Remove(s.path())
+	if s.lock != nil {
+		s.lock.Close()
+	}
+	os.Remove(s.lockPath())
+}
+
+// listForwardSessions reads the recorded sessions, discarding those whose
+// process has exited without cleaning up, which no longer hold their lock.
+func listForwardSessions() ([]*forwardSession, error) {
+	entries, err := os.ReadDir(forwardSessionDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*forwardSession
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(forwardSessionDir(), entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		s := &forwardSession{}
+		if err := json.Unmarshal(data, s); err != nil {
+			continue
+		}
+		if !fileLocked(s.lockPath()) {
+			s.remove()
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Started.Before(sessions[j].Started) })
+	return sessions, nil
+}
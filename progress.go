@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k8s-admin-cli/inspector"
+	"golang.org/x/term"
+)
+
+const progressBarWidth = 30
+
+// progressWriter counts the bytes written through it and draws a progress
+// bar on a terminal. When the total is unknown only the byte count is shown.
+type progressWriter struct {
+	label   string
+	total   int64
+	out     io.Writer
+	enabled bool
+
+	mu      sync.Mutex
+	written int64
+	drawn   time.Time
+}
+
+func newProgressWriter(label string, total int64) *progressWriter {
+	return &progressWriter{
+		label:   label,
+		total:   total,
+		out:     os.Stderr,
+		enabled: term.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.written += int64(len(b))
+	if p.enabled && time.Since(p.drawn) > 100*time.Millisecond {
+		p.draw()
+	}
+	return len(b), nil
+}
+
+// Done draws the final state and moves to a new line.
+func (p *progressWriter) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.enabled {
+		return
+	}
+	if p.total > 0 && p.written > p.total {
+		p.total = p.written
+	}
+	p.draw()
+	fmt.Fprintln(p.out)
+}
+
+func (p *progressWriter) draw() {
+	p.drawn = time.Now()
+	if p.total <= 0 {
+		fmt.Fprintf(p.out, "\r%s %s", p.label, inspector.FormatBytes(p.written))
+		return
+	}
+
+	ratio := float64(p.written) / float64(p.total)
+	if ratio > 1 {
+		ratio = 1
+	}
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	fmt.Fprintf(p.out, "\r%s [%s] %3.0f%% %s/%s", p.label, bar, ratio*100, inspector.FormatBytes(p.written), inspector.FormatBytes(p.total))
+}
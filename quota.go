@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func newQuotaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "quota",
+		Aliases: []string{"quotas", "resourcequota"},
+		Short:   "Inspect resource quotas and limit ranges",
+		Long:    `Report how much of their resource quotas namespaces use and the limit range defaults they apply.`,
+	}
+
+	cmd.AddCommand(newQuotaReportCmd())
+
+	return cmd
+}
+
+func newQuotaReportCmd() *cobra.Command {
+	var (
+		allNamespaces bool
+		thresholds    inspector.QuotaThresholds
+		top           int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Show quota usage, limit range defaults and the largest consumers",
+		Long: `Show, for each namespace, every ResourceQuota resource with its used and
+hard value and a usage bar, the defaults, minimums and maximums of the
+LimitRanges, and the workloads whose running pods use the largest share of
+the quota.
+
+Resources at or above --warning percent are marked WARNING, at or above
+--critical percent CRITICAL, and listed again below the report.`,
+		Example: `  k8s-admin quota report
+  k8s-admin quota report -A --warning 70 --critical 90 --top 10`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if thresholds.Warning > thresholds.Critical {
+				return fmt.Errorf("--warning (%.0f) must not be above --critical (%.0f)", thresholds.Warning, thresholds.Critical)
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			ns := namespace
+			if allNamespaces {
+				ns = metav1.NamespaceAll
+			}
+			quotas, err := clientset.CoreV1().ResourceQuotas(ns).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("error listing resource quotas: %v", err)
+			}
+			limitRanges, err := clientset.CoreV1().LimitRanges(ns).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("error listing limit ranges: %v", err)
+			}
+			pods, err := clientset.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("error listing pods: %v", err)
+			}
+
+			quotasByNS := map[string][]corev1.ResourceQuota{}
+			for _, q := range quotas.Items {
+				quotasByNS[q.Namespace] = append(quotasByNS[q.Namespace], q)
+			}
+			limitRangesByNS := map[string][]corev1.LimitRange{}
+			for _, lr := range limitRanges.Items {
+				limitRangesByNS[lr.Namespace] = append(limitRangesByNS[lr.Namespace], lr)
+			}
+			podsByNS := map[string][]corev1.Pod{}
+			for _, p := range pods.Items {
+				podsByNS[p.Namespace] = append(podsByNS[p.Namespace], p)
+			}
+
+			var namespaces []string
+			if allNamespaces {
+				seen := map[string]bool{}
+				for ns := range quotasByNS {
+					seen[ns] = true
+				}
+				for ns := range limitRangesByNS {
+					seen[ns] = true
+				}
+				for ns := range seen {
+					namespaces = append(namespaces, ns)
+				}
+				sort.Strings(namespaces)
+				if len(namespaces) == 0 {
+					fmt.Println("No resource quotas or limit ranges found")
+					return nil
+				}
+			} else {
+				if len(quotasByNS[namespace]) == 0 && len(limitRangesByNS[namespace]) == 0 {
+					fmt.Printf("No resource quotas or limit ranges found in namespace %s\n", namespace)
+					return nil
+				}
+				namespaces = []string{namespace}
+			}
+
+			var warnings []string
+			for i, ns := range namespaces {
+				if i > 0 {
+					fmt.Println()
+				}
+				consumers := inspector.TopQuotaConsumers(podsByNS[ns], quotasByNS[ns], limitRangesByNS[ns], top)
+				report, nsWarnings := inspector.FormatQuotaReport(ns, quotasByNS[ns], limitRangesByNS[ns], consumers, thresholds)
+				fmt.Print(report)
+				warnings = append(warnings, nsWarnings...)
+			}
+
+			if len(warnings) > 0 {
+				fmt.Println("\nWarnings:")
+				for _, w := range warnings {
+					fmt.Printf("  - %s\n", w)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "report the quotas of every namespace")
+	cmd.Flags().Float64Var(&thresholds.Warning, "warning", 80, "mark quota resources at or above this usage percentage as WARNING")
+	cmd.Flags().Float64Var(&thresholds.Critical, "critical", 95, "mark quota resources at or above this usage percentage as CRITICAL")
+	cmd.Flags().IntVar(&top, "top", 5, "number of workloads to list as the largest consumers (0 for all)")
+	return cmd
+}
+
+// checkPodQuota fails with a readable message when the quotas of the pod's
+// namespace would reject it, instead of leaving it to the API error. Users
+// who may not read quotas or limit ranges skip the check and get the API
+// server's verdict.
+func checkPodQuota(clientset *kubernetes.Clientset, pod *corev1.Pod) error {
+	quotas, err := clientset.CoreV1().ResourceQuotas(pod.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if apierrors.IsForbidden(err) {
+		fmt.Fprintf(os.Stderr, "Warning: not allowed to read the resource quotas of namespace %s; skipping the quota check\n", pod.Namespace)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error listing resource quotas: %v", err)
+	}
+	if len(quotas.Items) == 0 {
+		return nil
+	}
+	limitRanges, err := clientset.CoreV1().LimitRanges(pod.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if apierrors.IsForbidden(err) {
+		fmt.Fprintf(os.Stderr, "Warning: not allowed to read the limit ranges of namespace %s; skipping the quota check\n", pod.Namespace)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error listing limit ranges: %v", err)
+	}
+
+	problems := inspector.CheckPodQuota(pod, quotas.Items, limitRanges.Items)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("pod %s would be rejected by the resource quota of namespace %s:\n  - %s\nRun 'k8s-admin quota report -n %s' to see the current usage",
+		pod.Name, pod.Namespace, strings.Join(problems, "\n  - "), pod.Namespace)
+}
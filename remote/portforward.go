@@ -0,0 +1,54 @@
+package remote
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// ForwardedPort is a local port bound to a port of the pod.
+type ForwardedPort = portforward.ForwardedPort
+
+// Forward listens on the local side of each "LOCAL:REMOTE" pair in ports and
+// tunnels connections to the pod. It blocks until stop is closed or the
+// connection to the pod is lost. onReady, if set, receives the bound ports
+// once listening, which is how callers learn randomly assigned local ports.
+func Forward(config *rest.Config, clientset *kubernetes.Clientset, namespace, pod string, ports []string, stop <-chan struct{}, onReady func([]ForwardedPort), out, errOut io.Writer) error {
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return fmt.Errorf("error creating round tripper: %v", err)
+	}
+
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	ready := make(chan struct{})
+	fw, err := portforward.New(dialer, ports, stop, ready, out, errOut)
+	if err != nil {
+		return err
+	}
+
+	if onReady != nil {
+		go func() {
+			select {
+			case <-ready:
+				bound, err := fw.GetPorts()
+				if err == nil {
+					onReady(bound)
+				}
+			case <-stop:
+			}
+		}()
+	}
+
+	return fw.ForwardPorts()
+}
@@ -0,0 +1,127 @@
+// Package remote runs interactive sessions against pod containers through the
+// API server's exec and attach subresources.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// StreamOptions describes which container to connect to and how to wire up
+// its standard streams.
+type StreamOptions struct {
+	Namespace string
+	Pod       string
+	Container string
+	Command   []string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// TTY allocates a terminal in the container. Stdin must then be a
+	// terminal; it is switched to raw mode for the duration of the session
+	// and resize events are forwarded to the container.
+	TTY bool
+}
+
+// Exec runs opts.Command inside a container and streams its I/O.
+func Exec(ctx context.Context, config *rest.Config, clientset *kubernetes.Clientset, opts StreamOptions) error {
+	if len(opts.Command) == 0 {
+		return fmt.Errorf("a command is required")
+	}
+
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(opts.Namespace).
+		Name(opts.Pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: opts.Container,
+			Command:   opts.Command,
+			Stdin:     opts.Stdin != nil,
+			Stdout:    opts.Stdout != nil,
+			Stderr:    opts.Stderr != nil && !opts.TTY,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
+
+	return stream(ctx, config, req, opts)
+}
+
+// Attach connects to the main process of a running container.
+func Attach(ctx context.Context, config *rest.Config, clientset *kubernetes.Clientset, opts StreamOptions) error {
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(opts.Namespace).
+		Name(opts.Pod).
+		SubResource("attach").
+		VersionedParams(&corev1.PodAttachOptions{
+			Container: opts.Container,
+			Stdin:     opts.Stdin != nil,
+			Stdout:    opts.Stdout != nil,
+			Stderr:    opts.Stderr != nil && !opts.TTY,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
+
+	return stream(ctx, config, req, opts)
+}
+
+func stream(ctx context.Context, config *rest.Config, req *rest.Request, opts StreamOptions) error {
+	executor, err := newExecutor(config, req)
+	if err != nil {
+		return err
+	}
+
+	streamOpts := remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Tty:    opts.TTY,
+	}
+	if !opts.TTY {
+		streamOpts.Stderr = opts.Stderr
+	}
+
+	if !opts.TTY {
+		return executor.StreamWithContext(ctx, streamOpts)
+	}
+
+	term, err := newTerminal(opts.Stdin)
+	if err != nil {
+		return err
+	}
+	return term.run(ctx, func(sizes remotecommand.TerminalSizeQueue) error {
+		streamOpts.TerminalSizeQueue = sizes
+		return executor.StreamWithContext(ctx, streamOpts)
+	})
+}
+
+// newExecutor prefers the WebSocket protocol and falls back to SPDY when the
+// API server does not support it.
+func newExecutor(config *rest.Config, req *rest.Request) (remotecommand.Executor, error) {
+	spdyExec, err := remotecommand.NewSPDYExecutor(config, http.MethodPost, req.URL())
+	if err != nil {
+		return nil, fmt.Errorf("error creating SPDY executor: %v", err)
+	}
+
+	wsExec, err := remotecommand.NewWebSocketExecutor(config, http.MethodGet, req.URL().String())
+	if err != nil {
+		return nil, fmt.Errorf("error creating WebSocket executor: %v", err)
+	}
+
+	return remotecommand.NewFallbackExecutor(wsExec, spdyExec, httpstream.IsUpgradeFailure)
+}
+
+// IsTerminal reports whether r is an interactive terminal.
+func IsTerminal(r io.Reader) bool {
+	_, ok := terminalFd(r)
+	return ok
+}
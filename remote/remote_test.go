@@ -0,0 +1,467 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	remotecommandconsts "k8s.io/apimachinery/pkg/util/remotecommand"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// execServer is a stand-in for the API server's exec and attach
+// subresources. It echoes stdin to stdout prefixed with "out:", writes
+// "err" to stderr and ends the session with exitCode. Unless websocket is
+// set it rejects WebSocket upgrades, as API servers before 1.30 do.
+type execServer struct {
+	websocket   bool
+	exitCode    int
+	wantResizes int
+
+	mu       sync.Mutex
+	requests []string
+	resizes  []remotecommand.TerminalSize
+}
+
+// session is one connection's view of the remote command streams. Unused
+// streams are nil.
+type session struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	errors io.Writer
+	resize io.Reader
+}
+
+func (s *execServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+	s.mu.Unlock()
+
+	if websocket.IsWebSocketUpgrade(r) {
+		if !s.websocket {
+			http.Error(w, "websocket not supported", http.StatusBadRequest)
+			return
+		}
+		s.serveWebSocket(w, r)
+		return
+	}
+	s.serveSPDY(w, r)
+}
+
+func (s *execServer) serveSPDY(w http.ResponseWriter, r *http.Request) {
+	if _, err := httpstream.Handshake(r, w, []string{remotecommandconsts.StreamProtocolV4Name}); err != nil {
+		return
+	}
+
+	streams := make(chan httpstream.Stream, 5)
+	conn := spdy.NewResponseUpgrader().UpgradeResponse(w, r, func(stream httpstream.Stream, _ <-chan struct{}) error {
+		streams <- stream
+		return nil
+	})
+	if conn == nil {
+		return
+	}
+	defer conn.Close()
+
+	// The client opens the error stream and one stream per requested
+	// standard stream, plus a resize stream for terminals.
+	expected := 1
+	for _, param := range []string{"stdin", "stdout", "stderr", "tty"} {
+		if r.URL.Query().Get(param) == "true" {
+			expected++
+		}
+	}
+	byType := map[string]httpstream.Stream{}
+	for len(byType) < expected {
+		select {
+		case stream := <-streams:
+			byType[stream.Headers().Get(corev1.StreamType)] = stream
+		case <-time.After(5 * time.Second):
+			return
+		}
+	}
+
+	var sess session
+	if st, ok := byType[corev1.StreamTypeStdin]; ok {
+		sess.stdin = st
+	}
+	if st, ok := byType[corev1.StreamTypeStdout]; ok {
+		sess.stdout = st
+	}
+	if st, ok := byType[corev1.StreamTypeStderr]; ok {
+		sess.stderr = st
+	}
+	if st, ok := byType[corev1.StreamTypeResize]; ok {
+		sess.resize = st
+	}
+	sess.errors = byType[corev1.StreamTypeError]
+
+	s.run(sess, func() {
+		for _, t := range []string{corev1.StreamTypeStdout, corev1.StreamTypeStderr} {
+			if st, ok := byType[t]; ok {
+				st.Close()
+			}
+		}
+	})
+	byType[corev1.StreamTypeError].Close()
+}
+
+// wsChannel writes to one channel of the v5 WebSocket protocol.
+type wsChannel struct {
+	conn *websocket.Conn
+	id   byte
+}
+
+func (c wsChannel) Write(p []byte) (int, error) {
+	if err := c.conn.WriteMessage(websocket.BinaryMessage, append([]byte{c.id}, p...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *execServer) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{remotecommandconsts.StreamProtocolV5Name}}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	stdinR, stdinW := io.Pipe()
+	resizeR, resizeW := io.Pipe()
+	go func() {
+		defer stdinW.Close()
+		defer resizeW.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil || len(data) == 0 {
+				return
+			}
+			switch data[0] {
+			case 0:
+				stdinW.Write(data[1:])
+			case 4:
+				resizeW.Write(data[1:])
+			case remotecommandconsts.StreamClose:
+				if len(data) > 1 && data[1] == 0 {
+					stdinW.Close()
+				}
+			}
+		}
+	}()
+
+	sess := session{
+		stdout: wsChannel{conn, 1},
+		errors: wsChannel{conn, 3},
+	}
+	query := r.URL.Query()
+	if query.Get("stdin") == "true" {
+		sess.stdin = stdinR
+	}
+	if query.Get("stderr") == "true" {
+		sess.stderr = wsChannel{conn, 2}
+	}
+	if query.Get("tty") == "true" {
+		sess.resize = resizeR
+	}
+
+	s.run(sess, func() {})
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
+
+// run plays the remote command. closeOutput is called once stdout and
+// stderr are written, before the exit status is sent.
+func (s *execServer) run(sess session, closeOutput func()) {
+	resized := make(chan struct{})
+	if sess.resize != nil {
+		go func() {
+			decoder := json.NewDecoder(sess.resize)
+			for {
+				var size remotecommand.TerminalSize
+				if err := decoder.Decode(&size); err != nil {
+					return
+				}
+				s.mu.Lock()
+				s.resizes = append(s.resizes, size)
+				done := len(s.resizes) == s.wantResizes
+				s.mu.Unlock()
+				if done {
+					close(resized)
+				}
+			}
+		}()
+	}
+
+	var input []byte
+	if sess.stdin != nil {
+		input, _ = io.ReadAll(sess.stdin)
+	}
+	if sess.stdout != nil {
+		sess.stdout.Write(append([]byte("out:"), input...))
+	}
+	if sess.stderr != nil {
+		sess.stderr.Write([]byte("err"))
+	}
+	if s.wantResizes > 0 {
+		select {
+		case <-resized:
+		case <-time.After(5 * time.Second):
+		}
+	}
+	closeOutput()
+
+	status := metav1.Status{Status: metav1.StatusSuccess}
+	if s.exitCode != 0 {
+		status = metav1.Status{
+			Status: metav1.StatusFailure,
+			Reason: remotecommandconsts.NonZeroExitCodeReason,
+			Details: &metav1.StatusDetails{
+				Causes: []metav1.StatusCause{{Type: remotecommandconsts.ExitCodeCauseType, Message: strconv.Itoa(s.exitCode)}},
+			},
+		}
+	}
+	data, _ := json.Marshal(status)
+	sess.errors.Write(data)
+}
+
+func (s *execServer) methods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var methods []string
+	for _, r := range s.requests {
+		methods = append(methods, strings.SplitN(r, " ", 2)[0])
+	}
+	return methods
+}
+
+func startExecServer(t *testing.T, s *execServer) (*rest.Config, *kubernetes.Clientset) {
+	t.Helper()
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	config := &rest.Config{Host: srv.URL}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatalf("creating clientset: %v", err)
+	}
+	return config, clientset
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestExecFallsBackToSPDY(t *testing.T) {
+	server := &execServer{}
+	config, clientset := startExecServer(t, server)
+
+	var stdout, stderr bytes.Buffer
+	err := Exec(testContext(t), config, clientset, StreamOptions{
+		Namespace: "shop",
+		Pod:       "web-0",
+		Container: "app",
+		Command:   []string{"cat"},
+		Stdin:     strings.NewReader("hello"),
+		Stdout:    &stdout,
+		Stderr:    &stderr,
+	})
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if got := stdout.String(); got != "out:hello" {
+		t.Errorf("stdout = %q, want %q", got, "out:hello")
+	}
+	if got := stderr.String(); got != "err" {
+		t.Errorf("stderr = %q, want %q", got, "err")
+	}
+
+	// The WebSocket attempt is rejected before the SPDY upgrade is tried.
+	if got := strings.Join(server.methods(), ","); got != "GET,POST" {
+		t.Errorf("requests = %s, want GET,POST", got)
+	}
+	for _, r := range server.requests {
+		if !strings.Contains(r, "/api/v1/namespaces/shop/pods/web-0/exec?") || !strings.Contains(r, "container=app") || !strings.Contains(r, "command=cat") {
+			t.Errorf("unexpected request %s", r)
+		}
+	}
+}
+
+func TestExecOverWebSocket(t *testing.T) {
+	server := &execServer{websocket: true}
+	config, clientset := startExecServer(t, server)
+
+	var stdout, stderr bytes.Buffer
+	err := Exec(testContext(t), config, clientset, StreamOptions{
+		Namespace: "shop",
+		Pod:       "web-0",
+		Command:   []string{"cat"},
+		Stdin:     strings.NewReader("hello"),
+		Stdout:    &stdout,
+		Stderr:    &stderr,
+	})
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if got := stdout.String(); got != "out:hello" {
+		t.Errorf("stdout = %q, want %q", got, "out:hello")
+	}
+	if got := stderr.String(); got != "err" {
+		t.Errorf("stderr = %q, want %q", got, "err")
+	}
+	if got := strings.Join(server.methods(), ","); got != "GET" {
+		t.Errorf("requests = %s, want a single WebSocket GET", got)
+	}
+}
+
+func TestExecPropagatesExitCode(t *testing.T) {
+	for _, ws := range []bool{false, true} {
+		server := &execServer{websocket: ws, exitCode: 3}
+		config, clientset := startExecServer(t, server)
+
+		var stdout bytes.Buffer
+		err := Exec(testContext(t), config, clientset, StreamOptions{
+			Namespace: "shop",
+			Pod:       "web-0",
+			Command:   []string{"false"},
+			Stdout:    &stdout,
+		})
+
+		var exitErr exec.CodeExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("websocket=%v: Exec error = %v, want an exit code", ws, err)
+		}
+		if exitErr.ExitStatus() != 3 {
+			t.Errorf("websocket=%v: exit status = %d, want 3", ws, exitErr.ExitStatus())
+		}
+	}
+}
+
+func TestExecRequiresCommand(t *testing.T) {
+	server := &execServer{}
+	config, clientset := startExecServer(t, server)
+
+	if err := Exec(testContext(t), config, clientset, StreamOptions{Namespace: "shop", Pod: "web-0"}); err == nil {
+		t.Fatal("Exec without a command succeeded")
+	}
+	if len(server.requests) != 0 {
+		t.Errorf("requests = %v, want none", server.requests)
+	}
+}
+
+func TestAttach(t *testing.T) {
+	server := &execServer{}
+	config, clientset := startExecServer(t, server)
+
+	var stdout, stderr bytes.Buffer
+	err := Attach(testContext(t), config, clientset, StreamOptions{
+		Namespace: "shop",
+		Pod:       "web-0",
+		Container: "app",
+		Stdin:     strings.NewReader("ping"),
+		Stdout:    &stdout,
+		Stderr:    &stderr,
+	})
+	if err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	if got := stdout.String(); got != "out:ping" {
+		t.Errorf("stdout = %q, want %q", got, "out:ping")
+	}
+	for _, r := range server.requests {
+		if !strings.Contains(r, "/api/v1/namespaces/shop/pods/web-0/attach?") || !strings.Contains(r, "container=app") {
+			t.Errorf("unexpected request %s", r)
+		}
+	}
+}
+
+func TestTTYRequiresTerminal(t *testing.T) {
+	server := &execServer{}
+	config, clientset := startExecServer(t, server)
+
+	err := Exec(testContext(t), config, clientset, StreamOptions{
+		Namespace: "shop",
+		Pod:       "web-0",
+		Command:   []string{"sh"},
+		Stdin:     strings.NewReader(""),
+		Stdout:    io.Discard,
+		TTY:       true,
+	})
+	if !errors.Is(err, errNotTerminal) {
+		t.Fatalf("Exec error = %v, want %v", err, errNotTerminal)
+	}
+	if len(server.requests) != 0 {
+		t.Errorf("requests = %v, want none", server.requests)
+	}
+}
+
+// TestResize streams the sizes of a sizeQueue, as a terminal session does,
+// and checks that they reach the container over either protocol.
+func TestResize(t *testing.T) {
+	for _, ws := range []bool{false, true} {
+		server := &execServer{websocket: ws, wantResizes: 2}
+		config, clientset := startExecServer(t, server)
+		ctx := testContext(t)
+
+		req := clientset.CoreV1().RESTClient().Post().
+			Resource("pods").Namespace("shop").Name("web-0").SubResource("exec").
+			VersionedParams(&corev1.PodExecOptions{Command: []string{"sh"}, Stdout: true, TTY: true}, scheme.ParameterCodec)
+		executor, err := newExecutor(config, req)
+		if err != nil {
+			t.Fatalf("newExecutor: %v", err)
+		}
+
+		queueCtx, cancel := context.WithCancel(ctx)
+		queue := &sizeQueue{ctx: queueCtx, sizes: make(chan remotecommand.TerminalSize, 2)}
+		queue.sizes <- remotecommand.TerminalSize{Width: 80, Height: 24}
+		queue.sizes <- remotecommand.TerminalSize{Width: 120, Height: 40}
+
+		var stdout bytes.Buffer
+		err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+			Stdout:            &stdout,
+			Tty:               true,
+			TerminalSizeQueue: queue,
+		})
+		cancel()
+		if err != nil {
+			t.Fatalf("websocket=%v: stream: %v", ws, err)
+		}
+
+		server.mu.Lock()
+		got := server.resizes
+		server.mu.Unlock()
+		want := []remotecommand.TerminalSize{{Width: 80, Height: 24}, {Width: 120, Height: 40}}
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("websocket=%v: resizes = %v, want %v", ws, got, want)
+		}
+	}
+}
+
+func TestSizeQueueStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := &sizeQueue{ctx: ctx, sizes: make(chan remotecommand.TerminalSize)}
+	cancel()
+	if size := queue.Next(); size != nil {
+		t.Errorf("Next after cancel = %v, want nil", size)
+	}
+}
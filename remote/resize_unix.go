@@ -0,0 +1,35 @@
+//go:build !windows
+
+package remote
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// resizeEvents emits a value whenever the process receives SIGWINCH.
+func resizeEvents(ctx context.Context, _ *terminal) <-chan struct{} {
+	events := make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+
+	go func() {
+		defer signal.Stop(sigs)
+		defer close(events)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigs:
+				select {
+				case events <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package remote
+
+import (
+	"context"
+	"time"
+)
+
+// resizeEvents polls the console size since Windows has no SIGWINCH.
+func resizeEvents(ctx context.Context, t *terminal) <-chan struct{} {
+	events := make(chan struct{})
+
+	go func() {
+		defer close(events)
+		last := t.size()
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				size := t.size()
+				if size == nil || (last != nil && *size == *last) {
+					continue
+				}
+				last = size
+				select {
+				case events <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events
+}
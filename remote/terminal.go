@@ -0,0 +1,100 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+var errNotTerminal = errors.New("unable to use a TTY: input is not a terminal")
+
+// terminal puts the local terminal into raw mode and reports its size to the
+// remote side.
+type terminal struct {
+	fd int
+}
+
+func newTerminal(in io.Reader) (*terminal, error) {
+	fd, ok := terminalFd(in)
+	if !ok {
+		return nil, errNotTerminal
+	}
+	return &terminal{fd: fd}, nil
+}
+
+// run calls fn with the terminal in raw mode and restores it afterwards.
+func (t *terminal) run(ctx context.Context, fn func(remotecommand.TerminalSizeQueue) error) error {
+	state, err := term.MakeRaw(t.fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(t.fd, state)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return fn(newSizeQueue(ctx, t.fd))
+}
+
+func (t *terminal) size() *remotecommand.TerminalSize {
+	width, height, err := term.GetSize(t.fd)
+	if err != nil {
+		return nil
+	}
+	return &remotecommand.TerminalSize{Width: uint16(width), Height: uint16(height)}
+}
+
+// sizeQueue delivers the initial terminal size followed by every change.
+type sizeQueue struct {
+	ctx   context.Context
+	sizes chan remotecommand.TerminalSize
+}
+
+func (q *sizeQueue) Next() *remotecommand.TerminalSize {
+	select {
+	case size, ok := <-q.sizes:
+		if !ok {
+			return nil
+		}
+		return &size
+	case <-q.ctx.Done():
+		return nil
+	}
+}
+
+func newSizeQueue(ctx context.Context, fd int) *sizeQueue {
+	q := &sizeQueue{ctx: ctx, sizes: make(chan remotecommand.TerminalSize, 1)}
+	t := &terminal{fd: fd}
+
+	send := func() {
+		size := t.size()
+		if size == nil {
+			return
+		}
+		select {
+		case q.sizes <- *size:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		send()
+		for range resizeEvents(ctx, t) {
+			send()
+		}
+	}()
+	return q
+}
+
+func terminalFd(r io.Reader) (int, bool) {
+	f, ok := r.(*os.File)
+	if !ok {
+		return 0, false
+	}
+	fd := int(f.Fd())
+	return fd, term.IsTerminal(fd)
+}
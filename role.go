@@ -3,11 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func newRoleCmd() *cobra.Command {
@@ -25,27 +28,64 @@ func newRoleCmd() *cobra.Command {
 }
 
 func newRoleListCmd() *cobra.Command {
-	return &cobra.Command{
+	var (
+		watchRoles bool
+		output     string
+	)
+
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List roles",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
 			clientset, err := getClientset()
 			if err != nil {
 				return err
 			}
 
+			if watchRoles {
+				roles := clientset.RbacV1().Roles(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						return roles.List(ctx, opts)
+					},
+					watch: roles.Watch,
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]rbacv1.Role, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*rbacv1.Role))
+						}
+						writeRoleList(w, items)
+						return nil
+					},
+				}, output)
+			}
+
 			roles, err := clientset.RbacV1().Roles(namespace).List(context.TODO(), metav1.ListOptions{})
 			if err != nil {
 				return err
 			}
 
-			fmt.Printf("Roles in namespace %s:\n", namespace)
-			for _, role := range roles.Items {
-				fmt.Printf("- %s\n", role.Name)
+			if output == "json" {
+				return printObject(roles, output)
 			}
+			writeRoleList(os.Stdout, roles.Items)
 			return nil
 		},
 	}
+
+	addWatchFlags(cmd, &watchRoles, &output)
+	return cmd
+}
+
+func writeRoleList(w io.Writer, roles []rbacv1.Role) {
+	fmt.Fprintf(w, "Roles in namespace %s:\n", namespace)
+	for _, role := range roles {
+		fmt.Fprintf(w, "- %s\n", role.Name)
+	}
 }
 
 func newRoleCreateCmd() *cobra.Command {
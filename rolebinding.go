@@ -3,11 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func newRoleBindingCmd() *cobra.Command {
@@ -25,30 +28,67 @@ func newRoleBindingCmd() *cobra.Command {
 }
 
 func newRoleBindingListCmd() *cobra.Command {
-	return &cobra.Command{
+	var (
+		watchBindings bool
+		output        string
+	)
+
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List role bindings",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
 			clientset, err := getClientset()
 			if err != nil {
 				return err
 			}
 
+			if watchBindings {
+				rbs := clientset.RbacV1().RoleBindings(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						return rbs.List(ctx, opts)
+					},
+					watch: rbs.Watch,
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]rbacv1.RoleBinding, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*rbacv1.RoleBinding))
+						}
+						writeRoleBindingList(w, items)
+						return nil
+					},
+				}, output)
+			}
+
 			rbs, err := clientset.RbacV1().RoleBindings(namespace).List(context.TODO(), metav1.ListOptions{})
 			if err != nil {
 				return err
 			}
 
-			fmt.Printf("Role bindings in namespace %s:\n", namespace)
-			for _, rb := range rbs.Items {
-				fmt.Printf("- %s (Role: %s)\n", rb.Name, rb.RoleRef.Name)
-				for _, subject := range rb.Subjects {
-					fmt.Printf("  Subject: %s (%s)\n", subject.Name, subject.Kind)
-				}
+			if output == "json" {
+				return printObject(rbs, output)
 			}
+			writeRoleBindingList(os.Stdout, rbs.Items)
 			return nil
 		},
 	}
+
+	addWatchFlags(cmd, &watchBindings, &output)
+	return cmd
+}
+
+func writeRoleBindingList(w io.Writer, rbs []rbacv1.RoleBinding) {
+	fmt.Fprintf(w, "Role bindings in namespace %s:\n", namespace)
+	for _, rb := range rbs {
+		fmt.Fprintf(w, "- %s (Role: %s)\n", rb.Name, rb.RoleRef.Name)
+		for _, subject := range rb.Subjects {
+			fmt.Fprintf(w, "  Subject: %s (%s)\n", subject.Name, subject.Kind)
+		}
+	}
 }
 
 func newRoleBindingCreateCmd() *cobra.Command {
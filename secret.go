@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/yaml"
+)
+
+// secretMask replaces secret values unless --reveal is given.
+const secretMask = "********"
+
+func newSecretCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "secret",
+		Aliases: []string{"secrets"},
+		Short:   "Manage secrets",
+		Long: `List, create, view, edit and diff secrets, and restart the workloads that use
+them. Values are decoded from base64 for display and masked unless --reveal
+is given.`,
+	}
+
+	cmd.AddCommand(newSecretListCmd())
+	cmd.AddCommand(newSecretCreateCmd())
+	cmd.AddCommand(newSecretViewCmd())
+	cmd.AddCommand(newSecretEditCmd())
+	cmd.AddCommand(newSecretDiffCmd())
+	cmd.AddCommand(newRestartConsumersCmd("secret", "Secret"))
+
+	return cmd
+}
+
+func newSecretListCmd() *cobra.Command {
+	var (
+		selector string
+		watching bool
+		output   string
+		reveal   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List secrets",
+		Long: `List the secrets of the namespace with their type and number of keys.
+
+With -o json the values are blanked, and the last-applied-configuration
+annotation that may hold them is dropped, unless --reveal is given.`,
+		Example: `  k8s-admin secret list
+  k8s-admin secret list -l app=web
+  k8s-admin secret list -o json --reveal`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			if watching {
+				secrets := clientset.CoreV1().Secrets(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						opts.LabelSelector = selector
+						list, err := secrets.List(ctx, opts)
+						if err == nil && !reveal {
+							for i := range list.Items {
+								maskSecret(&list.Items[i])
+							}
+						}
+						return list, err
+					},
+					watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+						opts.LabelSelector = selector
+						w, err := secrets.Watch(ctx, opts)
+						if err != nil || reveal {
+							return w, err
+						}
+						return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+							if secret, ok := event.Object.(*corev1.Secret); ok {
+								maskSecret(secret)
+							}
+							return event, true
+						}), nil
+					},
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]corev1.Secret, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*corev1.Secret))
+						}
+						_, err := io.WriteString(w, inspector.FormatSecretTable(items, false))
+						return err
+					},
+				}, output)
+			}
+
+			secrets, err := clientset.CoreV1().Secrets(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				if !reveal {
+					for i := range secrets.Items {
+						maskSecret(&secrets.Items[i])
+					}
+				}
+				return printObject(secrets, output)
+			}
+			if len(secrets.Items) == 0 {
+				fmt.Printf("No secrets found in namespace %s\n", namespace)
+				return nil
+			}
+			fmt.Print(inspector.FormatSecretTable(secrets.Items, false))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter secrets")
+	cmd.Flags().BoolVar(&reveal, "reveal", false, "include the secret values in the json output")
+	addWatchFlags(cmd, &watching, &output)
+	return cmd
+}
+
+// maskSecret blanks the values of a secret, keeping its keys, and drops the
+// last-applied-configuration annotation, which holds the applied values.
+func maskSecret(secret *corev1.Secret) {
+	for k := range secret.Data {
+		secret.Data[k] = []byte{}
+	}
+	for k := range secret.StringData {
+		secret.StringData[k] = ""
+	}
+	delete(secret.Annotations, corev1.LastAppliedConfigAnnotation)
+}
+
+func newSecretCreateCmd() *cobra.Command {
+	var (
+		sources    dataSources
+		secretType string
+		dryRun     string
+		output     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a secret from literals and files",
+		Long: `Create a secret from any combination of literal values, files, whole
+directories and env files. Values are base64-encoded by the API, not by you.
+
+--dry-run=client prints the secret instead of creating it; note that the
+printed manifest contains the values.`,
+		Example: `  k8s-admin secret create db-credentials --from-literal username=app --from-file password=./db-password
+  k8s-admin secret create web-tls --type kubernetes.io/tls --from-file tls.crt=cert.pem --from-file tls.key=key.pem
+  k8s-admin secret create app-env --from-env-file .env`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateCreateFlags(dryRun, output); err != nil {
+				return err
+			}
+			data, err := sources.collect()
+			if err != nil {
+				return err
+			}
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: args[0], Namespace: namespace},
+				Type:       corev1.SecretType(secretType),
+				Data:       data,
+			}
+
+			if dryRun == "client" {
+				return printObject(secret, output)
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			createOptions := metav1.CreateOptions{}
+			if dryRun == "server" {
+				createOptions.DryRun = []string{metav1.DryRunAll}
+			}
+			secret, err = clientset.CoreV1().Secrets(namespace).Create(context.TODO(), secret, createOptions)
+			if err != nil {
+				return fmt.Errorf("error creating secret: %v", err)
+			}
+
+			if output != "" {
+				return printObject(secret, output)
+			}
+			if dryRun == "server" {
+				fmt.Printf("Secret %s validated in namespace %s (server dry run)\n", secret.Name, secret.Namespace)
+				return nil
+			}
+			fmt.Printf("Secret %s created in namespace %s with %d key(s)\n", secret.Name, secret.Namespace, len(data))
+			return nil
+		},
+	}
+
+	sources.register(cmd)
+	cmd.Flags().StringVar(&secretType, "type", string(corev1.SecretTypeOpaque), "Secret type (e.g. kubernetes.io/tls, kubernetes.io/dockerconfigjson)")
+	cmd.Flags().StringVar(&dryRun, "dry-run", "none", "Only print (client) or validate on the server (server) instead of creating")
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = "client"
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Print the secret as yaml or json")
+	return cmd
+}
+
+func newSecretViewCmd() *cobra.Command {
+	var (
+		key    string
+		reveal bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "view <name>",
+		Short: "Show the decoded keys of a secret",
+		Long: `Show the type and keys of a secret with the size of each value. Values are
+decoded from base64 and shown only with --reveal; binary values are never
+printed in the table.
+
+--key with --reveal prints only the raw value of that key, for use in
+scripts.`,
+		Example: `  k8s-admin secret view db-credentials
+  k8s-admin secret view db-credentials --reveal
+  k8s-admin secret view db-credentials --key password --reveal > password.txt`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			keys := make([]string, 0, len(secret.Data))
+			for k := range secret.Data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			if key != "" {
+				value, ok := secret.Data[key]
+				if !ok {
+					return fmt.Errorf("secret %s has no key %q", secret.Name, key)
+				}
+				if reveal {
+					_, err := os.Stdout.Write(value)
+					return err
+				}
+				keys = []string{key}
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "Name:\t%s\n", secret.Name)
+			fmt.Fprintf(w, "Namespace:\t%s\n", secret.Namespace)
+			fmt.Fprintf(w, "Type:\t%s\n", secret.Type)
+			fmt.Fprintln(w)
+			if len(keys) == 0 {
+				fmt.Fprintln(w, "No data")
+				return w.Flush()
+			}
+			fmt.Fprintln(w, "KEY\tSIZE\tVALUE")
+			for _, k := range keys {
+				value := secret.Data[k]
+				shown := secretMask
+				switch {
+				case !utf8.Valid(value):
+					shown = fmt.Sprintf("<binary, %d bytes>", len(value))
+				case reveal:
+					shown = fmt.Sprintf("%q", value)
+				}
+				fmt.Fprintf(w, "%s\t%d bytes\t%s\n", k, len(value), shown)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&key, "key", "", "only show this key")
+	cmd.Flags().BoolVar(&reveal, "reveal", false, "show the decoded values")
+	return cmd
+}
+
+func newSecretEditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit <name>",
+		Short: "Edit a secret in $EDITOR with decoded values",
+		Long: `Open a secret as YAML in $KUBE_EDITOR or $EDITOR. Text values are decoded
+into stringData so that they can be edited directly; binary values stay
+base64-encoded under data. The saved result is encoded again and updated.
+The update fails if the secret changed in the meantime; your edits are then
+kept in a temporary file, which contains the decoded values.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			decoded := secret.DeepCopy()
+			decoded.Data = nil
+			for k, v := range secret.Data {
+				if utf8.Valid(v) {
+					if decoded.StringData == nil {
+						decoded.StringData = map[string]string{}
+					}
+					decoded.StringData[k] = string(v)
+					continue
+				}
+				if decoded.Data == nil {
+					decoded.Data = map[string][]byte{}
+				}
+				decoded.Data[k] = v
+			}
+			original, err := encodeObject(decoded, "yaml")
+			if err != nil {
+				return err
+			}
+
+			return editObject(original, secret.Name, func(edited []byte) error {
+				updated := &corev1.Secret{}
+				if err := yaml.UnmarshalStrict(edited, updated); err != nil {
+					return fmt.Errorf("error parsing edited secret: %v", err)
+				}
+				if updated.Name != secret.Name || (updated.Namespace != "" && updated.Namespace != secret.Namespace) {
+					return fmt.Errorf("the name and namespace of a secret cannot be changed")
+				}
+				// stringData is write-only on the API; fold it into data so
+				// that keys removed in the editor are removed from the secret.
+				for k, v := range updated.StringData {
+					if updated.Data == nil {
+						updated.Data = map[string][]byte{}
+					}
+					updated.Data[k] = []byte(v)
+				}
+				updated.StringData = nil
+
+				if _, err := clientset.CoreV1().Secrets(namespace).Update(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+					return fmt.Errorf("error updating secret: %v", err)
+				}
+				fmt.Printf("Secret %s updated in namespace %s\n", secret.Name, namespace)
+				return nil
+			})
+		},
+	}
+
+	return cmd
+}
+
+func newSecretDiffCmd() *cobra.Command {
+	var (
+		file   string
+		reveal bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff <name> -f FILE",
+		Short: "Compare a secret with a local manifest",
+		Long: `Compare the keys and decoded values of a secret in the cluster with a local
+Secret manifest, which may use data, stringData or both. Changed values are
+reported by size only unless --reveal is given. The command exits with
+status 1 when they differ.`,
+		Example: `  k8s-admin secret diff db-credentials -f db-credentials.yaml
+  k8s-admin secret diff db-credentials -f db-credentials.yaml --reveal`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			local := &corev1.Secret{}
+			if err := readManifest(file, local); err != nil {
+				return err
+			}
+			localData := make(map[string][]byte, len(local.Data)+len(local.StringData))
+			for k, v := range local.Data {
+				localData[k] = v
+			}
+			// stringData takes precedence over data, as on the API server.
+			for k, v := range local.StringData {
+				localData[k] = []byte(v)
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+			secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			differs := local.Type != "" && secret.Type != local.Type
+			if differs {
+				fmt.Printf("type differs: %s in the cluster, %s locally\n", secret.Type, local.Type)
+			}
+			if printDataDiff("Secret", secret.Name, file, secret.Data, localData, !reveal) {
+				differs = true
+			}
+			if !differs {
+				fmt.Printf("Secret %s matches %s\n", secret.Name, file)
+				return nil
+			}
+			return differencesFound(cmd)
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "filename", "f", "", "Secret manifest to compare with ('-' reads stdin)")
+	cmd.Flags().BoolVar(&reveal, "reveal", false, "show a line diff of changed values")
+	cmd.MarkFlagRequired("filename")
+	return cmd
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func newServiceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "svc",
+		Aliases: []string{"service"},
+		Short:   "Manage services",
+		Long:    `Work with the services in your Kubernetes cluster.`,
+	}
+
+	cmd.AddCommand(newServiceListCmd())
+	cmd.AddCommand(newServiceDescribeCmd())
+	cmd.AddCommand(newServicePortForwardCmd())
+
+	return cmd
+}
+
+func newServiceListCmd() *cobra.Command {
+	var (
+		selector string
+		watching bool
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List services and flag misconfigured ones",
+		Long: `List services with their type, cluster and external IPs, ports and selector.
+
+Below the table, services are flagged whose selector matches no pods, whose
+named targetPorts are not declared by the selected pods, or whose node ports
+are also used by another service. These checks are skipped in watch mode.`,
+		Example: `  k8s-admin svc list
+  k8s-admin svc list -l app=web -w`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			if watching {
+				services := clientset.CoreV1().Services(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						opts.LabelSelector = selector
+						return services.List(ctx, opts)
+					},
+					watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+						opts.LabelSelector = selector
+						return services.Watch(ctx, opts)
+					},
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]corev1.Service, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*corev1.Service))
+						}
+						_, err := io.WriteString(w, inspector.FormatServiceTable(items, false))
+						return err
+					},
+				}, output)
+			}
+
+			services, err := clientset.CoreV1().Services(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				return printObject(services, output)
+			}
+			if len(services.Items) == 0 {
+				fmt.Printf("No services found in namespace %s\n", namespace)
+				return nil
+			}
+			fmt.Print(inspector.FormatServiceTable(services.Items, false))
+
+			pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("error listing pods: %v", err)
+			}
+			all, err := inspector.NodePortServices(clientset, namespace)
+			if err != nil {
+				return err
+			}
+			header := false
+			for i := range services.Items {
+				svc := &services.Items[i]
+				for _, warning := range inspector.ServiceWarnings(svc, pods.Items, all) {
+					if !header {
+						fmt.Println("\nWarnings:")
+						header = true
+					}
+					fmt.Printf("  %s: %s\n", svc.Name, warning)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter services")
+	addWatchFlags(cmd, &watching, &output)
+	return cmd
+}
+
+func newServiceDescribeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "describe <name>",
+		Short: "Show details of a service and its endpoints",
+		Long: `Show a service's addresses, ports and selector, the EndpointSlice addresses
+behind it with their pod, node and readiness, configuration warnings and
+recent events.`,
+		Example: `  k8s-admin svc describe web`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			description, err := inspector.DescribeService(clientset, namespace, args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Print(description)
+			return nil
+		},
+	}
+
+	return cmd
+}
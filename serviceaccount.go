@@ -3,48 +3,91 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func newServiceAccountCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "sa",
 		Short: "Manage service accounts",
-		Long:  `Create, delete, and list service accounts in your Kubernetes cluster.`,
+		Long:  `Create, update, delete, list, and prune service accounts in your Kubernetes cluster.`,
 	}
 
 	cmd.AddCommand(newSAListCmd())
 	cmd.AddCommand(newSACreateCmd())
+	cmd.AddCommand(newSAUpdateCmd())
 	cmd.AddCommand(newSADeleteCmd())
+	cmd.AddCommand(newSAUnusedCmd())
+	cmd.AddCommand(newSAPruneCmd())
 
 	return cmd
 }
 
 func newSAListCmd() *cobra.Command {
-	return &cobra.Command{
+	var (
+		watchSAs bool
+		output   string
+	)
+
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List service accounts",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
 			clientset, err := getClientset()
 			if err != nil {
 				return err
 			}
 
+			if watchSAs {
+				sas := clientset.CoreV1().ServiceAccounts(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						return sas.List(ctx, opts)
+					},
+					watch: sas.Watch,
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]corev1.ServiceAccount, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*corev1.ServiceAccount))
+						}
+						writeServiceAccountList(w, items)
+						return nil
+					},
+				}, output)
+			}
+
 			sas, err := clientset.CoreV1().ServiceAccounts(namespace).List(context.TODO(), metav1.ListOptions{})
 			if err != nil {
 				return err
 			}
 
-			fmt.Printf("Service accounts in namespace %s:\n", namespace)
-			for _, sa := range sas.Items {
-				fmt.Printf("- %s\n", sa.Name)
+			if output == "json" {
+				return printObject(sas, output)
 			}
+			writeServiceAccountList(os.Stdout, sas.Items)
 			return nil
 		},
 	}
+
+	addWatchFlags(cmd, &watchSAs, &output)
+	return cmd
+}
+
+func writeServiceAccountList(w io.Writer, sas []corev1.ServiceAccount) {
+	fmt.Fprintf(w, "Service accounts in namespace %s:\n", namespace)
+	for _, sa := range sas {
+		fmt.Fprintf(w, "- %s\n", sa.Name)
+	}
 }
 
 func newSACreateCmd() *cobra.Command {
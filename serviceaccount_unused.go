@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// UnusedServiceAccount describes a service account that no pod, workload
+// template or binding references. When only the start of its last use is
+// known, LastUsed is zero and FirstUsed is set instead.
+type UnusedServiceAccount struct {
+	ServiceAccount corev1.ServiceAccount
+	LastUsed       time.Time
+	FirstUsed      time.Time
+	LastUsedBy     string
+}
+
+// saHistory tracks the most recent evidence of a service account being used,
+// and, for workloads whose end of use is unknown, when that use started.
+type saHistory struct {
+	when   time.Time
+	source string
+
+	started       time.Time
+	startedSource string
+}
+
+func (h *saHistory) observe(when time.Time, source string) {
+	if when.After(h.when) {
+		h.when = when
+		h.source = source
+	}
+}
+
+func (h *saHistory) observeStart(when time.Time, source string) {
+	if when.After(h.started) {
+		h.started = when
+		h.startedSource = source
+	}
+}
+
+func newSAUnusedCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unused",
+		Short: "List service accounts that nothing references",
+		Long: `List service accounts that are not referenced by any pod, workload template
+or role binding. For each one, the last time a pod used it is reported when it
+can be found through old ReplicaSets, finished Jobs or events. When only the
+start of its last use is known, that is shown as the first use instead.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			unused, err := findUnusedServiceAccounts(clientset, namespace)
+			if err != nil {
+				return err
+			}
+
+			if len(unused) == 0 {
+				fmt.Printf("No unused service accounts in namespace %s\n", namespace)
+				return nil
+			}
+
+			fmt.Printf("Unused service accounts in namespace %s:\n", namespace)
+			printUnusedServiceAccounts(unused)
+			return nil
+		},
+	}
+}
+
+func newSAPruneCmd() *cobra.Command {
+	var (
+		backupDir string
+		yes       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete unused service accounts",
+		Long: `Delete every service account reported by "sa unused". A YAML backup of the
+deleted accounts is written before anything is removed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			unused, err := findUnusedServiceAccounts(clientset, namespace)
+			if err != nil {
+				return err
+			}
+
+			if len(unused) == 0 {
+				fmt.Printf("No unused service accounts in namespace %s\n", namespace)
+				return nil
+			}
+
+			fmt.Printf("The following service accounts in namespace %s will be deleted:\n", namespace)
+			printUnusedServiceAccounts(unused)
+
+			ok, err := confirm(fmt.Sprintf("Delete %d service account(s)?", len(unused)), yes)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Prune cancelled")
+				return nil
+			}
+
+			var objects []runtime.Object
+			for i := range unused {
+				sa := unused[i].ServiceAccount.DeepCopy()
+				sa.APIVersion = "v1"
+				sa.Kind = "ServiceAccount"
+				sa.ManagedFields = nil
+				objects = append(objects, sa)
+			}
+
+			backupPath, err := writeBackup(backupDir, fmt.Sprintf("sa-backup-%s", namespace), objects)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Backup written to %s\n", backupPath)
+
+			for _, u := range unused {
+				err := clientset.CoreV1().ServiceAccounts(namespace).Delete(context.TODO(), u.ServiceAccount.Name, metav1.DeleteOptions{})
+				if err != nil {
+					return fmt.Errorf("error deleting service account %s: %v", u.ServiceAccount.Name, err)
+				}
+				fmt.Printf("Service account %s deleted from namespace %s\n", u.ServiceAccount.Name, namespace)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backupDir, "backup-dir", ".", "directory to write the YAML backup to")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	return cmd
+}
+
+func printUnusedServiceAccounts(unused []UnusedServiceAccount) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "NAME\tAGE\tLAST USED\tLAST USED BY\n")
+	for _, u := range unused {
+		lastUsed, lastUsedBy := "never seen", "-"
+		switch {
+		case !u.LastUsed.IsZero():
+			lastUsed = inspector.FormatAge(u.LastUsed) + " ago"
+			lastUsedBy = u.LastUsedBy
+		case !u.FirstUsed.IsZero():
+			lastUsed = "unknown, first used " + inspector.FormatAge(u.FirstUsed) + " ago"
+			lastUsedBy = u.LastUsedBy
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.ServiceAccount.Name, inspector.FormatAge(u.ServiceAccount.CreationTimestamp.Time), lastUsed, lastUsedBy)
+	}
+	w.Flush()
+}
+
+// findUnusedServiceAccounts returns the service accounts in ns that are not
+// referenced by a pod, an active workload template or a role binding subject.
+// The "default" service account is never reported since the controller
+// manager recreates it.
+func findUnusedServiceAccounts(clientset *kubernetes.Clientset, ns string) ([]UnusedServiceAccount, error) {
+	ctx := context.TODO()
+
+	sas, err := clientset.CoreV1().ServiceAccounts(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing service accounts: %v", err)
+	}
+
+	used := make(map[string]bool)
+	history := make(map[string]*saHistory)
+	historyOf := func(sa string) *saHistory {
+		if sa == "" {
+			sa = "default"
+		}
+		if history[sa] == nil {
+			history[sa] = &saHistory{}
+		}
+		return history[sa]
+	}
+	record := func(sa string, when time.Time, source string) {
+		historyOf(sa).observe(when, source)
+	}
+
+	pods, err := clientset.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods: %v", err)
+	}
+	for _, pod := range pods.Items {
+		used[podServiceAccount(pod.Spec)] = true
+	}
+
+	deployments, err := clientset.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing deployments: %v", err)
+	}
+	for _, d := range deployments.Items {
+		used[podServiceAccount(d.Spec.Template.Spec)] = true
+	}
+
+	statefulSets, err := clientset.AppsV1().StatefulSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing statefulsets: %v", err)
+	}
+	for _, s := range statefulSets.Items {
+		used[podServiceAccount(s.Spec.Template.Spec)] = true
+	}
+
+	daemonSets, err := clientset.AppsV1().DaemonSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing daemonsets: %v", err)
+	}
+	for _, d := range daemonSets.Items {
+		used[podServiceAccount(d.Spec.Template.Spec)] = true
+	}
+
+	cronJobs, err := clientset.BatchV1().CronJobs(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing cronjobs: %v", err)
+	}
+	for _, c := range cronJobs.Items {
+		used[podServiceAccount(c.Spec.JobTemplate.Spec.Template.Spec)] = true
+	}
+
+	// ReplicaSets scaled to zero and finished Jobs no longer use their service
+	// account, but they tell us when it was last used.
+	owners := make(map[string]string)
+
+	events, err := clientset.CoreV1().Events(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing events: %v", err)
+	}
+
+	replicaSets, err := clientset.AppsV1().ReplicaSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing replicasets: %v", err)
+	}
+	scaledDown := replicaSetScaleDowns(events.Items)
+	for _, rs := range replicaSets.Items {
+		sa := podServiceAccount(rs.Spec.Template.Spec)
+		if rs.Spec.Replicas == nil || *rs.Spec.Replicas > 0 {
+			used[sa] = true
+			continue
+		}
+		owners[rs.Name] = sa
+		if when, ok := scaledDown[rs.Name]; ok {
+			record(sa, when, "replicaset/"+rs.Name)
+		} else if successor := replicaSetSuccessor(&rs, replicaSets.Items); successor != nil {
+			// The ReplicaSet was still in use when the next revision of its
+			// Deployment started rolling out.
+			record(sa, successor.CreationTimestamp.Time, "replicaset/"+rs.Name)
+		} else {
+			historyOf(sa).observeStart(rs.CreationTimestamp.Time, "replicaset/"+rs.Name)
+		}
+	}
+
+	jobs, err := clientset.BatchV1().Jobs(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing jobs: %v", err)
+	}
+	for _, job := range jobs.Items {
+		sa := podServiceAccount(job.Spec.Template.Spec)
+		// Suspended Jobs and Jobs waiting to retry have no active pods
+		// but still need their service account until they finish.
+		finished, ok := inspector.JobFinished(&job)
+		if !ok {
+			used[sa] = true
+			continue
+		}
+		owners[job.Name] = sa
+		record(sa, finished, "job/"+job.Name)
+	}
+
+	rbs, err := clientset.RbacV1().RoleBindings(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing role bindings: %v", err)
+	}
+	for _, rb := range rbs.Items {
+		for _, subject := range rb.Subjects {
+			if subject.Kind == "ServiceAccount" && (subject.Namespace == ns || subject.Namespace == "") {
+				used[subject.Name] = true
+			}
+		}
+	}
+
+	crbs, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing cluster role bindings: %v", err)
+	}
+	for _, crb := range crbs.Items {
+		for _, subject := range crb.Subjects {
+			if subject.Kind == "ServiceAccount" && subject.Namespace == ns {
+				used[subject.Name] = true
+			}
+		}
+	}
+
+	// Events outlive the pods that produced them. Pod names are derived from
+	// their owner's name, which lets us attribute pod events to the service
+	// account of an old ReplicaSet or Job.
+	for _, event := range events.Items {
+		when := inspector.EventTime(event)
+		switch event.InvolvedObject.Kind {
+		case "ServiceAccount":
+			record(event.InvolvedObject.Name, when, "event/"+event.Reason)
+		case "Pod":
+			// Prefer the longest owner name so "web-api" wins over "web".
+			owner := ""
+			for name := range owners {
+				if strings.HasPrefix(event.InvolvedObject.Name, name+"-") && len(name) > len(owner) {
+					owner = name
+				}
+			}
+			if owner != "" {
+				record(owners[owner], when, "pod/"+event.InvolvedObject.Name)
+			}
+		}
+	}
+
+	var unused []UnusedServiceAccount
+	for _, sa := range sas.Items {
+		if sa.Name == "default" || used[sa.Name] {
+			continue
+		}
+		u := UnusedServiceAccount{ServiceAccount: sa}
+		if h := history[sa.Name]; h != nil {
+			if !h.when.IsZero() {
+				u.LastUsed = h.when
+				u.LastUsedBy = h.source
+			} else {
+				u.FirstUsed = h.started
+				u.LastUsedBy = h.startedSource
+			}
+		}
+		unused = append(unused, u)
+	}
+
+	sort.Slice(unused, func(i, j int) bool {
+		return unused[i].ServiceAccount.Name < unused[j].ServiceAccount.Name
+	})
+	return unused, nil
+}
+
+// podServiceAccount returns the service account a pod spec runs as.
+func podServiceAccount(spec corev1.PodSpec) string {
+	if spec.ServiceAccountName != "" {
+		return spec.ServiceAccountName
+	}
+	if spec.DeprecatedServiceAccount != "" {
+		return spec.DeprecatedServiceAccount
+	}
+	return "default"
+}
+
+// replicaSetScaleDowns returns when each ReplicaSet was scaled to zero,
+// according to the ScalingReplicaSet events of its Deployment that are still
+// retained.
+func replicaSetScaleDowns(events []corev1.Event) map[string]time.Time {
+	scaledDown := make(map[string]time.Time)
+	for _, event := range events {
+		if event.InvolvedObject.Kind != "Deployment" || event.Reason != "ScalingReplicaSet" {
+			continue
+		}
+		// "Scaled down replica set web-5d4f8 to 0 from 2"
+		rest, ok := strings.CutPrefix(event.Message, "Scaled down replica set ")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 3 || fields[1] != "to" || fields[2] != "0" {
+			continue
+		}
+		if when := inspector.EventTime(event); when.After(scaledDown[fields[0]]) {
+			scaledDown[fields[0]] = when
+		}
+	}
+	return scaledDown
+}
+
+// replicaSetSuccessor returns the ReplicaSet of the next revision of rs's
+// Deployment, or nil when rs has no owner or is the latest revision.
+func replicaSetSuccessor(rs *appsv1.ReplicaSet, replicaSets []appsv1.ReplicaSet) *appsv1.ReplicaSet {
+	owner := metav1.GetControllerOf(rs)
+	revision, err := strconv.Atoi(rs.Annotations["deployment.kubernetes.io/revision"])
+	if owner == nil || err != nil {
+		return nil
+	}
+
+	var successor *appsv1.ReplicaSet
+	next := 0
+	for i := range replicaSets {
+		other := &replicaSets[i]
+		otherOwner := metav1.GetControllerOf(other)
+		if otherOwner == nil || otherOwner.UID != owner.UID {
+			continue
+		}
+		r, err := strconv.Atoi(other.Annotations["deployment.kubernetes.io/revision"])
+		if err != nil || r <= revision {
+			continue
+		}
+		if successor == nil || r < next {
+			successor, next = other, r
+		}
+	}
+	return successor
+}
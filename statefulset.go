@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+func newStatefulSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "sts",
+		Aliases: []string{"statefulset", "statefulsets"},
+		Short:   "Manage stateful sets",
+		Long:    `List, describe, scale and roll out stateful sets, one ordinal at a time if needed.`,
+	}
+
+	cmd.AddCommand(newStatefulSetListCmd())
+	cmd.AddCommand(newStatefulSetDescribeCmd())
+	cmd.AddCommand(newStatefulSetScaleCmd())
+	cmd.AddCommand(newStatefulSetPartitionCmd())
+	cmd.AddCommand(newStatefulSetPodsCmd())
+	cmd.AddCommand(newStatefulSetRolloutCmd())
+
+	return cmd
+}
+
+func newStatefulSetListCmd() *cobra.Command {
+	var (
+		selector string
+		watching bool
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List stateful sets",
+		Long: `List stateful sets with their ready and updated replicas, the rolling update
+partition and the images they run.`,
+		Example: `  k8s-admin sts list
+  k8s-admin sts list -l app=db -w`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			if watching {
+				sets := clientset.AppsV1().StatefulSets(namespace)
+				return runWatch(resourceWatch{
+					list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+						opts.LabelSelector = selector
+						return sets.List(ctx, opts)
+					},
+					watch: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+						opts.LabelSelector = selector
+						return sets.Watch(ctx, opts)
+					},
+					render: func(w io.Writer, objects []runtime.Object) error {
+						items := make([]appsv1.StatefulSet, 0, len(objects))
+						for _, obj := range objects {
+							items = append(items, *obj.(*appsv1.StatefulSet))
+						}
+						_, err := io.WriteString(w, inspector.FormatStatefulSetTable(items, false))
+						return err
+					},
+				}, output)
+			}
+
+			sets, err := clientset.AppsV1().StatefulSets(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				return printObject(sets, output)
+			}
+			if len(sets.Items) == 0 {
+				fmt.Printf("No stateful sets found in namespace %s\n", namespace)
+				return nil
+			}
+
+			fmt.Print(inspector.FormatStatefulSetTable(sets.Items, false))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector to filter stateful sets (e.g. app=db)")
+	addWatchFlags(cmd, &watching, &output)
+	return cmd
+}
+
+func newStatefulSetDescribeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "describe <name>",
+		Short: "Show details of a stateful set",
+		Long: `Show a stateful set's replicas, update strategy and revisions, pod template,
+volume claim templates and events.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			description, err := inspector.DescribeStatefulSet(clientset, namespace, args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Print(description)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newStatefulSetScaleCmd() *cobra.Command {
+	var replicas int32
+
+	cmd := &cobra.Command{
+		Use:   "scale <name>",
+		Short: "Set the number of replicas of a stateful set",
+		Long: `Set the number of replicas of a stateful set. Pods are added and removed at
+the highest ordinals; their persistent volume claims are kept unless the
+stateful set's retention policy says otherwise.`,
+		Example: `  k8s-admin sts scale db --replicas 3`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if replicas < 0 {
+				return fmt.Errorf("--replicas must not be negative")
+			}
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			sets := clientset.AppsV1().StatefulSets(namespace)
+			scale, err := sets.GetScale(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			previous := scale.Spec.Replicas
+			scale.Spec.Replicas = replicas
+			if _, err := sets.UpdateScale(context.TODO(), args[0], scale, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("error scaling stateful set: %v", err)
+			}
+
+			fmt.Printf("Stateful set %s scaled from %d to %d replicas in namespace %s\n", args[0], previous, replicas, namespace)
+			return nil
+		},
+	}
+
+	cmd.Flags().Int32Var(&replicas, "replicas", 0, "desired number of replicas")
+	cmd.MarkFlagRequired("replicas")
+	return cmd
+}
+
+func newStatefulSetPartitionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "partition <name> <partition>",
+		Short: "Control which ordinals a rolling update reaches",
+		Long: `Set the partition of a stateful set's rolling update. Only pods with an
+ordinal at or above the partition are updated to a new revision; the others
+keep running the current revision. Lower the partition step by step to roll
+out gradually, and set it to 0 to finish the rollout.`,
+		Example: `  # Update only the highest ordinal of a 3 replica set as a canary
+  k8s-admin sts partition db 2
+  k8s-admin sts pods db
+
+  # Release the update to every pod
+  k8s-admin sts partition db 0`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, err := strconv.ParseInt(args[1], 10, 32)
+			if err != nil || value < 0 {
+				return fmt.Errorf("invalid partition %q: must be a whole number of at least 0", args[1])
+			}
+			partition := int32(value)
+
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			s, err := clientset.AppsV1().StatefulSets(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if s.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+				return fmt.Errorf("stateful set %s uses the %s update strategy; partitions only apply to %s",
+					s.Name, appsv1.OnDeleteStatefulSetStrategyType, appsv1.RollingUpdateStatefulSetStrategyType)
+			}
+
+			if err := setStatefulSetPartition(clientset, s, partition); err != nil {
+				return err
+			}
+
+			replicas := int32(1)
+			if s.Spec.Replicas != nil {
+				replicas = *s.Spec.Replicas
+			}
+			switch {
+			case partition == 0:
+				fmt.Printf("Stateful set %s partition set to 0: every pod will be updated\n", s.Name)
+			case partition >= replicas:
+				fmt.Printf("Stateful set %s partition set to %d: no pods will be updated\n", s.Name, partition)
+			default:
+				fmt.Printf("Stateful set %s partition set to %d: ordinals %d to %d will be updated\n", s.Name, partition, partition, replicas-1)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// setStatefulSetPartition sets the rolling update partition of s, failing
+// if the stateful set changed since it was read.
+func setStatefulSetPartition(clientset *kubernetes.Clientset, s *appsv1.StatefulSet, partition int32) error {
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{"resourceVersion": s.ResourceVersion},
+		"spec": map[string]interface{}{
+			"updateStrategy": map[string]interface{}{
+				"type":          appsv1.RollingUpdateStatefulSetStrategyType,
+				"rollingUpdate": map[string]interface{}{"partition": partition},
+			},
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("error encoding patch: %v", err)
+	}
+
+	_, err = clientset.AppsV1().StatefulSets(s.Namespace).Patch(context.TODO(), s.Name, types.MergePatchType, data, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("error updating stateful set: %v", err)
+	}
+	return nil
+}
+
+func newStatefulSetPodsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pods <name>",
+		Short: "Show the pods of a stateful set by ordinal",
+		Long: `Show one row per ordinal of a stateful set with the pod's readiness, status,
+node and revision. Ordinals without a pod are listed as missing, and the
+REVISION column shows whether a pod is updated, waiting for the rolling
+update, or held back by the partition.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			s, err := clientset.AppsV1().StatefulSets(namespace).Get(context.TODO(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			pods, err := inspector.StatefulSetPods(clientset, s)
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(inspector.FormatStatefulSetPods(s, pods))
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newStatefulSetRolloutCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollout",
+		Short: "Manage the rollout of a stateful set",
+	}
+
+	cmd.AddCommand(newRolloutStatusCmd("sts", "stateful set", func(ctx context.Context, clientset *kubernetes.Clientset, name string) (string, bool, error) {
+		s, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", false, err
+		}
+		return inspector.StatefulSetRolloutStatus(s)
+	}))
+
+	return cmd
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/k8s-admin-cli/visualizer"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newStorageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "storage",
+		Aliases: []string{"pvc"},
+		Short:   "Inspect persistent volume claims and volumes",
+		Long:    `List persistent volume claims and volumes, and find unbound claims, unused claims and leaked disks.`,
+	}
+
+	cmd.AddCommand(newStorageListCmd())
+	cmd.AddCommand(newStorageVolumesCmd())
+
+	return cmd
+}
+
+func newStorageListCmd() *cobra.Command {
+	var (
+		selector      string
+		allNamespaces bool
+		noStats       bool
+		usageWarning  float64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List persistent volume claims with their volumes, usage and pods",
+		Long: `List persistent volume claims with the volume bound to them and its reclaim
+policy, their capacity, access modes and storage class, and the pods that
+mount them.
+
+Usage is read from the kubelet stats summary of the nodes running those pods
+through the API server's node proxy, which requires access to nodes/proxy.
+Claims that are not mounted, or on nodes whose stats cannot be read, show no
+usage.
+
+Problems are listed below the table: Pending claims with the likely reason,
+Lost claims, bound claims that no pod mounts, claims used at or above
+--usage-warning percent, and Released or Failed volumes of the claims'
+namespaces that still hold a disk.
+
+Volumes and storage classes are cluster-scoped. Users who may not list them
+get the claims alone, without reclaim policies, leaked volumes or the
+reasons claims are Pending.`,
+		Example: `  k8s-admin storage list
+  k8s-admin storage list -A --usage-warning 80`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			ns := namespace
+			if allNamespaces {
+				ns = metav1.NamespaceAll
+			}
+			claims, err := clientset.CoreV1().PersistentVolumeClaims(ns).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return fmt.Errorf("error listing persistent volume claims: %v", err)
+			}
+			// Volumes and storage classes are cluster-scoped; users limited
+			// to their namespaces get a report of the claims only.
+			var forbidden []string
+			volumes, err := clientset.CoreV1().PersistentVolumes().List(context.TODO(), metav1.ListOptions{})
+			if apierrors.IsForbidden(err) {
+				volumes, err = nil, nil
+				forbidden = append(forbidden, "persistent volumes")
+			}
+			if err != nil {
+				return fmt.Errorf("error listing persistent volumes: %v", err)
+			}
+			classes, err := clientset.StorageV1().StorageClasses().List(context.TODO(), metav1.ListOptions{})
+			if apierrors.IsForbidden(err) {
+				classes, err = nil, nil
+				forbidden = append(forbidden, "storage classes")
+			}
+			if err != nil {
+				return fmt.Errorf("error listing storage classes: %v", err)
+			}
+			var volumeItems []corev1.PersistentVolume
+			if volumes != nil {
+				volumeItems = volumes.Items
+			}
+			pods, err := clientset.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("error listing pods: %v", err)
+			}
+
+			var stats map[string]inspector.VolumeStats
+			var unreachable []string
+			if !noStats {
+				stats, unreachable = inspector.KubeletVolumeStats(clientset, claimNodes(pods.Items))
+			}
+			report := inspector.ClaimStorageReport(claims.Items, volumeItems, pods.Items, stats)
+
+			// Released volumes belong to no claim anymore; show those that
+			// belonged to a claim of the namespace.
+			var leaked []corev1.PersistentVolume
+			for _, pv := range volumeItems {
+				if allNamespaces || (pv.Spec.ClaimRef != nil && pv.Spec.ClaimRef.Namespace == namespace) {
+					leaked = append(leaked, pv)
+				}
+			}
+			problems := inspector.ClaimProblems(report, classes, usageWarning)
+			if selector == "" {
+				problems = append(problems, inspector.VolumeProblems(leaked)...)
+			}
+
+			if len(report) == 0 {
+				if allNamespaces {
+					fmt.Println("No persistent volume claims found")
+				} else {
+					fmt.Printf("No persistent volume claims found in namespace %s\n", namespace)
+				}
+			} else {
+				fmt.Print(inspector.FormatClaimTable(report, allNamespaces))
+			}
+			if len(forbidden) > 0 {
+				fmt.Printf("\nNot allowed to list %s; volume details, Released volumes and Pending reasons are not shown\n", strings.Join(forbidden, " or "))
+			}
+			if len(unreachable) > 0 {
+				fmt.Printf("\nUsage unavailable for claims on nodes %s (the kubelet stats could not be read)\n", strings.Join(unreachable, ", "))
+			}
+			if len(problems) > 0 {
+				fmt.Println("\nProblems:")
+				for _, p := range problems {
+					fmt.Printf("  - %s\n", p)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter persistent volume claims")
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "list the claims of every namespace")
+	cmd.Flags().BoolVar(&noStats, "no-stats", false, "do not read volume usage from the kubelets")
+	cmd.Flags().Float64Var(&usageWarning, "usage-warning", 90, "report claims whose usage is at or above this percentage")
+	return cmd
+}
+
+func newStorageVolumesCmd() *cobra.Command {
+	var problemsOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "volumes",
+		Short: "List persistent volumes",
+		Long: `List the persistent volumes of the cluster with their capacity, access modes,
+reclaim policy, status, claim and storage class.
+
+Released volumes, which keep the disk and data of a deleted claim but cannot
+be bound again, and Failed volumes are listed as problems below the table.`,
+		Example: `  k8s-admin storage volumes
+  k8s-admin storage volumes --problems`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientset, err := getClientset()
+			if err != nil {
+				return err
+			}
+
+			volumes, err := clientset.CoreV1().PersistentVolumes().List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("error listing persistent volumes: %v", err)
+			}
+			items := volumes.Items
+			if problemsOnly {
+				items = nil
+				for _, pv := range volumes.Items {
+					if pv.Status.Phase == corev1.VolumeReleased || pv.Status.Phase == corev1.VolumeFailed {
+						items = append(items, pv)
+					}
+				}
+			}
+			sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+
+			if len(items) == 0 {
+				if problemsOnly {
+					fmt.Println("No Released or Failed persistent volumes found")
+				} else {
+					fmt.Println("No persistent volumes found")
+				}
+				return nil
+			}
+			fmt.Print(inspector.FormatVolumeTable(items))
+
+			if problems := inspector.VolumeProblems(items); len(problems) > 0 {
+				fmt.Println("\nProblems:")
+				for _, p := range problems {
+					fmt.Printf("  - %s\n", p)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&problemsOnly, "problems", false, "only list Released and Failed volumes")
+	return cmd
+}
+
+// claimNodes returns the nodes running pods that mount a claim, whose
+// kubelets report the claims' usage.
+func claimNodes(pods []corev1.Pod) []string {
+	seen := map[string]bool{}
+	var nodes []string
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning || seen[pod.Spec.NodeName] {
+			continue
+		}
+		if len(visualizer.PodClaimNames(pod)) > 0 {
+			seen[pod.Spec.NodeName] = true
+			nodes = append(nodes, pod.Spec.NodeName)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
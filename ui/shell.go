@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/k8s-admin-cli/inspector"
+	"github.com/k8s-admin-cli/remote"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// shellCommand starts bash when the image has it and falls back to sh.
+var shellCommand = []string{"/bin/sh", "-c", "command -v bash >/dev/null 2>&1 && exec bash || exec sh"}
+
+// shellFinishedMsg is sent once an interactive shell session returns control
+// to the TUI.
+type shellFinishedMsg struct {
+	pod string
+	err error
+}
+
+// podShell runs an interactive shell in a pod's default container. It
+// implements tea.ExecCommand so the program is suspended while it runs.
+type podShell struct {
+	namespace string
+	pod       string
+	stdin     io.Reader
+	stdout    io.Writer
+	stderr    io.Writer
+}
+
+func (s *podShell) SetStdin(r io.Reader)  { s.stdin = r }
+func (s *podShell) SetStdout(w io.Writer) { s.stdout = w }
+func (s *podShell) SetStderr(w io.Writer) { s.stderr = w }
+
+func (s *podShell) Run() error {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error building kubeconfig: %v", err)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		return err
+	}
+
+	pod, err := clientset.CoreV1().Pods(s.namespace).Get(context.TODO(), s.pod, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting pod: %v", err)
+	}
+	container := inspector.DefaultContainer(pod)
+	if container == "" {
+		return fmt.Errorf("pod %s has no containers", s.pod)
+	}
+
+	fmt.Fprintf(s.stdout, "Connecting to %s/%s (container %s). Type 'exit' to return.\r\n", s.namespace, s.pod, container)
+
+	return remote.Exec(context.Background(), config, clientset, remote.StreamOptions{
+		Namespace: s.namespace,
+		Pod:       s.pod,
+		Container: container,
+		Command:   shellCommand,
+		Stdin:     s.stdin,
+		Stdout:    s.stdout,
+		Stderr:    s.stderr,
+		TTY:       remote.IsTerminal(s.stdin),
+	})
+}
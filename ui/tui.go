@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/k8s-admin-cli/inspector"
 	"github.com/k8s-admin-cli/visualizer"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -70,6 +72,8 @@ type model struct {
 	selectedPod    string
 	resourceItems  list.Model
 	inResourceMenu bool
+	deployItems    list.Model
+	inDeployMenu   bool
 }
 
 type itemDelegate struct{}
@@ -102,6 +106,7 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 func createMainList() list.Model {
 	items := []list.Item{
 		item{title: "Pods", description: "Manage Kubernetes pods"},
+		item{title: "Deployments", description: "Scale, restart and roll back deployments"},
 		item{title: "Service Accounts", description: "Manage Kubernetes service accounts"},
 		item{title: "Roles", description: "Manage RBAC roles"},
 		item{title: "Role Bindings", description: "Manage RBAC role bindings"},
@@ -193,8 +198,9 @@ func createPodSubmenu() list.Model {
 		item{title: "List Pods", description: "View all pods"},
 		item{title: "Create Pod", description: "Create a new pod"},
 		item{title: "Delete Pod", description: "Delete an existing pod"},
-		item{title: "Pod Details", description: "View detailed pod information"},
+		item{title: "Pod Details", description: "View detailed pod information and a diagnosis"},
 		item{title: "Pod Logs", description: "View pod logs"},
+		item{title: "Exec Shell", description: "Open an interactive shell in a pod"},
 		item{title: "Back", description: "Return to main menu"},
 	}
 
@@ -211,12 +217,36 @@ func createPodSubmenu() list.Model {
 	return podList
 }
 
+func createDeploymentSubmenu() list.Model {
+	items := []list.Item{
+		item{title: "List Deployments", description: "View all deployments"},
+		item{title: "Deployment Details", description: "View a deployment, its ReplicaSets and events"},
+		item{title: "Scale Deployment", description: "Change the number of replicas"},
+		item{title: "Restart Deployment", description: "Roll out new pods without changing the spec"},
+		item{title: "Rollout History", description: "View the revisions of a deployment"},
+		item{title: "Back", description: "Return to main menu"},
+	}
+
+	l := list.New(items, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Deployment Operations"
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.Styles.Title = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginLeft(2)
+	l.Styles.PaginationStyle = lipgloss.NewStyle().Padding(0, 1)
+
+	return l
+}
+
 func initialModel() *model {
 	mainList := createMainList()
 	resourceList := createResourceSubmenu()
 	saList := createServiceAccountSubmenu()
 	roleList := createRoleSubmenu()
 	podList := createPodSubmenu()
+	deployList := createDeploymentSubmenu()
 
 	vp := viewport.New(100, 30)
 	vp.Style = lipgloss.NewStyle().
@@ -240,6 +270,7 @@ func initialModel() *model {
 		submenuItems:  saList,
 		roleItems:     roleList,
 		podItems:      podList,
+		deployItems:   deployList,
 		textInput:     ti,
 		spinner:       s,
 		viewport:      vp,
@@ -258,11 +289,18 @@ func initialModel() *model {
 	m.podItems.SetHeight(30 - 4)
 	m.resourceItems.SetWidth(100 - 4)
 	m.resourceItems.SetHeight(30 - 4)
+	m.deployItems.SetWidth(100 - 4)
+	m.deployItems.SetHeight(30 - 4)
 
 	return m
 }
 
-func New() *tea.Program {
+// New creates the TUI program. ns is the namespace given on the command
+// line: lists are limited to it and names entered without a namespace are
+// looked up in it. When empty, lists cover every namespace and such names
+// are looked up in the default namespace.
+func New(ns string) *tea.Program {
+	namespace = ns
 	p := tea.NewProgram(
 		initialModel(),
 		tea.WithAltScreen(),
@@ -288,6 +326,15 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case shellFinishedMsg:
+		if msg.err != nil {
+			m.result = fmt.Sprintf("Shell session in pod %s failed: %v", msg.pod, msg.err)
+		} else {
+			m.result = fmt.Sprintf("Shell session in pod %s ended", msg.pod)
+		}
+		m.viewport.SetContent(m.result)
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -309,9 +356,9 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			case tea.KeyEnter:
 				value := m.textInput.Value()
-				m.handleInput(value)
+				cmd = m.handleInput(value)
 				m.textInput.SetValue("")
-				return m, nil
+				return m, cmd
 			}
 			m.textInput, cmd = m.textInput.Update(msg)
 			return m, cmd
@@ -323,10 +370,11 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.clearResults()
 				return m, nil
 			}
-			if m.inPodMenu || m.inSubmenu || m.inRoleMenu {
+			if m.inPodMenu || m.inSubmenu || m.inRoleMenu || m.inDeployMenu {
 				m.inPodMenu = false
 				m.inSubmenu = false
 				m.inRoleMenu = false
+				m.inDeployMenu = false
 				m.clearResults()
 				return m, nil
 			}
@@ -343,6 +391,8 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		if m.inPodMenu {
 			m.podItems, cmd = m.podItems.Update(msg)
+		} else if m.inDeployMenu {
+			m.deployItems, cmd = m.deployItems.Update(msg)
 		} else if m.inSubmenu {
 			m.submenuItems, cmd = m.submenuItems.Update(msg)
 		} else if m.inRoleMenu {
@@ -381,6 +431,8 @@ func (m *model) View() string {
 		content = "\n" + m.resourceItems.View()
 	} else if m.inPodMenu {
 		content = "\n" + m.podItems.View()
+	} else if m.inDeployMenu {
+		content = "\n" + m.deployItems.View()
 	} else if m.inSubmenu {
 		content = "\n" + m.submenuItems.View()
 	} else if m.inRoleMenu {
@@ -397,7 +449,9 @@ func (m *model) View() string {
 		Render(content + helpText)
 }
 
-func (m *model) handleInput(value string) {
+func (m *model) handleInput(value string) tea.Cmd {
+	var cmd tea.Cmd
+
 	switch m.inputAction {
 	case "create-pod":
 		switch m.inputStep {
@@ -440,9 +494,89 @@ func (m *model) handleInput(value string) {
 	case "pod-details":
 		m.result = fmt.Sprintf("Fetching details for pod %s", value)
 		m.inputting = false
+		m.loading = true
+		go func() {
+			ns, name := splitNamespacedName(value)
+			details, err := getPodDetails(name, ns)
+			m.loading = false
+			if err != nil {
+				m.result = fmt.Sprintf("Error fetching pod details: %v", err)
+			} else {
+				m.result = details
+			}
+			m.viewport.SetContent(m.result)
+			program.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{0}})
+		}()
 	case "pod-logs":
 		m.result = fmt.Sprintf("Fetching logs for pod %s", value)
 		m.inputting = false
+		m.loading = true
+		go func() {
+			ns, name := splitNamespacedName(value)
+			logs, err := getPodLogs(name, ns)
+			m.loading = false
+			if err != nil {
+				m.result = fmt.Sprintf("Error fetching logs: %v", err)
+			} else if logs == "" {
+				m.result = fmt.Sprintf("Pod %s has no logs", value)
+			} else {
+				m.result = logs
+			}
+			m.viewport.SetContent(m.result)
+			program.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{0}})
+		}()
+	case "pod-exec":
+		m.result = fmt.Sprintf("Opening a shell in pod %s", value)
+		m.inputting = false
+		ns, name := splitNamespacedName(value)
+		cmd = tea.Exec(&podShell{namespace: ns, pod: name}, func(err error) tea.Msg {
+			return shellFinishedMsg{pod: value, err: err}
+		})
+	case "deployment-details":
+		m.inputting = false
+		m.runDeploymentAction(value, getDeploymentDetails)
+	case "rollout-history":
+		m.inputting = false
+		m.runDeploymentAction(value, getRolloutHistory)
+	case "restart-deployment":
+		m.inputting = false
+		m.runDeploymentAction(value, func(name, namespace string) (string, error) {
+			clientset, err := getClientset()
+			if err != nil {
+				return "", fmt.Errorf("error getting clientset: %v", err)
+			}
+			if err := inspector.RestartDeployment(clientset, namespace, name); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Deployment %s restarted in namespace %s", name, namespace), nil
+		})
+	case "scale-deployment":
+		switch m.inputStep {
+		case "name":
+			m.inputData["name"] = value
+			m.inputStep = "replicas"
+			m.textInput.SetValue("")
+			m.textInput.Placeholder = "Enter number of replicas..."
+			m.textInput.Focus()
+			m.result = "Enter the desired number of replicas (press Enter to confirm, Esc to cancel):"
+		case "replicas":
+			replicas, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+			if err != nil || replicas < 0 {
+				m.result = fmt.Sprintf("Invalid number of replicas %q; enter a whole number of at least 0:", value)
+				break
+			}
+			m.inputting = false
+			m.runDeploymentAction(m.inputData["name"], func(name, namespace string) (string, error) {
+				clientset, err := getClientset()
+				if err != nil {
+					return "", fmt.Errorf("error getting clientset: %v", err)
+				}
+				if _, err := inspector.ScaleDeployment(clientset, namespace, name, int32(replicas)); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Deployment %s scaled to %d replicas in namespace %s", name, replicas, namespace), nil
+			})
+		}
 	case "create-sa":
 		switch m.inputStep {
 		case "name":
@@ -482,6 +616,7 @@ func (m *model) handleInput(value string) {
 		m.inputting = false
 	}
 	m.viewport.SetContent(m.result)
+	return cmd
 }
 
 func (m *model) handleEnterKey() (tea.Model, tea.Cmd) {
@@ -542,7 +677,7 @@ func (m *model) handleEnterKey() (tea.Model, tea.Cmd) {
 				m.textInput.Reset()
 				m.textInput.Placeholder = "Enter pod name..."
 				m.textInput.Focus()
-				m.result = "Enter name of the pod to view details (press Enter to confirm, Esc to cancel):"
+				m.result = "Enter name of the pod to view details as NAME or NAMESPACE/NAME (press Enter to confirm, Esc to cancel):"
 				m.viewport.SetContent(m.result)
 				return m, nil
 
@@ -553,7 +688,18 @@ func (m *model) handleEnterKey() (tea.Model, tea.Cmd) {
 				m.textInput.Reset()
 				m.textInput.Placeholder = "Enter pod name..."
 				m.textInput.Focus()
-				m.result = "Enter name of the pod to view logs (press Enter to confirm, Esc to cancel):"
+				m.result = "Enter name of the pod to view logs as NAME or NAMESPACE/NAME (press Enter to confirm, Esc to cancel):"
+				m.viewport.SetContent(m.result)
+				return m, nil
+
+			case "Exec Shell":
+				m.inputting = true
+				m.inputAction = "pod-exec"
+				m.inputStep = "name"
+				m.textInput.Reset()
+				m.textInput.Placeholder = "Enter pod name..."
+				m.textInput.Focus()
+				m.result = "Enter name of the pod to open a shell in as NAME or NAMESPACE/NAME (press Enter to confirm, Esc to cancel):"
 				m.viewport.SetContent(m.result)
 				return m, nil
 
@@ -563,6 +709,56 @@ func (m *model) handleEnterKey() (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 		}
+	} else if m.inDeployMenu {
+		i, ok := m.deployItems.SelectedItem().(item)
+		if ok {
+			switch i.title {
+			case "List Deployments":
+				m.loading = true
+				m.clearResults()
+				go func() {
+					clientset, err := getClientset()
+					if err != nil {
+						m.loading = false
+						m.err = err
+						program.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{0}})
+						return
+					}
+
+					deployments, err := clientset.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
+					m.loading = false
+					if err != nil {
+						m.err = err
+					} else {
+						m.result = inspector.FormatDeploymentTable(deployments.Items, true)
+						m.viewport.SetContent(m.result)
+					}
+					program.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{0}})
+				}()
+				return m, m.spinner.Tick
+
+			case "Deployment Details":
+				m.promptDeployment("deployment-details", "Enter name of the deployment to view details")
+				return m, nil
+
+			case "Scale Deployment":
+				m.promptDeployment("scale-deployment", "Enter name of the deployment to scale")
+				return m, nil
+
+			case "Restart Deployment":
+				m.promptDeployment("restart-deployment", "Enter name of the deployment to restart")
+				return m, nil
+
+			case "Rollout History":
+				m.promptDeployment("rollout-history", "Enter name of the deployment to view its rollout history")
+				return m, nil
+
+			case "Back":
+				m.inDeployMenu = false
+				m.clearResults()
+				return m, nil
+			}
+		}
 	} else if m.inSubmenu {
 		i, ok := m.submenuItems.SelectedItem().(item)
 		if ok {
@@ -679,6 +875,13 @@ func (m *model) handleEnterKey() (tea.Model, tea.Cmd) {
 				m.inRoleMenu = false
 				m.clearResults()
 				return m, nil
+			case "Deployments":
+				m.inDeployMenu = true
+				m.inPodMenu = false
+				m.inSubmenu = false
+				m.inRoleMenu = false
+				m.clearResults()
+				return m, nil
 			case "Service Accounts":
 				m.inSubmenu = true
 				m.inRoleMenu = false
@@ -741,6 +944,37 @@ func (m *model) handleEnterKey() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// promptDeployment asks for a deployment name for the given input action.
+func (m *model) promptDeployment(action, prompt string) {
+	m.inputting = true
+	m.inputAction = action
+	m.inputStep = "name"
+	m.textInput.Reset()
+	m.textInput.Placeholder = "Enter deployment name..."
+	m.textInput.Focus()
+	m.result = prompt + " as NAME or NAMESPACE/NAME (press Enter to confirm, Esc to cancel):"
+	m.viewport.SetContent(m.result)
+}
+
+// runDeploymentAction runs action for the deployment named by value in the
+// background and shows its output.
+func (m *model) runDeploymentAction(value string, action func(name, namespace string) (string, error)) {
+	m.result = fmt.Sprintf("Working on deployment %s", value)
+	m.loading = true
+	go func() {
+		ns, name := splitNamespacedName(value)
+		result, err := action(name, ns)
+		m.loading = false
+		if err != nil {
+			m.result = fmt.Sprintf("Error: %v", err)
+		} else {
+			m.result = result
+		}
+		m.viewport.SetContent(m.result)
+		program.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{0}})
+	}()
+}
+
 func (m *model) createPod() {
 	clientset, err := getClientset()
 	if err != nil {
@@ -897,17 +1131,11 @@ func getClientset() (*kubernetes.Clientset, error) {
 }
 
 func formatPodList(pods *corev1.PodList) string {
-	var result strings.Builder
-	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
-
-	fmt.Fprintf(w, "NAMESPACE\tNAME\tSTATUS\tAGE\n")
-	for _, pod := range pods.Items {
-		age := time.Since(pod.CreationTimestamp.Time).Round(time.Second)
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", pod.Namespace, pod.Name, pod.Status.Phase, age)
+	table, err := inspector.FormatPodTable(pods.Items, inspector.PodTableOptions{ShowNamespace: true})
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
-
-	w.Flush()
-	return result.String()
+	return table
 }
 
 func formatServiceAccountList(sas *corev1.ServiceAccountList) string {
@@ -960,39 +1188,69 @@ func getPodDetails(name, namespace string) (string, error) {
 		return "", fmt.Errorf("error getting clientset: %v", err)
 	}
 
+	description, err := inspector.DescribePod(clientset, namespace, name)
+	if err != nil {
+		return "", err
+	}
+
 	pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 	if err != nil {
-		return "", fmt.Errorf("error getting pod details: %v", err)
+		return "", fmt.Errorf("error getting pod: %v", err)
 	}
 
-	var result strings.Builder
-	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+	opts := inspector.DiagnoseOptions{}
+	if config, err := clientcmd.BuildConfigFromFlags("", kubeconfig); err == nil {
+		if metricsClientset, err := versioned.NewForConfig(config); err == nil {
+			opts.Metrics = metricsClientset
+		}
+	}
+	findings, err := inspector.DiagnosePod(clientset, pod, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return description + "\n" + inspector.FormatDiagnosis(pod, findings), nil
+}
+
+func getDeploymentDetails(name, namespace string) (string, error) {
+	clientset, err := getClientset()
+	if err != nil {
+		return "", fmt.Errorf("error getting clientset: %v", err)
+	}
+
+	return inspector.DescribeDeployment(clientset, namespace, name)
+}
 
-	fmt.Fprintf(w, "Name:\t%s\n", pod.Name)
-	fmt.Fprintf(w, "Namespace:\t%s\n", pod.Namespace)
-	fmt.Fprintf(w, "Status:\t%s\n", pod.Status.Phase)
-	fmt.Fprintf(w, "IP:\t%s\n", pod.Status.PodIP)
-	fmt.Fprintf(w, "Node:\t%s\n", pod.Spec.NodeName)
-	fmt.Fprintf(w, "Start Time:\t%s\n", pod.Status.StartTime.Time)
-
-	fmt.Fprintf(w, "\nContainers:\n")
-	for _, container := range pod.Spec.Containers {
-		fmt.Fprintf(w, "  - %s:\n", container.Name)
-		fmt.Fprintf(w, "    Image:\t%s\n", container.Image)
-		fmt.Fprintf(w, "    Ready:\t%v\n", getContainerStatus(pod, container.Name))
+func getRolloutHistory(name, namespace string) (string, error) {
+	clientset, err := getClientset()
+	if err != nil {
+		return "", fmt.Errorf("error getting clientset: %v", err)
 	}
 
-	w.Flush()
-	return result.String(), nil
+	d, err := clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error getting deployment: %v", err)
+	}
+	revisions, err := inspector.DeploymentRevisions(clientset, d)
+	if err != nil {
+		return "", err
+	}
+	return inspector.FormatRolloutHistory(d, revisions), nil
 }
 
-func getContainerStatus(pod *corev1.Pod, containerName string) bool {
-	for _, status := range pod.Status.ContainerStatuses {
-		if status.Name == containerName {
-			return status.Ready
-		}
+// podLogTailLines caps how much of a pod's log is loaded into the viewport.
+const podLogTailLines = 1000
+
+// splitNamespacedName parses NAMESPACE/NAME input, falling back to the
+// TUI's namespace when only a name is given.
+func splitNamespacedName(value string) (string, string) {
+	if ns, name, ok := strings.Cut(strings.TrimSpace(value), "/"); ok {
+		return ns, name
+	}
+	if namespace == "" {
+		return metav1.NamespaceDefault, strings.TrimSpace(value)
 	}
-	return false
+	return namespace, strings.TrimSpace(value)
 }
 
 func getPodLogs(name, namespace string) (string, error) {
@@ -1001,7 +1259,8 @@ func getPodLogs(name, namespace string) (string, error) {
 		return "", fmt.Errorf("error getting clientset: %v", err)
 	}
 
-	podLogOpts := corev1.PodLogOptions{}
+	tailLines := int64(podLogTailLines)
+	podLogOpts := corev1.PodLogOptions{TailLines: &tailLines}
 	req := clientset.CoreV1().Pods(namespace).GetLogs(name, &podLogOpts)
 	podLogs, err := req.Stream(context.TODO())
 	if err != nil {
@@ -21,6 +21,7 @@ This will show relationships between:
 - Deployments and ConfigMaps
 - Deployments and Secrets
 - Services and their selected Pods
+- Pods, the PersistentVolumeClaims they mount and the PersistentVolumes bound to them
 The output will be saved as a PNG file.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			clientset, err := getClientset()
@@ -48,6 +49,9 @@ The output will be saved as a PNG file.`,
 			fmt.Println("- Green ovals: Services")
 			fmt.Println("- Yellow notes: ConfigMaps")
 			fmt.Println("- Pink notes: Secrets")
+			fmt.Println("- Grey boxes: Pods mounting PersistentVolumeClaims")
+			fmt.Println("- Orange boxes: PersistentVolumeClaims")
+			fmt.Println("- Tan boxes: PersistentVolumes")
 			fmt.Println("\nArrows indicate relationships between resources.")
 			return nil
 		},
@@ -7,6 +7,7 @@ import (
 
 	"github.com/goccy/go-graphviz"
 	"github.com/goccy/go-graphviz/cgraph"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -92,7 +93,7 @@ func (v *DependencyVisualizer) CreateGraph(outputPath string) error {
 		// Connect services to deployments
 		if service.Spec.Selector != nil {
 			for _, deployment := range deployments.Items {
-				if labelsMatch(deployment.Spec.Template.Labels, service.Spec.Selector) {
+				if LabelsMatch(deployment.Spec.Template.Labels, service.Spec.Selector) {
 					deploymentNode := nodes[fmt.Sprintf("deployment/%s", deployment.Name)]
 					if deploymentNode != nil {
 						edge, err := graph.CreateEdgeByName("", deploymentNode, node)
@@ -216,6 +217,69 @@ func (v *DependencyVisualizer) CreateGraph(outputPath string) error {
 		}
 	}
 
+	// Get persistent volume claims and the volumes they are bound to
+	claims, err := v.clientset.CoreV1().PersistentVolumeClaims(v.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing persistent volume claims: %v", err)
+	}
+
+	// Add claim and volume nodes. They are prefixed by their kind since
+	// claims are often named like the workload that uses them.
+	for _, claim := range claims.Items {
+		node, err := createStorageNode(graph, "pvc/"+claim.Name, claim.Name, "orange")
+		if err != nil {
+			return err
+		}
+		nodes[fmt.Sprintf("pvc/%s", claim.Name)] = node
+
+		if claim.Spec.VolumeName == "" {
+			continue
+		}
+		volumeNode, err := createStorageNode(graph, "pv/"+claim.Spec.VolumeName, claim.Spec.VolumeName, "tan")
+		if err != nil {
+			return err
+		}
+		edge, err := graph.CreateEdgeByName("", node, volumeNode)
+		if err != nil {
+			return fmt.Errorf("error creating edge: %v", err)
+		}
+		if err := edge.Set("label", "bound"); err != nil {
+			return fmt.Errorf("error setting edge label: %v", err)
+		}
+	}
+
+	// Connect pods to the claims they mount
+	if len(claims.Items) > 0 {
+		pods, err := v.clientset.CoreV1().Pods(v.namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("error listing pods: %v", err)
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			for _, claimName := range PodClaimNames(pod) {
+				claimNode := nodes[fmt.Sprintf("pvc/%s", claimName)]
+				if claimNode == nil {
+					continue
+				}
+				podNode := nodes[fmt.Sprintf("pod/%s", pod.Name)]
+				if podNode == nil {
+					podNode, err = createStorageNode(graph, "pod/"+pod.Name, pod.Name, "lightgrey")
+					if err != nil {
+						return err
+					}
+					nodes[fmt.Sprintf("pod/%s", pod.Name)] = podNode
+				}
+				edge, err := graph.CreateEdgeByName("", podNode, claimNode)
+				if err != nil {
+					return fmt.Errorf("error creating edge: %v", err)
+				}
+				if err := edge.Set("label", "mounts"); err != nil {
+					return fmt.Errorf("error setting edge label: %v", err)
+				}
+			}
+		}
+	}
+
 	// Create output file
 	out, err := os.Create(outputPath)
 	if err != nil {
@@ -231,7 +295,29 @@ func (v *DependencyVisualizer) CreateGraph(outputPath string) error {
 	return nil
 }
 
-func labelsMatch(labels, selector map[string]string) bool {
+// createStorageNode adds a filled node for a pod, claim or volume, keyed by
+// name and labelled with the object's own name.
+func createStorageNode(graph *cgraph.Graph, name, label, color string) (*cgraph.Node, error) {
+	node, err := graph.CreateNodeByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("error creating node %s: %v", name, err)
+	}
+	if err := node.Set("label", label); err != nil {
+		return nil, fmt.Errorf("error setting node label: %v", err)
+	}
+	if err := node.Set("style", "filled"); err != nil {
+		return nil, fmt.Errorf("error setting node style: %v", err)
+	}
+	if err := node.Set("fillcolor", color); err != nil {
+		return nil, fmt.Errorf("error setting node color: %v", err)
+	}
+	return node, nil
+}
+
+// LabelsMatch reports whether labels contain every key and value of an
+// equality-based selector such as a service's. An empty selector matches
+// everything.
+func LabelsMatch(labels, selector map[string]string) bool {
 	for key, value := range selector {
 		if labels[key] != value {
 			return false
@@ -239,3 +325,19 @@ func labelsMatch(labels, selector map[string]string) bool {
 	}
 	return true
 }
+
+// PodClaimNames returns the names of the PersistentVolumeClaims a pod
+// mounts, including the claims of its generic ephemeral volumes, which are
+// named after the pod and the volume.
+func PodClaimNames(pod *corev1.Pod) []string {
+	var claims []string
+	for _, volume := range pod.Spec.Volumes {
+		switch {
+		case volume.PersistentVolumeClaim != nil:
+			claims = append(claims, volume.PersistentVolumeClaim.ClaimName)
+		case volume.Ephemeral != nil:
+			claims = append(claims, pod.Name+"-"+volume.Name)
+		}
+	}
+	return claims
+}
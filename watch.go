@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// redrawInterval bounds how often the watched table is redrawn when many
+// events arrive at once.
+const redrawInterval = 200 * time.Millisecond
+
+// resourceWatch describes how to list and watch one kind of object and how
+// to render the current set of objects as a table.
+type resourceWatch struct {
+	list   func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error)
+	watch  func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	render func(w io.Writer, objects []runtime.Object) error
+}
+
+// watchEvent is the JSON form of a watch event, one per line.
+type watchEvent struct {
+	Type   watch.EventType `json:"type"`
+	Object runtime.Object  `json:"object"`
+}
+
+// addWatchFlags registers the --watch and --output flags shared by the list
+// commands.
+func addWatchFlags(cmd *cobra.Command, watching *bool, output *string) {
+	cmd.Flags().BoolVarP(watching, "watch", "w", false, "keep watching for changes after listing")
+	cmd.Flags().StringVarP(output, "output", "o", "", "output format: json (an event stream with --watch)")
+}
+
+func validateListOutput(output string) error {
+	if output != "" && output != "json" {
+		return fmt.Errorf("invalid --output %q: only json is supported", output)
+	}
+	return nil
+}
+
+// runWatch lists the objects once and then follows changes with the Watch
+// API until interrupted. A closed watch is resumed from the last seen
+// resourceVersion; when that version has expired (410 Gone) the objects are
+// listed again. With output "json" every change is written as an event line,
+// otherwise the rendered table is refreshed in place.
+func runWatch(rw resourceWatch, output string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	wr := &watchRenderer{rw: rw, json: output == "json", tty: term.IsTerminal(int(os.Stdout.Fd()))}
+	if err := wr.relist(ctx); err != nil {
+		return err
+	}
+	if err := wr.flush(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(redrawInterval)
+	defer ticker.Stop()
+
+	for {
+		w, err := rw.watch(ctx, metav1.ListOptions{ResourceVersion: wr.resourceVersion, AllowWatchBookmarks: true})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+				if err := wr.relist(ctx); err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("error watching: %v", err)
+		}
+
+		err = wr.consume(ctx, w, ticker.C)
+		w.Stop()
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+type watchRenderer struct {
+	rw   resourceWatch
+	json bool
+	tty  bool
+
+	objects         map[string]runtime.Object
+	resourceVersion string
+	dirty           bool
+}
+
+// consume handles events until the watch closes, expires or ctx is done.
+func (wr *watchRenderer) consume(ctx context.Context, w watch.Interface, tick <-chan time.Time) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return wr.flush()
+		case <-tick:
+			if err := wr.flush(); err != nil {
+				return err
+			}
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return wr.flush()
+			}
+
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				wr.objects[objectKey(event.Object)] = event.Object
+				wr.setResourceVersion(event.Object)
+				wr.emit(event.Type, event.Object)
+			case watch.Deleted:
+				delete(wr.objects, objectKey(event.Object))
+				wr.setResourceVersion(event.Object)
+				wr.emit(event.Type, event.Object)
+			case watch.Bookmark:
+				wr.setResourceVersion(event.Object)
+			case watch.Error:
+				err := apierrors.FromObject(event.Object)
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					if err := wr.relist(ctx); err != nil {
+						return err
+					}
+					return wr.flush()
+				}
+				return fmt.Errorf("error watching: %v", err)
+			}
+		}
+	}
+}
+
+// relist replaces the known objects with a fresh list. In JSON mode the
+// differences are reported as events so that consumers see a consistent
+// stream across the relist.
+func (wr *watchRenderer) relist(ctx context.Context) error {
+	list, err := wr.rw.list(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	listMeta, err := meta.ListAccessor(list)
+	if err != nil {
+		return err
+	}
+
+	fresh := make(map[string]runtime.Object, len(items))
+	for _, item := range items {
+		fresh[objectKey(item)] = item
+	}
+
+	for key, obj := range fresh {
+		old, ok := wr.objects[key]
+		switch {
+		case !ok:
+			wr.emit(watch.Added, obj)
+		case resourceVersionOf(old) != resourceVersionOf(obj):
+			wr.emit(watch.Modified, obj)
+		}
+	}
+	for key, obj := range wr.objects {
+		if _, ok := fresh[key]; !ok {
+			wr.emit(watch.Deleted, obj)
+		}
+	}
+
+	wr.objects = fresh
+	wr.resourceVersion = listMeta.GetResourceVersion()
+	wr.dirty = true
+	return nil
+}
+
+func (wr *watchRenderer) emit(eventType watch.EventType, obj runtime.Object) {
+	if !wr.json {
+		wr.dirty = true
+		return
+	}
+
+	data, err := json.Marshal(watchEvent{Type: eventType, Object: exportObject(obj)})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding event: %v\n", err)
+		return
+	}
+	fmt.Println(string(data))
+}
+
+// flush redraws the table if anything changed since the last draw.
+func (wr *watchRenderer) flush() error {
+	if wr.json || !wr.dirty {
+		return nil
+	}
+	wr.dirty = false
+
+	keys := make([]string, 0, len(wr.objects))
+	for key := range wr.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	objects := make([]runtime.Object, 0, len(keys))
+	for _, key := range keys {
+		objects = append(objects, wr.objects[key])
+	}
+
+	var out strings.Builder
+	if err := wr.rw.render(&out, objects); err != nil {
+		return err
+	}
+
+	if wr.tty {
+		// Move to the top-left corner and clear the screen.
+		fmt.Print("\033[H\033[2J")
+	} else {
+		fmt.Println()
+	}
+	fmt.Print(out.String())
+	fmt.Printf("\nWatching for changes (updated %s, Ctrl+C to stop)\n", time.Now().Format("15:04:05"))
+	return nil
+}
+
+func (wr *watchRenderer) setResourceVersion(obj runtime.Object) {
+	if rv := resourceVersionOf(obj); rv != "" {
+		wr.resourceVersion = rv
+	}
+}
+
+func objectKey(obj runtime.Object) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetNamespace() + "/" + accessor.GetName()
+}
+
+func resourceVersionOf(obj runtime.Object) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetResourceVersion()
+}